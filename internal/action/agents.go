@@ -0,0 +1,82 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// agentList is the subset of the agent list API response, which the go-tfe client does not support
+type agentList struct {
+	Data []struct {
+		Attributes struct {
+			Status string `json:"status"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			NextPage int `json:"next-page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// CountAvailableAgents returns the number of agents of the passed agent pool that can pick up runs, which are the idle and busy agents
+func CountAvailableAgents(ctx context.Context, httpClient *http.Client, address string, token string, agentPoolID string) (int, error) {
+	available := 0
+
+	for page := 1; page != 0; {
+		var agents agentList
+
+		status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("agent-pools/%s/agents?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", url.PathEscape(agentPoolID), page, maxPageSize), &agents)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the agents of agent pool %q: %w", agentPoolID, err)
+		}
+
+		if status != http.StatusOK {
+			return 0, fmt.Errorf("failed to list the agents of agent pool %q: %d %s", agentPoolID, status, http.StatusText(status))
+		}
+
+		for _, a := range agents.Data {
+			if a.Attributes.Status == "idle" || a.Attributes.Status == "busy" {
+				available++
+			}
+		}
+
+		page = agents.Meta.Pagination.NextPage
+	}
+
+	return available, nil
+}
+
+// AgentPoolsWithoutAgents returns a warning for each organization whose agent pool, passed by ID or name, has no available agents.
+// Runs of workspaces using such a pool wait until an agent connects, so an apply would otherwise hang.
+func AgentPoolsWithoutAgents(ctx context.Context, client *tfe.Client, httpClient *http.Client, address string, token string, organizations []string, agentPoolID string, agentPoolName string) ([]string, error) {
+	warnings := []string{}
+
+	for _, org := range organizations {
+		poolID := agentPoolID
+
+		if poolID == "" {
+			id, err := GetAgentPoolIDByName(ctx, client, org, agentPoolName)
+			if err != nil {
+				return nil, err
+			}
+
+			poolID = id
+		}
+
+		available, err := CountAvailableAgents(ctx, httpClient, address, token, poolID)
+		if err != nil {
+			return nil, err
+		}
+
+		if available == 0 {
+			warnings = append(warnings, fmt.Sprintf("agent pool %q of organization %q has no available agents, runs of its workspaces wait until an agent connects", poolID, org))
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,39 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentPoolsWithoutAgents(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/agent-pools/apool-abc123/agents", testServerResHandler(t, 200, `{"data": [{"id": "agent-1", "type": "agents", "attributes": {"status": "exited"}}, {"id": "agent-2", "type": "agents", "attributes": {"status": "idle"}}], "meta": {"pagination": {"next-page": null}}}`))
+	mux.HandleFunc("/api/v2/agent-pools/apool-def456/agents", testServerResHandler(t, 200, `{"data": [{"id": "agent-3", "type": "agents", "attributes": {"status": "errored"}}], "meta": {"pagination": {"next-page": null}}}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("no warning when the agent pool has available agents", func(t *testing.T) {
+		warnings, err := AgentPoolsWithoutAgents(ctx, client, http.DefaultClient, server.URL, "12345", []string{"org"}, "apool-abc123", "")
+		require.NoError(t, err)
+
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("warn when the agent pool has no available agents", func(t *testing.T) {
+		warnings, err := AgentPoolsWithoutAgents(ctx, client, http.DefaultClient, server.URL, "12345", []string{"org"}, "apool-def456", "")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"agent pool \"apool-def456\" of organization \"org\" has no available agents, runs of its workspaces wait until an agent connects"}, warnings)
+	})
+}
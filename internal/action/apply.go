@@ -0,0 +1,409 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// failedResourcePattern matches the resource address Terraform attaches to an apply diagnostic, e.g. "with tfe_variable.staging-foo,"
+var failedResourcePattern = regexp.MustCompile(`(?m)^\s*(?:│\s*)?with ([^\s,]+),`)
+
+// FailedResources returns the sorted, distinct resource addresses named in the diagnostics of a failed apply
+func FailedResources(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	addrs := []string{}
+
+	for _, m := range failedResourcePattern.FindAllStringSubmatch(err.Error(), -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			addrs = append(addrs, m[1])
+		}
+	}
+
+	sort.Strings(addrs)
+
+	return addrs
+}
+
+// ChangedResources returns the sorted addresses of the resources that have pending changes in the passed plan
+func ChangedResources(plan *tfjson.Plan) []string {
+	addrs := []string{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+
+		addrs = append(addrs, rc.Address)
+	}
+
+	sort.Strings(addrs)
+
+	return addrs
+}
+
+// SucceededResources returns the resources changed in the original plan that no longer have pending changes in the remaining plan
+func SucceededResources(original, remaining *tfjson.Plan) []string {
+	pending := map[string]bool{}
+
+	for _, addr := range ChangedResources(remaining) {
+		pending[addr] = true
+	}
+
+	succeeded := []string{}
+
+	for _, addr := range ChangedResources(original) {
+		if !pending[addr] {
+			succeeded = append(succeeded, addr)
+		}
+	}
+
+	return succeeded
+}
+
+// ContinuationTargets returns the resources with pending changes in the plan that neither failed nor depend, directly or through other pending resources, on a failed resource
+func ContinuationTargets(plan *tfjson.Plan, failed []string) []string {
+	excluded := append([]string{}, failed...)
+	isExcluded := map[string]bool{}
+
+	for _, addr := range failed {
+		isExcluded[addr] = true
+	}
+
+	resources := configResources(plan)
+	pending := []*tfjson.ResourceChange{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+
+		pending = append(pending, rc)
+	}
+
+	// dependents of excluded resources are excluded until no more are found, so dependents of dependents are excluded as well
+	for found := true; found; {
+		found = false
+
+		for _, rc := range pending {
+			if isExcluded[rc.Address] || !dependsOnAny(resources[rc.Type+"."+rc.Name], excluded) {
+				continue
+			}
+
+			excluded = append(excluded, rc.Address)
+			isExcluded[rc.Address] = true
+			found = true
+		}
+	}
+
+	targets := []string{}
+
+	for _, rc := range pending {
+		if !isExcluded[rc.Address] {
+			targets = append(targets, rc.Address)
+		}
+	}
+
+	sort.Strings(targets)
+
+	return targets
+}
+
+// configResources returns the root module resources of the plan configuration, keyed by resource address
+func configResources(plan *tfjson.Plan) map[string]*tfjson.ConfigResource {
+	resources := map[string]*tfjson.ConfigResource{}
+
+	if plan.Config == nil || plan.Config.RootModule == nil {
+		return resources
+	}
+
+	for _, r := range plan.Config.RootModule.Resources {
+		resources[r.Address] = r
+	}
+
+	return resources
+}
+
+// dependsOnAny returns true if the passed configuration resource references one of the passed resource addresses.
+// A for_each referencing a whole resource depends on every instance of it.
+func dependsOnAny(r *tfjson.ConfigResource, addrs []string) bool {
+	if r == nil {
+		return false
+	}
+
+	if referencesAny(append(expressionReferences(r.Expressions), r.DependsOn...), addrs) {
+		return true
+	}
+
+	if r.ForEachExpression == nil || r.ForEachExpression.ExpressionData == nil {
+		return false
+	}
+
+	for _, ref := range r.ForEachExpression.References {
+		for _, addr := range addrs {
+			if referencesAny([]string{ref}, []string{addr}) || strings.HasPrefix(addr, ref+"[") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// expressionReferences returns all references of the passed expressions, including those of nested blocks
+func expressionReferences(exprs map[string]*tfjson.Expression) []string {
+	refs := []string{}
+
+	for _, expr := range exprs {
+		if expr == nil || expr.ExpressionData == nil {
+			continue
+		}
+
+		refs = append(refs, expr.References...)
+
+		for _, block := range expr.NestedBlocks {
+			refs = append(refs, expressionReferences(block)...)
+		}
+	}
+
+	return refs
+}
+
+// referencesAny returns true if any of the references points to one of the passed resource addresses
+func referencesAny(references []string, addrs []string) bool {
+	for _, ref := range references {
+		for _, addr := range addrs {
+			if ref == addr || strings.HasPrefix(ref, addr+".") || strings.HasPrefix(ref, addr+"[") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// terraformApplier plans, shows and applies saved plans, implemented by *tfexec.Terraform
+type terraformApplier interface {
+	planShower
+	Plan(context.Context, ...tfexec.PlanOption) (bool, error)
+	Apply(context.Context, ...tfexec.ApplyOption) error
+}
+
+// VerifyPlanUnchanged plans again and returns an error describing the differences if the new plan differs from the saved plan, such as when the workspaces changed between planning and applying
+func VerifyPlanUnchanged(ctx context.Context, tf terraformApplier, saved *tfjson.Plan, targetOpts []tfexec.PlanOption) error {
+	planPath := "verify.plan.txt"
+
+	diff, err := tf.Plan(ctx, append([]tfexec.PlanOption{tfexec.Out(planPath)}, targetOpts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to plan again before applying: %w", err)
+	}
+
+	current := &tfjson.Plan{}
+
+	if diff {
+		current, err = tf.ShowPlanFile(ctx, planPath)
+		if err != nil {
+			return fmt.Errorf("failed to create plan struct: %w", err)
+		}
+	}
+
+	if diffs := PlanDifferences(current, saved); len(diffs) > 0 {
+		return planBlocked("error: the plan changed since it was saved, not applying: %s", strings.Join(diffs, "; "))
+	}
+
+	return nil
+}
+
+// VerifyApprovedPlan returns an error describing the differences if the passed plan differs from the approved plan, which is not checked if nil
+func VerifyApprovedPlan(plan *tfjson.Plan, approved *tfjson.Plan) error {
+	if approved == nil {
+		return nil
+	}
+
+	if diffs := PlanDifferences(plan, approved); len(diffs) > 0 {
+		return planBlocked("error: the plan differs from the approved plan, not applying: %s", strings.Join(diffs, "; "))
+	}
+
+	return nil
+}
+
+// PlanChecks are the checks a plan must pass to be applied
+type PlanChecks struct {
+	AllowWorkspaceDeletion bool
+	DeletableWorkspaces    []string
+	MaxNewWorkspaces       int
+	AllowTagChanges        bool
+}
+
+// NewPlanChecks returns the plan checks configured by the passed inputs, allowing tag changes unless allow_tag_changes is explicitly false
+func NewPlanChecks(config *Inputs, deletableWorkspaces []string, maxNewWorkspaces int) *PlanChecks {
+	return &PlanChecks{
+		AllowWorkspaceDeletion: config.AllowWorkspaceDeletion,
+		DeletableWorkspaces:    deletableWorkspaces,
+		MaxNewWorkspaces:       maxNewWorkspaces,
+		AllowTagChanges:        config.AllowTagChanges == nil || *config.AllowTagChanges,
+	}
+}
+
+// Check returns an error if the passed plan deletes workspaces that are not allowed to be deleted, creates more than the maximum number of workspaces or changes workspace tags without allowing it
+func (c *PlanChecks) Check(plan *tfjson.Plan) error {
+	if destroyed := DestroyedWorkspaces(plan); len(destroyed) > 0 && !c.AllowWorkspaceDeletion {
+		blocked, err := BlockedWorkspaceDeletions(destroyed, c.DeletableWorkspaces)
+		if err != nil {
+			return fmt.Errorf("failed to check workspace deletions: %w", err)
+		}
+
+		if len(blocked) > 0 {
+			return planBlocked("error: allow_workspace_deletion must be true, or allow_workspace_deletion_names must list %s, to allow workspace deletion. Deleting a workspace will permanently, irrecoverably delete all of its stored Terraform state versions", strings.Join(blocked, ", "))
+		}
+	}
+
+	if created := CreatedWorkspaces(plan); c.MaxNewWorkspaces >= 0 && len(created) > c.MaxNewWorkspaces {
+		return planBlocked("error: the plan creates %d workspaces (%s), more than max_new_workspaces (%d)", len(created), strings.Join(created, ", "), c.MaxNewWorkspaces)
+	}
+
+	if len(WorkspaceTagChanges(plan)) > 0 && !c.AllowTagChanges {
+		return planBlocked("error: allow_tag_changes must be true to change the tags of existing workspaces. Tag changes can affect policy sets and other configuration scoped by tag")
+	}
+
+	return nil
+}
+
+// PlanSubsetDifferences describes each pending change of the passed plan that is not part of the reviewed plan with the same action and planned values, sorted by address.
+// Values that were unknown in the reviewed plan, such as the IDs of resources created since, are not compared.
+func PlanSubsetDifferences(plan *tfjson.Plan, reviewed *tfjson.Plan) []string {
+	previous := pendingChanges(reviewed)
+	diffs := []string{}
+
+	for address, change := range pendingChanges(plan) {
+		prev, ok := previous[address]
+
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s is now planned to %s", address, planActionName(change.Actions)))
+		case !reflect.DeepEqual(change.Actions, prev.Actions):
+			diffs = append(diffs, fmt.Sprintf("%s is now planned to %s instead of %s", address, planActionName(change.Actions), planActionName(prev.Actions)))
+		case !knownValuesMatch(change, prev):
+			diffs = append(diffs, fmt.Sprintf("%s has different planned values", address))
+		}
+	}
+
+	sort.Strings(diffs)
+
+	return diffs
+}
+
+// knownValuesMatch returns true if the planned values of the passed change match the values of the reviewed change, skipping the attributes that were unknown in the reviewed change
+func knownValuesMatch(change *tfjson.Change, reviewed *tfjson.Change) bool {
+	after, ok := change.After.(map[string]interface{})
+	prevAfter, prevOK := reviewed.After.(map[string]interface{})
+
+	if !ok || !prevOK {
+		return reflect.DeepEqual(change.After, reviewed.After)
+	}
+
+	unknown, _ := reviewed.AfterUnknown.(map[string]interface{})
+
+	for _, values := range []map[string]interface{}{after, prevAfter} {
+		for attr := range values {
+			if u, ok := unknown[attr].(bool); ok && u {
+				continue
+			}
+
+			if !reflect.DeepEqual(after[attr], prevAfter[attr]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ContinueAfterPartialFailure logs the outcome of a failed apply and applies the remaining independent resources.
+// The remaining changes are saved to a plan that must pass the passed plan check and only contain changes of the saved plan before it is applied.
+func ContinueAfterPartialFailure(ctx context.Context, tf terraformApplier, plan *tfjson.Plan, applyErr error, targetOpts []tfexec.PlanOption, check func(*tfjson.Plan) error) error {
+	failed := FailedResources(applyErr)
+	if len(failed) == 0 {
+		githubactions.Infof("Apply failure is not scoped to a resource, skipping partial apply continuation\n")
+
+		return nil
+	}
+
+	githubactions.Infof("Apply failed for %s\n", strings.Join(failed, ", "))
+
+	remaining, err := showPlan(ctx, tf, "remaining.plan.txt", targetOpts)
+	if err != nil {
+		return fmt.Errorf("failed to plan remaining changes: %w", err)
+	}
+
+	if succeeded := SucceededResources(plan, remaining); len(succeeded) > 0 {
+		githubactions.Infof("Applied successfully: %s\n", strings.Join(succeeded, ", "))
+	}
+
+	targets := ContinuationTargets(remaining, failed)
+	if len(targets) == 0 {
+		githubactions.Infof("No independent resources left to apply\n")
+
+		return nil
+	}
+
+	githubactions.Infof("Continuing with %s\n", strings.Join(targets, ", "))
+
+	continuationOpts := []tfexec.PlanOption{}
+
+	for _, target := range targets {
+		continuationOpts = append(continuationOpts, tfexec.Target(target))
+	}
+
+	planPath := "continue.plan.txt"
+
+	continuation, err := showPlan(ctx, tf, planPath, continuationOpts)
+	if err != nil {
+		return fmt.Errorf("failed to plan the remaining resources: %w", err)
+	}
+
+	if err = check(continuation); err != nil {
+		return err
+	}
+
+	if diffs := PlanSubsetDifferences(continuation, plan); len(diffs) > 0 {
+		return planBlocked("error: the remaining changes are not part of the saved plan, not applying: %s", strings.Join(diffs, "; "))
+	}
+
+	if err = tf.Apply(ctx, tfexec.DirOrPlan(planPath)); err != nil {
+		return fmt.Errorf("failed to apply remaining resources: %w", err)
+	}
+
+	return nil
+}
+
+// showPlan saves a plan with the passed options to the passed path and returns it, which has no changes if nothing is planned
+func showPlan(ctx context.Context, tf terraformApplier, planPath string, opts []tfexec.PlanOption) (*tfjson.Plan, error) {
+	diff, err := tf.Plan(ctx, append([]tfexec.PlanOption{tfexec.Out(planPath)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff {
+		return &tfjson.Plan{}, nil
+	}
+
+	plan, err := tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan struct: %w", err)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,253 @@
+package action
+
+import (
+	"errors"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedResources(t *testing.T) {
+	t.Run("return the addresses of failed resources", func(t *testing.T) {
+		err := errors.New(`exit status 1
+
+Error: Error creating variable
+
+  with tfe_variable.staging-foo,
+  on main.tf.json line 1, in resource.tfe_variable.staging-foo:
+
+Error: Error updating workspace
+
+│   with tfe_workspace.workspace["production"],
+│   on main.tf.json line 1, in resource.tfe_workspace.workspace:
+
+Error: Error creating variable
+
+  with tfe_variable.staging-foo,
+`)
+
+		assert.Equal(t, []string{
+			`tfe_variable.staging-foo`,
+			`tfe_workspace.workspace["production"]`,
+		}, FailedResources(err))
+	})
+
+	t.Run("return no addresses when the error is not resource scoped", func(t *testing.T) {
+		assert.Empty(t, FailedResources(errors.New("Error: Failed to read organization")))
+		assert.Empty(t, FailedResources(nil))
+	})
+}
+
+// newTestContinuationPlan returns a plan creating a workspace, a variable referencing it and an unrelated variable
+func newTestContinuationPlan() *tfjson.Plan {
+	create := &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}
+
+	return &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: `tfe_workspace.workspace["staging"]`, Type: "tfe_workspace", Name: "workspace", Change: create},
+			{Address: `tfe_workspace.workspace["production"]`, Type: "tfe_workspace", Name: "workspace", Change: create},
+			{Address: "tfe_variable.staging-foo", Type: "tfe_variable", Name: "staging-foo", Change: create},
+			{Address: "tfe_variable.production-foo", Type: "tfe_variable", Name: "production-foo", Change: create},
+			{Address: "tfe_team_access.teams", Type: "tfe_team_access", Name: "teams", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+		},
+		Config: &tfjson.Config{
+			RootModule: &tfjson.ConfigModule{
+				Resources: []*tfjson.ConfigResource{
+					{
+						Address: "tfe_variable.staging-foo",
+						Expressions: map[string]*tfjson.Expression{
+							"workspace_id": {ExpressionData: &tfjson.ExpressionData{References: []string{
+								`tfe_workspace.workspace["staging"].id`,
+								`tfe_workspace.workspace["staging"]`,
+								"tfe_workspace.workspace",
+							}}},
+						},
+					},
+					{
+						Address: "tfe_variable.production-foo",
+						Expressions: map[string]*tfjson.Expression{
+							"workspace_id": {ExpressionData: &tfjson.ExpressionData{References: []string{
+								`tfe_workspace.workspace["production"].id`,
+							}}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestContinuationTargets(t *testing.T) {
+	t.Run("exclude failed resources and their dependents", func(t *testing.T) {
+		targets := ContinuationTargets(newTestContinuationPlan(), []string{`tfe_workspace.workspace["staging"]`})
+
+		assert.Equal(t, []string{
+			"tfe_variable.production-foo",
+			`tfe_workspace.workspace["production"]`,
+		}, targets)
+	})
+
+	t.Run("exclude only the failed resource when nothing depends on it", func(t *testing.T) {
+		targets := ContinuationTargets(newTestContinuationPlan(), []string{"tfe_variable.staging-foo"})
+
+		assert.Equal(t, []string{
+			"tfe_variable.production-foo",
+			`tfe_workspace.workspace["production"]`,
+			`tfe_workspace.workspace["staging"]`,
+		}, targets)
+	})
+
+	t.Run("exclude for_each dependents and the resources depending on them", func(t *testing.T) {
+		create := &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}
+
+		plan := newTestContinuationPlan()
+		plan.ResourceChanges = append(plan.ResourceChanges,
+			&tfjson.ResourceChange{Address: `tfe_workspace_run.run["staging"]`, Type: "tfe_workspace_run", Name: "run", Change: create},
+			&tfjson.ResourceChange{Address: `tfe_workspace_run.run["production"]`, Type: "tfe_workspace_run", Name: "run", Change: create},
+			&tfjson.ResourceChange{Address: "tfe_notification_configuration.production", Type: "tfe_notification_configuration", Name: "production", Change: create},
+		)
+		plan.Config.RootModule.Resources = append(plan.Config.RootModule.Resources,
+			&tfjson.ConfigResource{
+				Address:           "tfe_workspace_run.run",
+				ForEachExpression: &tfjson.Expression{ExpressionData: &tfjson.ExpressionData{References: []string{"tfe_workspace.workspace"}}},
+			},
+			&tfjson.ConfigResource{
+				Address:   "tfe_notification_configuration.production",
+				DependsOn: []string{`tfe_workspace_run.run["production"]`},
+			},
+		)
+
+		targets := ContinuationTargets(plan, []string{`tfe_workspace.workspace["staging"]`})
+
+		assert.Equal(t, []string{
+			"tfe_variable.production-foo",
+			`tfe_workspace.workspace["production"]`,
+		}, targets)
+	})
+}
+
+func TestSucceededResources(t *testing.T) {
+	original := newTestContinuationPlan()
+	remaining := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_variable.staging-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"tfe_variable.production-foo",
+		`tfe_workspace.workspace["production"]`,
+		`tfe_workspace.workspace["staging"]`,
+	}, SucceededResources(original, remaining))
+}
+
+func TestVerifyApprovedPlan(t *testing.T) {
+	plan := &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+		{Address: "tfe_variable.default-foo", Type: "tfe_variable", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+	}}
+
+	t.Run("pass without an approved plan", func(t *testing.T) {
+		assert.NoError(t, VerifyApprovedPlan(plan, nil))
+	})
+
+	t.Run("pass when the plan matches", func(t *testing.T) {
+		assert.NoError(t, VerifyApprovedPlan(plan, plan))
+	})
+
+	t.Run("error when the plan differs", func(t *testing.T) {
+		err := VerifyApprovedPlan(&tfjson.Plan{}, plan)
+
+		var blocked *PlanBlockedError
+
+		assert.ErrorAs(t, err, &blocked)
+	})
+}
+
+func TestPlanChecks(t *testing.T) {
+	deleted := &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+		{Address: `tfe_workspace.workspace["staging"]`, Type: "tfe_workspace", Change: &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionDelete},
+			Before:  map[string]interface{}{"name": "foo-staging"},
+		}},
+	}}
+
+	created := &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+		{Address: `tfe_workspace.workspace["staging"]`, Type: "tfe_workspace", Change: &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionCreate},
+			After:   map[string]interface{}{"name": "foo-staging"},
+		}},
+	}}
+
+	t.Run("block workspace deletions that are not allowed", func(t *testing.T) {
+		err := (&PlanChecks{MaxNewWorkspaces: -1}).Check(deleted)
+
+		var blocked *PlanBlockedError
+
+		assert.ErrorAs(t, err, &blocked)
+	})
+
+	t.Run("pass workspace deletions allowed by name", func(t *testing.T) {
+		assert.NoError(t, (&PlanChecks{MaxNewWorkspaces: -1, DeletableWorkspaces: []string{"foo-*"}}).Check(deleted))
+	})
+
+	t.Run("block more new workspaces than allowed", func(t *testing.T) {
+		err := (&PlanChecks{MaxNewWorkspaces: 0}).Check(created)
+		assert.EqualError(t, err, "error: the plan creates 1 workspaces (foo-staging), more than max_new_workspaces (0)")
+	})
+
+	t.Run("pass without a limit of new workspaces", func(t *testing.T) {
+		assert.NoError(t, (&PlanChecks{MaxNewWorkspaces: -1}).Check(created))
+	})
+}
+
+func TestNewPlanChecks(t *testing.T) {
+	t.Run("allow tag changes when unset", func(t *testing.T) {
+		assert.True(t, NewPlanChecks(&Inputs{}, nil, -1).AllowTagChanges)
+	})
+
+	t.Run("block tag changes when explicitly disabled", func(t *testing.T) {
+		assert.False(t, NewPlanChecks(&Inputs{AllowTagChanges: boolPtr(false)}, nil, -1).AllowTagChanges)
+	})
+
+	t.Run("keep the deletion and new workspace limits", func(t *testing.T) {
+		checks := NewPlanChecks(&Inputs{AllowWorkspaceDeletion: true}, []string{"foo-*"}, 2)
+
+		assert.True(t, checks.AllowWorkspaceDeletion)
+		assert.Equal(t, []string{"foo-*"}, checks.DeletableWorkspaces)
+		assert.Equal(t, 2, checks.MaxNewWorkspaces)
+	})
+}
+
+func TestPlanSubsetDifferences(t *testing.T) {
+	reviewed := &tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+		{Address: "tfe_variable.staging-foo", Change: &tfjson.Change{
+			Actions:      tfjson.Actions{tfjson.ActionCreate},
+			After:        map[string]interface{}{"key": "foo", "value": "bar"},
+			AfterUnknown: map[string]interface{}{"workspace_id": true},
+		}},
+		{Address: "tfe_variable.production-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+	}}
+
+	t.Run("pass for a subset of the reviewed changes with values known since", func(t *testing.T) {
+		assert.Empty(t, PlanSubsetDifferences(&tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_variable.staging-foo", Change: &tfjson.Change{
+				Actions: tfjson.Actions{tfjson.ActionCreate},
+				After:   map[string]interface{}{"key": "foo", "value": "bar", "workspace_id": "ws-abc123"},
+			}},
+		}}, reviewed))
+	})
+
+	t.Run("describe changes that were not reviewed", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"tfe_variable.qa-foo is now planned to create",
+			"tfe_variable.staging-foo has different planned values",
+		}, PlanSubsetDifferences(&tfjson.Plan{ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_variable.staging-foo", Change: &tfjson.Change{
+				Actions: tfjson.Actions{tfjson.ActionCreate},
+				After:   map[string]interface{}{"key": "foo", "value": "baz"},
+			}},
+			{Address: "tfe_variable.qa-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+		}}, reviewed))
+	})
+}
@@ -0,0 +1,169 @@
+package action
+
+// redacted replaces secret values in the resolved inputs
+const redacted = "[REDACTED]"
+
+// ResolvedInputs is the fully resolved configuration of a run, as set in the "resolved_inputs_json" output. Secret values are redacted.
+type ResolvedInputs struct {
+	Host             string               `json:"host"`
+	Organization     string               `json:"organization"`
+	Token            string               `json:"token,omitempty"`
+	HTTPHeaders      map[string]string    `json:"http_headers,omitempty"`
+	Apply            bool                 `json:"apply"`
+	TargetWorkspaces []string             `json:"target_workspaces,omitempty"`
+	Workspaces       []ResolvedWorkspace  `json:"workspaces"`
+	Variables        []ResolvedVariable   `json:"variables"`
+	TeamAccess       []ResolvedTeamAccess `json:"team_access"`
+	Settings         ResolvedSettings     `json:"settings"`
+}
+
+// ResolvedWorkspace is a workspace managed by the run
+type ResolvedWorkspace struct {
+	Name         string `json:"name"`
+	Workspace    string `json:"workspace"`
+	ID           string `json:"id,omitempty"`
+	Organization string `json:"organization"`
+}
+
+// ResolvedVariable is a variable of a workspace managed by the run, with the value redacted if it is sensitive
+type ResolvedVariable struct {
+	Workspace   string `json:"workspace"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category"`
+	Sensitive   bool   `json:"sensitive"`
+	HCL         bool   `json:"hcl"`
+}
+
+// ResolvedTeamAccess is the access of a team to a workspace managed by the run
+type ResolvedTeamAccess struct {
+	Workspace   string                      `json:"workspace"`
+	TeamName    string                      `json:"team_name"`
+	Access      string                      `json:"access,omitempty"`
+	Permissions *TeamAccessPermissionsInput `json:"permissions,omitempty"`
+}
+
+// ResolvedSettings are the settings applied to the workspaces managed by the run
+type ResolvedSettings struct {
+	AgentPoolID                string          `json:"agent_pool_id,omitempty"`
+	AgentPoolName              string          `json:"agent_pool_name,omitempty"`
+	AssessmentsEnabled         *bool           `json:"assessments_enabled,omitempty"`
+	AutoApply                  *bool           `json:"auto_apply,omitempty"`
+	AutoDestroyAt              string          `json:"auto_destroy_at,omitempty"`
+	Description                string          `json:"description,omitempty"`
+	ExecutionMode              string          `json:"execution_mode,omitempty"`
+	FileTriggersEnabled        *bool           `json:"file_triggers_enabled,omitempty"`
+	GlobalRemoteState          *bool           `json:"global_remote_state,omitempty"`
+	QueueAllRuns               *bool           `json:"queue_all_runs,omitempty"`
+	RemoteStateConsumerIDs     string          `json:"remote_state_consumer_ids,omitempty"`
+	SpeculativeEnabled         *bool           `json:"speculative_enabled,omitempty"`
+	StructuredRunOutputEnabled *bool           `json:"structured_run_output_enabled,omitempty"`
+	SSHKeyID                   string          `json:"ssh_key_id,omitempty"`
+	Tags                       map[string]Tags `json:"tags,omitempty"`
+	TerraformVersion           string          `json:"terraform_version,omitempty"`
+	TriggerPatterns            []string        `json:"trigger_patterns,omitempty"`
+	TriggerPrefixes            []string        `json:"trigger_prefixes,omitempty"`
+	TriggerTagsRegex           string          `json:"trigger_tags_regex,omitempty"`
+	VCSIngressSubmodules       bool            `json:"vcs_ingress_submodules,omitempty"`
+	VCSRepo                    string          `json:"vcs_repo,omitempty"`
+	VCSTokenID                 string          `json:"vcs_token_id,omitempty"`
+	VCSType                    string          `json:"vcs_type,omitempty"`
+	WorkingDirectory           string          `json:"working_directory,omitempty"`
+
+	WorkspaceSettings map[string]WorkspaceSettings `json:"workspace_settings,omitempty"`
+}
+
+// NewResolvedInputs returns the resolved configuration of a run, redacting the token, HTTP header values and sensitive variable values
+func NewResolvedInputs(config *Inputs, workspaces []*Workspace, variables Variables, teamAccess TeamAccess, options *WorkspaceResourceOptions, headers map[string]string, targets []string) *ResolvedInputs {
+	resolved := &ResolvedInputs{
+		Host:             config.Host,
+		Organization:     config.Organization,
+		Apply:            config.Apply,
+		TargetWorkspaces: targets,
+		Workspaces:       []ResolvedWorkspace{},
+		Variables:        []ResolvedVariable{},
+		TeamAccess:       []ResolvedTeamAccess{},
+		Settings: ResolvedSettings{
+			AgentPoolID:                options.AgentPoolID,
+			AgentPoolName:              options.AgentPoolName,
+			AssessmentsEnabled:         options.AssessmentsEnabled,
+			AutoApply:                  options.AutoApply,
+			AutoDestroyAt:              options.AutoDestroyAt,
+			Description:                options.Description,
+			ExecutionMode:              options.ExecutionMode,
+			FileTriggersEnabled:        options.FileTriggersEnabled,
+			GlobalRemoteState:          options.GlobalRemoteState,
+			QueueAllRuns:               options.QueueAllRuns,
+			RemoteStateConsumerIDs:     options.RemoteStateConsumerIDs,
+			SpeculativeEnabled:         options.SpeculativeEnabled,
+			StructuredRunOutputEnabled: options.StructuredRunOutputEnabled,
+			SSHKeyID:                   options.SSHKeyID,
+			Tags:                       options.Tags,
+			TerraformVersion:           options.TerraformVersion,
+			TriggerPatterns:            options.TriggerPatterns,
+			TriggerPrefixes:            options.TriggerPrefixes,
+			TriggerTagsRegex:           options.TriggerTagsRegex,
+			VCSIngressSubmodules:       options.VCSIngressSubmodules,
+			VCSRepo:                    options.VCSRepo,
+			VCSTokenID:                 options.VCSTokenID,
+			VCSType:                    options.VCSType,
+			WorkingDirectory:           options.WorkingDirectory,
+			WorkspaceSettings:          options.WorkspaceSettings,
+		},
+	}
+
+	if config.Token != "" {
+		resolved.Token = redacted
+	}
+
+	if len(headers) > 0 {
+		resolved.HTTPHeaders = map[string]string{}
+
+		for name := range headers {
+			resolved.HTTPHeaders[name] = redacted
+		}
+	}
+
+	for _, ws := range workspaces {
+		rw := ResolvedWorkspace{
+			Name:         ws.Name,
+			Workspace:    ws.Workspace,
+			Organization: workspaceOrganization(ws, config.Organization),
+		}
+
+		if ws.ID != nil {
+			rw.ID = *ws.ID
+		}
+
+		resolved.Workspaces = append(resolved.Workspaces, rw)
+	}
+
+	for _, v := range variables {
+		value := v.Value
+		if v.Sensitive {
+			value = redacted
+		}
+
+		resolved.Variables = append(resolved.Variables, ResolvedVariable{
+			Workspace:   v.Workspace.Workspace,
+			Key:         v.Key,
+			Value:       value,
+			Description: v.Description,
+			Category:    v.Category,
+			Sensitive:   v.Sensitive,
+			HCL:         v.HCL,
+		})
+	}
+
+	for _, ta := range teamAccess {
+		resolved.TeamAccess = append(resolved.TeamAccess, ResolvedTeamAccess{
+			Workspace:   ta.Workspace.Workspace,
+			TeamName:    ta.TeamName,
+			Access:      ta.Access,
+			Permissions: ta.Permissions,
+		})
+	}
+
+	return resolved
+}
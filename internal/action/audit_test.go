@@ -0,0 +1,50 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolvedInputs(t *testing.T) {
+	ws := &Workspace{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")}
+
+	resolved := NewResolvedInputs(
+		&Inputs{Host: "app.terraform.io", Organization: "org", Token: "secret-token", Apply: true},
+		[]*Workspace{ws},
+		Variables{
+			{Key: "foo", Value: "bar", Category: "env", Workspace: ws},
+			{Key: "password", Value: "hunter2", Category: "terraform", Sensitive: true, Workspace: ws},
+		},
+		TeamAccess{
+			{TeamName: "readers", Access: "read", Workspace: ws},
+		},
+		&WorkspaceResourceOptions{ExecutionMode: "remote", AutoApply: boolPtr(true)},
+		map[string]string{"X-Proxy-Auth": "secret-header"},
+		nil,
+	)
+
+	b, err := json.Marshal(resolved)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+	"host": "app.terraform.io",
+	"organization": "org",
+	"token": "[REDACTED]",
+	"http_headers": {"X-Proxy-Auth": "[REDACTED]"},
+	"apply": true,
+	"workspaces": [{"name": "foo-staging", "workspace": "staging", "id": "ws-abc123", "organization": "org"}],
+	"variables": [
+		{"workspace": "staging", "key": "foo", "value": "bar", "category": "env", "sensitive": false, "hcl": false},
+		{"workspace": "staging", "key": "password", "value": "[REDACTED]", "category": "terraform", "sensitive": true, "hcl": false}
+	],
+	"team_access": [{"workspace": "staging", "team_name": "readers", "access": "read"}],
+	"settings": {"auto_apply": true, "execution_mode": "remote"}
+}`, string(b))
+
+	assert.NotContains(t, string(b), "secret-token")
+	assert.NotContains(t, string(b), "secret-header")
+	assert.NotContains(t, string(b), "hunter2")
+}
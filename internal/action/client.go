@@ -0,0 +1,150 @@
+package action
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// systemCABundleFiles are the locations of the system root certificates, searched in order as Go does on Linux
+var systemCABundleFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// WriteCABundle writes the system root certificates followed by the CA bundle at the passed path to a file in the passed directory and returns its path.
+// Terraform replaces its trusted roots with the SSL_CERT_FILE bundle, so the system roots are kept to still reach public hosts such as the registry.
+func WriteCABundle(caBundlePath string, dir string) (string, error) {
+	b, err := os.ReadFile(caBundlePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	candidates := systemCABundleFiles
+	if f := os.Getenv("SSL_CERT_FILE"); f != "" {
+		candidates = append([]string{f}, candidates...)
+	}
+
+	var system []byte
+
+	for _, f := range candidates {
+		if system, err = os.ReadFile(f); err == nil {
+			break
+		}
+	}
+
+	if len(system) == 0 {
+		githubactions.Warningf("System root certificates not found, Terraform only trusts the CA bundle %s\n", caBundlePath)
+	}
+
+	bundle := append(append(system, '\n'), b...)
+
+	bundlePath := path.Join(dir, "ca-bundle.pem")
+	if err = os.WriteFile(bundlePath, bundle, 0644); err != nil {
+		return "", fmt.Errorf("failed to write CA bundle: %w", err)
+	}
+
+	return bundlePath, nil
+}
+
+// NewHTTPClient returns the HTTP client used for Terraform Cloud API requests.
+// Certificates signed by the CA bundle at the passed path are trusted in addition to the system roots, or TLS certificate verification is skipped if requested.
+// The passed headers are added to every request.
+func NewHTTPClient(sslSkipVerify bool, caBundlePath string, headers map[string]string) (*http.Client, error) {
+	if err := ValidateHTTPHeaders(headers); err != nil {
+		return nil, err
+	}
+
+	client := cleanhttp.DefaultPooledClient()
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: sslSkipVerify,
+	}
+
+	if caBundlePath != "" {
+		b, err := os.ReadFile(caBundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caBundlePath)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+
+	if len(headers) > 0 {
+		h := http.Header{}
+		for name, value := range headers {
+			h.Set(name, value)
+		}
+
+		client.Transport = &headerTransport{base: client.Transport, headers: h}
+	}
+
+	return client, nil
+}
+
+// headerTransport adds a fixed set of headers to every request sent through the base transport
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+// RoundTrip adds the headers to a copy of the request and sends it through the base transport
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// ValidateHTTPHeaders checks that the passed header names are valid HTTP tokens, that the values are single line and that no header set by the API client is overridden
+func ValidateHTTPHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isHeaderTokenRune(r) }) != -1 {
+			return fmt.Errorf("invalid HTTP header name %q", name)
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("HTTP header %q must be a single line", name)
+		}
+
+		if http.CanonicalHeaderKey(name) == "Authorization" {
+			return fmt.Errorf("HTTP header %q is set from the Terraform token and cannot be overridden", name)
+		}
+	}
+
+	return nil
+}
+
+// isHeaderTokenRune returns true if the rune is allowed in an HTTP header name, as defined by the token rule of RFC 7230
+func isHeaderTokenRune(r rune) bool {
+	if r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+		return true
+	}
+
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
@@ -0,0 +1,124 @@
+package action
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("verify certificates by default", func(t *testing.T) {
+		client, err := NewHTTPClient(false, "", nil)
+		require.NoError(t, err)
+
+		_, err = client.Get(server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("skip certificate verification", func(t *testing.T) {
+		client, err := NewHTTPClient(true, "", nil)
+		require.NoError(t, err)
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("trust the certificates of a CA bundle", func(t *testing.T) {
+		bundlePath := path.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundlePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644))
+
+		client, err := NewHTTPClient(false, bundlePath, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("error on a CA bundle without certificates", func(t *testing.T) {
+		bundlePath := path.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundlePath, []byte("foo"), 0644))
+
+		_, err := NewHTTPClient(false, bundlePath, nil)
+		assert.EqualError(t, err, "no certificates found in CA bundle "+bundlePath)
+	})
+
+	t.Run("add headers to every request", func(t *testing.T) {
+		var received http.Header
+
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer plain.Close()
+
+		client, err := NewHTTPClient(false, "", map[string]string{"X-Proxy-Auth": "secret", "traceparent": "00-abc-def-01"})
+		require.NoError(t, err)
+
+		resp, err := client.Get(plain.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "secret", received.Get("X-Proxy-Auth"))
+		assert.Equal(t, "00-abc-def-01", received.Get("Traceparent"))
+	})
+}
+
+func TestWriteCABundle(t *testing.T) {
+	dir := t.TempDir()
+
+	systemPath := path.Join(dir, "system.pem")
+	require.NoError(t, os.WriteFile(systemPath, []byte("system"), 0644))
+
+	bundlePath := path.Join(dir, "internal.pem")
+	require.NoError(t, os.WriteFile(bundlePath, []byte("internal"), 0644))
+
+	files := systemCABundleFiles
+	t.Cleanup(func() { systemCABundleFiles = files })
+
+	systemCABundleFiles = []string{path.Join(dir, "missing.pem"), systemPath}
+	t.Setenv("SSL_CERT_FILE", "")
+
+	written, err := WriteCABundle(bundlePath, dir)
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(written)
+	require.NoError(t, err)
+
+	assert.Equal(t, "system\ninternal", string(b))
+}
+
+func TestValidateHTTPHeaders(t *testing.T) {
+	t.Run("accept valid headers", func(t *testing.T) {
+		assert.NoError(t, ValidateHTTPHeaders(map[string]string{"X-Proxy-Auth": "secret", "X-B3-TraceId": "abc"}))
+	})
+
+	t.Run("error on an invalid header name", func(t *testing.T) {
+		assert.EqualError(t, ValidateHTTPHeaders(map[string]string{"X Proxy": "secret"}), `invalid HTTP header name "X Proxy"`)
+		assert.EqualError(t, ValidateHTTPHeaders(map[string]string{"": "secret"}), `invalid HTTP header name ""`)
+	})
+
+	t.Run("error on a multi line value", func(t *testing.T) {
+		assert.EqualError(t, ValidateHTTPHeaders(map[string]string{"X-Proxy-Auth": "secret\nX-Other: foo"}), `HTTP header "X-Proxy-Auth" must be a single line`)
+	})
+
+	t.Run("error on overriding the authorization header", func(t *testing.T) {
+		assert.EqualError(t, ValidateHTTPHeaders(map[string]string{"authorization": "Bearer foo"}), `HTTP header "authorization" is set from the Terraform token and cannot be overridden`)
+	})
+}
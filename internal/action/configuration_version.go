@@ -0,0 +1,32 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// UploadEmptyConfigurationVersion uploads an empty configuration to the passed workspace, without queueing a run, so that a CLI-driven workspace is not left awaiting its initial configuration
+func UploadEmptyConfigurationVersion(ctx context.Context, client *tfe.Client, workspaceID string) error {
+	dir, err := ioutil.TempDir("", "empty-configuration")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	cv, err := client.ConfigurationVersions.Create(ctx, workspaceID, tfe.ConfigurationVersionCreateOptions{
+		AutoQueueRuns: tfe.Bool(false),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create configuration version: %w", err)
+	}
+
+	if err = client.ConfigurationVersions.Upload(ctx, cv.UploadURL, dir); err != nil {
+		return fmt.Errorf("failed to upload configuration version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadEmptyConfigurationVersion(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	var (
+		autoQueueRuns interface{}
+		uploaded      bool
+	)
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123/configuration-versions", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &body))
+
+		autoQueueRuns = body.Data.Attributes["auto-queue-runs"]
+
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(201)
+		fmt.Fprintf(w, `{"data": {"id": "cv-abc123", "type": "configuration-versions", "attributes": {"upload-url": "%s/upload"}}}`, server.URL)
+	})
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		uploaded = true
+	})
+
+	client := newTestTFClient(t, server.URL)
+
+	require.NoError(t, UploadEmptyConfigurationVersion(ctx, client, "ws-abc123"))
+
+	assert.Equal(t, false, autoQueueRuns)
+	assert.True(t, uploaded)
+}
@@ -0,0 +1,83 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// CostEstimate is the cost estimate of a Terraform Cloud run, as set in the "cost_estimate_json" output
+type CostEstimate struct {
+	ID                      string `json:"id"`
+	Status                  string `json:"status"`
+	PriorMonthlyCost        string `json:"prior_monthly_cost"`
+	ProposedMonthlyCost     string `json:"proposed_monthly_cost"`
+	DeltaMonthlyCost        string `json:"delta_monthly_cost"`
+	ResourcesCount          int    `json:"resources_count"`
+	MatchedResourcesCount   int    `json:"matched_resources_count"`
+	UnmatchedResourcesCount int    `json:"unmatched_resources_count"`
+	ErrorMessage            string `json:"error_message,omitempty"`
+}
+
+// FetchCostEstimate returns the cost estimate of the latest run of the passed workspace, if the run was created after the passed time.
+// Nil is returned if cost estimation is disabled for the organization, no run was created since or the run has no cost estimate.
+func FetchCostEstimate(ctx context.Context, client *tfe.Client, organization string, workspace string, since time.Time) (*CostEstimate, error) {
+	org, err := client.Organizations.Read(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	if !org.CostEstimationEnabled {
+		githubactions.Infof("Cost estimation is not enabled for organization %q, skipping cost estimate\n", organization)
+		return nil, nil
+	}
+
+	ws, err := client.Workspaces.Read(ctx, organization, workspace)
+	if err != nil {
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			githubactions.Infof("Workspace %q not found, skipping cost estimate\n", workspace)
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	runs, err := client.Runs.List(ctx, ws.ID, tfe.RunListOptions{
+		ListOptions: tfe.ListOptions{
+			PageSize: 1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs.Items) == 0 || runs.Items[0].CreatedAt.Before(since) {
+		githubactions.Infof("No run was created in workspace %q, skipping cost estimate\n", workspace)
+		return nil, nil
+	}
+
+	if runs.Items[0].CostEstimate == nil {
+		githubactions.Infof("No cost estimate found for the latest run of workspace %q, skipping cost estimate\n", workspace)
+		return nil, nil
+	}
+
+	ce, err := client.CostEstimates.Read(ctx, runs.Items[0].CostEstimate.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CostEstimate{
+		ID:                      ce.ID,
+		Status:                  string(ce.Status),
+		PriorMonthlyCost:        ce.PriorMonthlyCost,
+		ProposedMonthlyCost:     ce.ProposedMonthlyCost,
+		DeltaMonthlyCost:        ce.DeltaMonthlyCost,
+		ResourcesCount:          ce.ResourcesCount,
+		MatchedResourcesCount:   ce.MatchedResourcesCount,
+		UnmatchedResourcesCount: ce.UnmatchedResourcesCount,
+		ErrorMessage:            ce.ErrorMessage,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCostEstimate(t *testing.T) {
+	ctx := context.Background()
+
+	workspaceResponse := `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "state"}}}`
+
+	since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("return the cost estimate of the latest run", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"name": "org", "cost-estimation-enabled": true}}}`))
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/state", testServerResHandler(t, 200, workspaceResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/runs", testServerResHandler(t, 200, `{"data": [{"id": "run-abc123", "type": "runs", "attributes": {"created-at": "2022-01-01T00:05:00Z"}, "relationships": {"cost-estimate": {"data": {"id": "ce-abc123", "type": "cost-estimates"}}}}]}`))
+		mux.HandleFunc("/api/v2/cost-estimates/ce-abc123", testServerResHandler(t, 200, `{"data": {"id": "ce-abc123", "type": "cost-estimates", "attributes": {"status": "finished", "prior-monthly-cost": "1.00", "proposed-monthly-cost": "3.00", "delta-monthly-cost": "2.00", "resources-count": 4, "matched-resources-count": 3, "unmatched-resources-count": 1}}}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		ce, err := FetchCostEstimate(ctx, newTestTFClient(t, server.URL), "org", "state", since)
+		require.NoError(t, err)
+
+		assert.Equal(t, &CostEstimate{
+			ID:                      "ce-abc123",
+			Status:                  "finished",
+			PriorMonthlyCost:        "1.00",
+			ProposedMonthlyCost:     "3.00",
+			DeltaMonthlyCost:        "2.00",
+			ResourcesCount:          4,
+			MatchedResourcesCount:   3,
+			UnmatchedResourcesCount: 1,
+		}, ce)
+	})
+
+	t.Run("return nil if cost estimation is disabled", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"name": "org", "cost-estimation-enabled": false}}}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		ce, err := FetchCostEstimate(ctx, newTestTFClient(t, server.URL), "org", "state", since)
+		require.NoError(t, err)
+
+		assert.Nil(t, ce)
+	})
+
+	t.Run("return nil if the workspace has no runs", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"name": "org", "cost-estimation-enabled": true}}}`))
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/state", testServerResHandler(t, 200, workspaceResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/runs", testServerResHandler(t, 200, `{"data": []}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		ce, err := FetchCostEstimate(ctx, newTestTFClient(t, server.URL), "org", "state", since)
+		require.NoError(t, err)
+
+		assert.Nil(t, ce)
+	})
+	t.Run("return nil if the latest run was created before the plan", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"name": "org", "cost-estimation-enabled": true}}}`))
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/state", testServerResHandler(t, 200, workspaceResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/runs", testServerResHandler(t, 200, `{"data": [{"id": "run-abc123", "type": "runs", "attributes": {"created-at": "2021-12-31T23:55:00Z"}, "relationships": {"cost-estimate": {"data": {"id": "ce-abc123", "type": "cost-estimates"}}}}]}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		ce, err := FetchCostEstimate(ctx, newTestTFClient(t, server.URL), "org", "state", since)
+		require.NoError(t, err)
+
+		assert.Nil(t, ce)
+	})
+}
@@ -0,0 +1,59 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// deleteRetryAttempts is the number of attempts made to delete a single workspace
+var deleteRetryAttempts = 3
+
+// deleteRetryInterval is the time waited between attempts to delete a workspace
+var deleteRetryInterval = 5 * time.Second
+
+// DeleteWorkspace deletes the workspace with the passed ID, force unlocking it first if it is locked.
+// Failed deletions are retried, and a workspace that no longer exists is treated as deleted.
+func DeleteWorkspace(ctx context.Context, client *tfe.Client, workspaceID string) error {
+	ws, err := client.Workspaces.ReadByID(ctx, workspaceID)
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read workspace %q: %w", workspaceID, err)
+	}
+
+	if ws.Locked {
+		githubactions.Infof("Workspace %q is locked, unlocking before deleting it\n", ws.Name)
+
+		if _, err = client.Workspaces.ForceUnlock(ctx, ws.ID); err != nil && !errors.Is(err, tfe.ErrWorkspaceNotLocked) {
+			return fmt.Errorf("failed to unlock workspace %q: %w", ws.Name, err)
+		}
+	}
+
+	for attempt := 1; attempt <= deleteRetryAttempts; attempt++ {
+		err = client.Workspaces.DeleteByID(ctx, ws.ID)
+		if err == nil || errors.Is(err, tfe.ErrResourceNotFound) {
+			return nil
+		}
+
+		if attempt == deleteRetryAttempts {
+			break
+		}
+
+		githubactions.Infof("Failed to delete workspace %q, retrying in %s: %s\n", ws.Name, deleteRetryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(deleteRetryInterval):
+		}
+	}
+
+	return fmt.Errorf("failed to delete workspace %q after %d attempts: %w", ws.Name, deleteRetryAttempts, err)
+}
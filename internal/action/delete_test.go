@@ -0,0 +1,75 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteWorkspace(t *testing.T) {
+	ctx := context.Background()
+
+	retryInterval := deleteRetryInterval
+	deleteRetryInterval = time.Millisecond
+
+	t.Cleanup(func() {
+		deleteRetryInterval = retryInterval
+	})
+
+	t.Run("unlock a locked workspace and retry a failed deletion", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		t.Cleanup(func() {
+			server.Close()
+		})
+
+		unlocked := false
+		deleteAttempts := 0
+
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo", "locked": true}}}`)(w, r)
+				return
+			}
+
+			require.Equal(t, http.MethodDelete, r.Method)
+
+			deleteAttempts++
+			if deleteAttempts == 1 {
+				testServerResHandler(t, 422, `{"errors": [{"status": "422", "title": "invalid"}]}`)(w, r)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/actions/force-unlock", func(w http.ResponseWriter, r *http.Request) {
+			unlocked = true
+
+			testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo", "locked": false}}}`)(w, r)
+		})
+
+		require.NoError(t, DeleteWorkspace(ctx, newTestTFClient(t, server.URL), "ws-abc123"))
+
+		assert.True(t, unlocked)
+		assert.Equal(t, 2, deleteAttempts)
+	})
+
+	t.Run("ignore a workspace that no longer exists", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		t.Cleanup(func() {
+			server.Close()
+		})
+
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+		assert.NoError(t, DeleteWorkspace(ctx, newTestTFClient(t, server.URL), "ws-abc123"))
+	})
+}
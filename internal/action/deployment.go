@@ -0,0 +1,53 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GitHubDeployments creates GitHub deployments and statuses for the repository running the action
+type GitHubDeployments struct {
+	*GitHubClient
+}
+
+// NewGitHubDeploymentsFromEnv configures a GitHubDeployments client from the GitHub Actions environment
+func NewGitHubDeploymentsFromEnv() (*GitHubDeployments, error) {
+	c, err := NewGitHubClientFromEnv("create deployments")
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitHubDeployments{c}, nil
+}
+
+// Create creates a deployment of the passed ref to the passed environment and returns its ID
+func (d *GitHubDeployments) Create(ctx context.Context, ref string, environment string) (int64, error) {
+	var deployment struct {
+		ID int64 `json:"id"`
+	}
+
+	err := d.do(ctx, http.MethodPost, "deployments", map[string]interface{}{
+		"ref":               ref,
+		"environment":       environment,
+		"auto_merge":        false,
+		"required_contexts": []string{},
+		"description":       "Terraform Cloud workspace apply",
+	}, http.StatusCreated, &deployment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	return deployment.ID, nil
+}
+
+// SetStatus sets the state of the passed deployment, such as "in_progress", "success" or "failure"
+func (d *GitHubDeployments) SetStatus(ctx context.Context, id int64, state string) error {
+	if err := d.do(ctx, http.MethodPost, fmt.Sprintf("deployments/%d/statuses", id), map[string]interface{}{
+		"state": state,
+	}, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("failed to set deployment status: %w", err)
+	}
+
+	return nil
+}
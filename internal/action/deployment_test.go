@@ -0,0 +1,79 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGitHubDeploymentsFromEnv(t *testing.T) {
+	t.Run("read the GitHub Actions environment", func(t *testing.T) {
+		t.Setenv("GITHUB_API_URL", "")
+		t.Setenv("GITHUB_TOKEN", "token")
+		t.Setenv("GITHUB_REPOSITORY", "org/repo")
+
+		d, err := NewGitHubDeploymentsFromEnv()
+		require.NoError(t, err)
+
+		assert.Equal(t, &GitHubDeployments{&GitHubClient{APIURL: "https://api.github.com", Token: "token", Repository: "org/repo"}}, d)
+	})
+
+	t.Run("error without a token", func(t *testing.T) {
+		t.Setenv("GITHUB_TOKEN", "")
+		t.Setenv("GITHUB_REPOSITORY", "org/repo")
+
+		_, err := NewGitHubDeploymentsFromEnv()
+		assert.EqualError(t, err, "GITHUB_TOKEN must be set in the environment to create deployments")
+	})
+}
+
+func TestGitHubDeployments(t *testing.T) {
+	ctx := context.Background()
+
+	var statuses []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/deployments", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		assert.Equal(t, "abc123", body["ref"])
+		assert.Equal(t, "production", body["environment"])
+
+		testServerResHandler(t, 201, `{"id": 42}`)(w, r)
+	})
+	mux.HandleFunc("/repos/org/repo/deployments/42/statuses", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		statuses = append(statuses, body["state"])
+
+		testServerResHandler(t, 201, `{}`)(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	d := &GitHubDeployments{&GitHubClient{APIURL: server.URL, Token: "token", Repository: "org/repo"}}
+
+	t.Run("create a deployment and set its status", func(t *testing.T) {
+		id, err := d.Create(ctx, "abc123", "production")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), id)
+
+		require.NoError(t, d.SetStatus(ctx, id, "success"))
+		assert.Equal(t, []string{"success"}, statuses)
+	})
+
+	t.Run("error on an unexpected response", func(t *testing.T) {
+		err := d.SetStatus(ctx, 7, "failure")
+		assert.EqualError(t, err, "failed to set deployment status: unexpected response from deployments/7/statuses: 404 Not Found")
+	})
+}
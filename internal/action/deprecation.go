@@ -0,0 +1,37 @@
+package action
+
+import "fmt"
+
+// deprecatedInput is an input slated for removal and the input replacing it
+type deprecatedInput struct {
+	name        string
+	replacement string
+	used        func(config *Inputs) bool
+}
+
+// deprecatedInputs lists the inputs slated for removal
+var deprecatedInputs = []deprecatedInput{
+	{
+		name:        "workspace_speculative_enabled",
+		replacement: "the `speculative_enabled` setting of `workspace_settings`",
+		used:        func(config *Inputs) bool { return config.WorkspaceSpeculative != "" },
+	},
+	{
+		name:        "workspace_terraform_versions",
+		replacement: "the `terraform_version` setting of `workspace_settings`",
+		used:        func(config *Inputs) bool { return config.WorkspaceTerraformVersions != "" },
+	},
+}
+
+// DeprecationWarnings returns a warning for each deprecated input that is set
+func DeprecationWarnings(config *Inputs) []string {
+	warnings := []string{}
+
+	for _, input := range deprecatedInputs {
+		if input.used(config) {
+			warnings = append(warnings, fmt.Sprintf("Input %s is deprecated and will be removed in a future version, use %s instead", input.name, input.replacement))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,23 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	t.Run("return no warnings when no deprecated input is set", func(t *testing.T) {
+		assert.Empty(t, DeprecationWarnings(&Inputs{WorkspaceSettings: "staging: {}"}))
+	})
+
+	t.Run("return a warning for each deprecated input that is set", func(t *testing.T) {
+		warnings := DeprecationWarnings(&Inputs{
+			WorkspaceTerraformVersions: "staging: 1.1.0",
+		})
+
+		assert.Equal(t, []string{
+			"Input workspace_terraform_versions is deprecated and will be removed in a future version, use the `terraform_version` setting of `workspace_settings` instead",
+		}, warnings)
+	})
+}
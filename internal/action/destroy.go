@@ -0,0 +1,45 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// DestroyMode returns whether the workspaces are destroyed from the destroy input, which is "true", "false", or "auto" to destroy them when the workflow was triggered by a closed pull request
+func DestroyMode(input string) (bool, error) {
+	switch input {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	case "auto":
+		return PullRequestClosed(), nil
+	}
+
+	return false, fmt.Errorf("destroy must be true, false or auto, got %q", input)
+}
+
+// PullRequestClosed returns true if the workflow was triggered by a pull request being closed, whether or not it was merged
+func PullRequestClosed() bool {
+	if event := os.Getenv("GITHUB_EVENT_NAME"); event != "pull_request" && event != "pull_request_target" {
+		return false
+	}
+
+	b, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		githubactions.Debugf("Failed to read the GitHub event payload: %s\n", err)
+		return false
+	}
+
+	var event githubEvent
+
+	if err := json.Unmarshal(b, &event); err != nil {
+		githubactions.Debugf("Failed to decode the GitHub event payload: %s\n", err)
+		return false
+	}
+
+	return event.Action == "closed"
+}
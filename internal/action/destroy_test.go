@@ -0,0 +1,50 @@
+package action
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestroyMode(t *testing.T) {
+	eventPath := path.Join(t.TempDir(), "event.json")
+
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"action": "closed", "pull_request": {"number": 12}}`), 0644))
+
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+
+	t.Run("explicit values", func(t *testing.T) {
+		for input, expected := range map[string]bool{"": false, "false": false, "true": true} {
+			destroy, err := DestroyMode(input)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, destroy, input)
+		}
+	})
+
+	t.Run("auto destroys on a closed pull request", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+
+		destroy, err := DestroyMode("auto")
+		require.NoError(t, err)
+
+		assert.True(t, destroy)
+	})
+
+	t.Run("auto does not destroy on other events", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "push")
+
+		destroy, err := DestroyMode("auto")
+		require.NoError(t, err)
+
+		assert.False(t, destroy)
+	})
+
+	t.Run("error on an invalid value", func(t *testing.T) {
+		_, err := DestroyMode("yes")
+		assert.Error(t, err)
+	})
+}
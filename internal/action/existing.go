@@ -0,0 +1,74 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+)
+
+const (
+	// OnExistingError fails when a workspace already exists but is not managed by the action
+	OnExistingError = "error"
+	// OnExistingImport imports the resources of every existing workspace, as if import were enabled
+	OnExistingImport = "import"
+	// OnExistingAdopt imports the resources of the existing workspaces that are not managed by the action only
+	OnExistingAdopt = "adopt"
+)
+
+// ParseOnExisting validates the on_existing input, which defaults to "error"
+func ParseOnExisting(input string) (string, error) {
+	switch input {
+	case "":
+		return OnExistingError, nil
+	case OnExistingError, OnExistingImport, OnExistingAdopt:
+		return input, nil
+	}
+
+	return "", fmt.Errorf("on_existing must be error, import or adopt, got %q", input)
+}
+
+// UnmanagedWorkspaces returns the passed workspaces that exist in Terraform Cloud but are not in the Terraform state, which the plan would otherwise try to create again.
+// A workspace moved by one of the passed moved blocks is managed if the address it is moved from is in the state.
+func UnmanagedWorkspaces(ctx context.Context, tf TerraformCLI, workspaces []*Workspace, moved []tfconfig.Moved) ([]*Workspace, error) {
+	existing := []*Workspace{}
+
+	for _, ws := range workspaces {
+		if ws.ID != nil {
+			existing = append(existing, ws)
+		}
+	}
+
+	if len(existing) == 0 {
+		return existing, nil
+	}
+
+	state, err := tf.Show(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	managed := map[string]bool{}
+
+	if state.Values != nil && state.Values.RootModule != nil {
+		for _, r := range state.Values.RootModule.Resources {
+			managed[r.Address] = true
+		}
+	}
+
+	for _, m := range moved {
+		if managed[m.From] {
+			managed[m.To] = true
+		}
+	}
+
+	unmanaged := []*Workspace{}
+
+	for _, ws := range existing {
+		if !managed[ws.Address()] {
+			unmanaged = append(unmanaged, ws)
+		}
+	}
+
+	return unmanaged, nil
+}
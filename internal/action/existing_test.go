@@ -0,0 +1,87 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+)
+
+func TestParseOnExisting(t *testing.T) {
+	for input, expect := range map[string]string{
+		"":       OnExistingError,
+		"error":  OnExistingError,
+		"import": OnExistingImport,
+		"adopt":  OnExistingAdopt,
+	} {
+		onExisting, err := ParseOnExisting(input)
+		require.NoError(t, err)
+
+		assert.Equal(t, expect, onExisting)
+	}
+
+	_, err := ParseOnExisting("skip")
+	assert.EqualError(t, err, `on_existing must be error, import or adopt, got "skip"`)
+}
+
+func TestUnmanagedWorkspaces(t *testing.T) {
+	ctx := context.Background()
+
+	workspaces := []*Workspace{
+		{Name: "foo-staging", Workspace: "staging", ID: tfe.String("ws-abc123")},
+		{Name: "foo-production", Workspace: "production", ID: tfe.String("ws-def456")},
+		{Name: "foo-sandbox", Workspace: "sandbox"},
+	}
+
+	t.Run("return existing workspaces missing from the state", func(t *testing.T) {
+		tf := &TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_workspace.workspace[\"staging\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		unmanaged, err := UnmanagedWorkspaces(ctx, tf, workspaces, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []*Workspace{workspaces[1]}, unmanaged)
+	})
+
+	t.Run("return every existing workspace with an empty state", func(t *testing.T) {
+		unmanaged, err := UnmanagedWorkspaces(ctx, &TestTFExec{State: &tfjson.State{}}, workspaces, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, workspaces[:2], unmanaged)
+	})
+
+	t.Run("treat workspaces moved from an address in the state as managed", func(t *testing.T) {
+		tf := &TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_workspace.workspace[\"staging\"]"},
+							{Address: "tfe_workspace.workspace[\"prod\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		unmanaged, err := UnmanagedWorkspaces(ctx, tf, workspaces, []tfconfig.Moved{
+			{From: "tfe_workspace.workspace[\"prod\"]", To: "tfe_workspace.workspace[\"production\"]"},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, unmanaged)
+	})
+}
@@ -0,0 +1,80 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// GitHubClient sends requests to the GitHub REST API of the repository running the action
+type GitHubClient struct {
+	APIURL     string
+	Token      string
+	Repository string
+}
+
+// NewGitHubClientFromEnv configures a GitHubClient from the GitHub Actions environment, the passed purpose describes what the client is required for in errors
+func NewGitHubClientFromEnv(purpose string) (*GitHubClient, error) {
+	c := &GitHubClient{
+		APIURL:     os.Getenv("GITHUB_API_URL"),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+	}
+
+	if c.APIURL == "" {
+		c.APIURL = "https://api.github.com"
+	}
+
+	if c.Token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN must be set in the environment to %s", purpose)
+	}
+
+	if c.Repository == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY must be set in the environment to %s", purpose)
+	}
+
+	return c, nil
+}
+
+// do sends a request with the passed JSON body to the passed repository API path, decoding the response into out
+func (c *GitHubClient) do(ctx context.Context, method string, path string, body interface{}, wantStatus int, out interface{}) error {
+	var reqBody io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/repos/%s/%s", c.APIURL, c.Repository, path), reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("unexpected response from %s: %s", path, resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
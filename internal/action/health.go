@@ -0,0 +1,144 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// workspaceAssessmentSettings is the subset of the workspace API response containing its assessment setting
+type workspaceAssessmentSettings struct {
+	Data struct {
+		Attributes struct {
+			AssessmentsEnabled bool `json:"assessments-enabled"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// assessmentResult is the subset of the current assessment result API response
+type assessmentResult struct {
+	Data struct {
+		Attributes struct {
+			Drifted   bool   `json:"drifted"`
+			Succeeded bool   `json:"succeeded"`
+			ErrorMsg  string `json:"error-msg"`
+			CreatedAt string `json:"created-at"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// WorkspaceHealth is the latest health assessment result of a workspace, as set in the "health_json" output
+type WorkspaceHealth struct {
+	Workspace  string `json:"workspace"`
+	Name       string `json:"name"`
+	Assessed   bool   `json:"assessed"`
+	Drifted    bool   `json:"drifted"`
+	Succeeded  bool   `json:"succeeded"`
+	Error      string `json:"error,omitempty"`
+	AssessedAt string `json:"assessed_at,omitempty"`
+}
+
+// FetchWorkspaceAssessments returns whether health assessments are currently enabled for each passed workspace, keyed by name. Workspaces without an ID are skipped.
+func FetchWorkspaceAssessments(ctx context.Context, httpClient *http.Client, address string, token string, workspaces []*Workspace) (map[string]bool, error) {
+	enabled := map[string]bool{}
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			continue
+		}
+
+		var settings workspaceAssessmentSettings
+
+		status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("workspaces/%s", url.PathEscape(*ws.ID)), &settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+		}
+
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to read workspace %q: %d %s", ws.Name, status, http.StatusText(status))
+		}
+
+		enabled[ws.Name] = settings.Data.Attributes.AssessmentsEnabled
+	}
+
+	return enabled, nil
+}
+
+// fetchJSONAPI reads the passed API path into v, returning the response status code.
+// The body is only decoded if the request succeeds.
+func fetchJSONAPI(ctx context.Context, httpClient *http.Client, address string, token string, apiPath string, v interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/v2/%s", address, apiPath), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// FetchWorkspaceHealth returns the latest health assessment result of each passed workspace.
+// Workspaces without an ID or without assessments enabled are skipped, workspaces that have not been assessed yet are returned as not assessed.
+func FetchWorkspaceHealth(ctx context.Context, httpClient *http.Client, address string, token string, workspaces []*Workspace) ([]WorkspaceHealth, error) {
+	health := []WorkspaceHealth{}
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			continue
+		}
+
+		var settings workspaceAssessmentSettings
+
+		status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("workspaces/%s", url.PathEscape(*ws.ID)), &settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+		}
+
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to read workspace %q: %d %s", ws.Name, status, http.StatusText(status))
+		}
+
+		if !settings.Data.Attributes.AssessmentsEnabled {
+			continue
+		}
+
+		var result assessmentResult
+
+		status, err = fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("workspaces/%s/current-assessment-result", url.PathEscape(*ws.ID)), &result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the assessment result of workspace %q: %w", ws.Name, err)
+		}
+
+		h := WorkspaceHealth{Workspace: ws.Workspace, Name: ws.Name}
+
+		switch status {
+		case http.StatusOK:
+			h.Assessed = true
+			h.Drifted = result.Data.Attributes.Drifted
+			h.Succeeded = result.Data.Attributes.Succeeded
+			h.Error = result.Data.Attributes.ErrorMsg
+			h.AssessedAt = result.Data.Attributes.CreatedAt
+		case http.StatusNotFound:
+			// no assessment has completed yet
+		default:
+			return nil, fmt.Errorf("failed to read the assessment result of workspace %q: %d %s", ws.Name, status, http.StatusText(status))
+		}
+
+		health = append(health, h)
+	}
+
+	return health, nil
+}
@@ -0,0 +1,60 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchWorkspaceHealth(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"assessments-enabled": true}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123/current-assessment-result", testServerResHandler(t, 200, `{"data": {"id": "asmtres-abc123", "type": "assessment-results", "attributes": {"drifted": true, "succeeded": true, "error-msg": "", "created-at": "2022-07-02T22:29:58Z"}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-def456", testServerResHandler(t, 200, `{"data": {"id": "ws-def456", "type": "workspaces", "attributes": {"assessments-enabled": false}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-ghi789", testServerResHandler(t, 200, `{"data": {"id": "ws-ghi789", "type": "workspaces", "attributes": {"assessments-enabled": true}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-ghi789/current-assessment-result", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+	t.Run("return the latest assessment result of workspaces with assessments enabled", func(t *testing.T) {
+		health, err := FetchWorkspaceHealth(ctx, server.Client(), server.URL, "token", []*Workspace{
+			{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")},
+			{Name: "foo-production", Workspace: "production", ID: strPtr("ws-def456")},
+			{Name: "foo-development", Workspace: "development", ID: strPtr("ws-ghi789")},
+			{Name: "foo-new", Workspace: "new"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []WorkspaceHealth{
+			{Workspace: "staging", Name: "foo-staging", Assessed: true, Drifted: true, Succeeded: true, AssessedAt: "2022-07-02T22:29:58Z"},
+			{Workspace: "development", Name: "foo-development"},
+		}, health)
+	})
+}
+
+func TestFetchWorkspaceAssessments(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"assessments-enabled": true}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-def456", testServerResHandler(t, 200, `{"data": {"id": "ws-def456", "type": "workspaces", "attributes": {"assessments-enabled": false}}}`))
+
+	assessments, err := FetchWorkspaceAssessments(context.Background(), server.Client(), server.URL, "token", []*Workspace{
+		{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")},
+		{Name: "foo-production", Workspace: "production", ID: strPtr("ws-def456")},
+		{Name: "foo-development", Workspace: "development"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"foo-staging": true, "foo-production": false}, assessments)
+}
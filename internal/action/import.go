@@ -3,6 +3,8 @@ package action
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	tfe "github.com/hashicorp/go-tfe"
 	"github.com/hashicorp/terraform-exec/tfexec"
@@ -13,6 +15,9 @@ import (
 
 var maxPageSize int = 100
 
+// importLockTimeout is the time a concurrent import waits for the state lock held by another import
+const importLockTimeout = "5m"
+
 func shouldImport(ctx context.Context, tf TerraformCLI, address string) (bool, error) {
 	state, err := tf.Show(ctx)
 	if err != nil {
@@ -38,14 +43,16 @@ type TerraformCLI interface {
 }
 
 // ImportWorkspace imports the passed workspace into Terraform state
-func ImportWorkspace(ctx context.Context, tf TerraformCLI, client *tfe.Client, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+func ImportWorkspace(ctx context.Context, tf TerraformCLI, results *ImportResults, client *tfe.Client, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+	address := workspace.Address()
+
 	if workspace.ID == nil {
 		githubactions.Infof("Workspace %q not found, skipping import\n", workspace.Name)
+		results.record(address, "", importSkipped, "workspace not found")
+
 		return nil
 	}
 
-	address := fmt.Sprintf("tfe_workspace.workspace[%q]", workspace.Workspace)
-
 	imp, err := shouldImport(ctx, tf, address)
 	if err != nil {
 		return err
@@ -53,6 +60,8 @@ func ImportWorkspace(ctx context.Context, tf TerraformCLI, client *tfe.Client, w
 
 	if !imp {
 		githubactions.Infof("Workspace %q already exists in state, skipping import\n", workspace.Name)
+		results.record(address, *workspace.ID, importSkipped, "already in state")
+
 		return nil
 	}
 
@@ -60,23 +69,28 @@ func ImportWorkspace(ctx context.Context, tf TerraformCLI, client *tfe.Client, w
 
 	err = tf.Import(ctx, address, *workspace.ID, opts...)
 	if err != nil {
+		results.record(address, *workspace.ID, importFailed, err.Error())
 		return err
 	}
 
 	githubactions.Infof("Successful workspace import: %s\n", workspace.Name)
+	results.record(address, *workspace.ID, importImported, "")
 
 	return nil
 }
 
 // ImportVariable imports the passed variable into Terraform state
-func ImportVariable(ctx context.Context, tf TerraformCLI, v *tfe.Variable, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+func ImportVariable(ctx context.Context, tf TerraformCLI, results *ImportResults, v *tfe.Variable, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+	address := fmt.Sprintf("tfe_variable.%s-%s", workspace.Workspace, v.Key)
+	importID := fmt.Sprintf("%s/%s/%s", workspaceOrganization(workspace, organization), workspace.Name, v.ID)
+
 	if workspace.ID == nil {
 		githubactions.Infof("Workspace %q not found, skipping import\n", workspace.Name)
+		results.record(address, importID, importSkipped, "workspace not found")
+
 		return nil
 	}
 
-	address := fmt.Sprintf("tfe_variable.%s-%s", workspace.Workspace, v.Key)
-
 	imp, err := shouldImport(ctx, tf, address)
 	if err != nil {
 		return err
@@ -84,19 +98,21 @@ func ImportVariable(ctx context.Context, tf TerraformCLI, v *tfe.Variable, works
 
 	if !imp {
 		githubactions.Infof("Variable %q already exists in state, skipping import\n", address)
+		results.record(address, importID, importSkipped, "already in state")
+
 		return nil
 	}
 
 	githubactions.Infof("Importing variable: %q\n", address)
 
-	importID := fmt.Sprintf("%s/%s/%s", organization, workspace.Name, v.ID)
-
 	err = tf.Import(ctx, address, importID, opts...)
 	if err != nil {
+		results.record(address, importID, importFailed, err.Error())
 		return err
 	}
 
 	githubactions.Infof("Variable %q successfully imported\n", importID)
+	results.record(address, importID, importImported, "")
 
 	return nil
 }
@@ -122,14 +138,17 @@ func GetTeam(ctx context.Context, client *tfe.Client, teamName string, organizat
 }
 
 // ImportTeamAccess imports a team access resource by looking up an existing relation
-func ImportTeamAccess(ctx context.Context, tf TerraformCLI, access *tfe.TeamAccess, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+func ImportTeamAccess(ctx context.Context, tf TerraformCLI, results *ImportResults, access *tfe.TeamAccess, workspace *Workspace, organization string, opts ...tfexec.ImportOption) error {
+	address := fmt.Sprintf("tfe_team_access.teams[\"%s-%s\"]", workspace.Workspace, access.Team.ID)
+	importID := fmt.Sprintf("%s/%s/%s", workspaceOrganization(workspace, organization), workspace.Name, access.ID)
+
 	if workspace.ID == nil {
 		githubactions.Infof("Workspace %q not found, skipping team access import\n", workspace.Name)
+		results.record(address, importID, importSkipped, "workspace not found")
+
 		return nil
 	}
 
-	address := fmt.Sprintf("tfe_team_access.teams[\"%s-%s\"]", workspace.Workspace, access.Team.ID)
-
 	imp, err := shouldImport(ctx, tf, address)
 	if err != nil {
 		return err
@@ -137,145 +156,492 @@ func ImportTeamAccess(ctx context.Context, tf TerraformCLI, access *tfe.TeamAcce
 
 	if !imp {
 		githubactions.Infof("Team access %q already exists in state, skipping import\n", address)
+		results.record(address, importID, importSkipped, "already in state")
+
 		return nil
 	}
 
 	githubactions.Infof("Importing team access: %q\n", address)
 
-	importID := fmt.Sprintf("%s/%s/%s", organization, workspace.Name, access.ID)
-
 	if err = tf.Import(ctx, address, importID, opts...); err != nil {
+		results.record(address, importID, importFailed, err.Error())
 		return err
 	}
 
 	githubactions.Infof("Team access %q successfully imported\n", importID)
+	results.record(address, importID, importImported, "")
 
 	return nil
 }
 
-// ImportRunTriggers imports all related inbound run triggers to the passed workspace
-func ImportRunTriggers(ctx context.Context, tf TerraformCLI, triggers []*tfe.RunTrigger, client *tfe.Client, workspace *Workspace) error {
+// ImportRunTrigger imports an inbound run trigger related to the passed workspace
+func ImportRunTrigger(ctx context.Context, tf TerraformCLI, results *ImportResults, trigger *tfe.RunTrigger, workspace *Workspace, opts ...tfexec.ImportOption) error {
+	address := fmt.Sprintf("tfe_run_trigger.trigger[\"%s-%s\"]", workspace.Workspace, trigger.Sourceable.ID)
+
 	if workspace.ID == nil {
 		githubactions.Infof("Workspace %q not found, skipping run trigger import\n", workspace.Name)
+		results.record(address, trigger.ID, importSkipped, "workspace not found")
+
 		return nil
 	}
 
-	for _, trigger := range triggers {
-		address := fmt.Sprintf("tfe_run_trigger.trigger[\"%s-%s\"]", workspace.Workspace, trigger.Sourceable.ID)
+	imp, err := shouldImport(ctx, tf, address)
+	if err != nil {
+		return err
+	}
 
-		imp, err := shouldImport(ctx, tf, address)
-		if err != nil {
-			return err
-		}
+	if !imp {
+		githubactions.Infof("Run trigger %q already exists in state, skipping import\n", address)
+		results.record(address, trigger.ID, importSkipped, "already in state")
 
-		if !imp {
-			githubactions.Infof("Run trigger %q already exists in state, skipping import\n", address)
-			return nil
-		}
+		return nil
+	}
+
+	githubactions.Infof("Importing run trigger: %q\n", address)
+
+	if err := tf.Import(ctx, address, trigger.ID, opts...); err != nil {
+		results.record(address, trigger.ID, importFailed, err.Error())
+		return err
+	}
 
-		githubactions.Infof("Importing run trigger: %q\n", address)
+	githubactions.Infof("Run trigger %q successfully imported\n", address)
+	results.record(address, trigger.ID, importImported, "")
 
-		if err := tf.Import(ctx, address, trigger.ID); err != nil {
+	return nil
+}
+
+// ImportRunTriggers imports all related inbound run triggers to the passed workspace
+func ImportRunTriggers(ctx context.Context, tf TerraformCLI, results *ImportResults, triggers []*tfe.RunTrigger, workspace *Workspace) error {
+	for _, trigger := range triggers {
+		if err := ImportRunTrigger(ctx, tf, results, trigger, workspace); err != nil {
 			return err
 		}
-
-		githubactions.Infof("Run trigger %q successfully imported\n", address)
 	}
 
 	return nil
 }
 
-// ImportWorkspaceResources discovers and imports resources related to the passed workspace
-func ImportWorkspaceResources(ctx context.Context, client *tfe.Client, tf *tfexec.Terraform, filePath string, workspace *Workspace, organization string, providers []Provider) error {
+// ImportNotification imports an existing notification configuration related to the passed workspace
+func ImportNotification(ctx context.Context, tf TerraformCLI, results *ImportResults, notification *tfe.NotificationConfiguration, workspace *Workspace, opts ...tfexec.ImportOption) error {
+	address := fmt.Sprintf("tfe_notification_configuration.%s", workspace.Workspace)
+
 	if workspace.ID == nil {
-		githubactions.Infof("Workspace %q is not found, skipping import", workspace.Name)
+		githubactions.Infof("Workspace %q not found, skipping notification import\n", workspace.Name)
+		results.record(address, notification.ID, importSkipped, "workspace not found")
+
 		return nil
 	}
 
-	module := NewModule()
-
-	wsConfig, err := NewWorkspaceResource(ctx, client, []*Workspace{workspace}, &WorkspaceResourceOptions{})
+	imp, err := shouldImport(ctx, tf, address)
 	if err != nil {
 		return err
 	}
 
-	module.AppendResource("tfe_workspace", "workspace", wsConfig)
+	if !imp {
+		githubactions.Infof("Notification %q already exists in state, skipping import\n", address)
+		results.record(address, notification.ID, importSkipped, "already in state")
+
+		return nil
+	}
+
+	githubactions.Infof("Importing notification: %q\n", address)
+
+	if err := tf.Import(ctx, address, notification.ID, opts...); err != nil {
+		results.record(address, notification.ID, importFailed, err.Error())
+		return err
+	}
+
+	githubactions.Infof("Notification %q successfully imported\n", address)
+	results.record(address, notification.ID, importImported, "")
+
+	return nil
+}
+
+// ImportResult is the outcome of a single attempted import, as set in the "imports_json" output
+type ImportResult struct {
+	Address string `json:"address"`
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+const (
+	importImported = "imported"
+	importSkipped  = "skipped"
+	importFailed   = "failed"
+)
+
+// ImportResults collects the outcome of each import attempted during a run, in order
+type ImportResults struct {
+	mu      sync.Mutex
+	results []ImportResult
+}
+
+// record adds the outcome of an import, a nil collector discards it
+func (r *ImportResults) record(address string, id string, status string, reason string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results = append(r.results, ImportResult{Address: address, ID: id, Status: status, Reason: reason})
+}
+
+// Results returns the outcome of every recorded import
+func (r *ImportResults) Results() []ImportResult {
+	if r == nil {
+		return []ImportResult{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]ImportResult{}, r.results...)
+}
+
+// ImportError aggregates the failed imports of a single import phase
+type ImportError struct {
+	Phase  string
+	Errors []error
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("failed to import %s (%d errors): %s", e.Phase, len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// importPhase is a group of imports that must all complete before the next phase starts
+type importPhase struct {
+	name    string
+	imports []func() error
+	// concurrency is the number of imports run at once, imports of the same group run one after another
+	concurrency int
+	// groups holds the group of each import, imports without a group run one after another
+	groups []string
+}
+
+// runImportPhases runs each import phase in order, attempting every import in a phase and stopping after the first phase with failures
+func runImportPhases(phases []importPhase) error {
+	for _, phase := range phases {
+		var errs []error
+
+		for _, err := range phase.run() {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if len(errs) > 0 {
+			return &ImportError{Phase: phase.name, Errors: errs}
+		}
+	}
+
+	return nil
+}
+
+// run runs the imports of the phase with up to its concurrency of groups at once, returning the error of each import in order
+func (p importPhase) run() []error {
+	errs := make([]error, len(p.imports))
+
+	if p.concurrency <= 1 || len(p.groups) != len(p.imports) {
+		for i, imp := range p.imports {
+			errs[i] = imp()
+		}
+
+		return errs
+	}
+
+	groups := map[string][]int{}
+	order := []string{}
+
+	for i, g := range p.groups {
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+
+		groups[g] = append(groups[g], i)
+	}
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, p.concurrency)
+
+	for _, g := range order {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func(indexes []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			for _, i := range indexes {
+				errs[i] = p.imports[i]()
+			}
+		}(groups[g])
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// stateSnapshot is a TerraformCLI returning a state read once, so concurrent imports do not read the state while another import writes it
+type stateSnapshot struct {
+	TerraformCLI
+	state *tfjson.State
+}
+
+func (s *stateSnapshot) Show(context.Context, ...tfexec.ShowOption) (*tfjson.State, error) {
+	return s.state, nil
+}
+
+// workspaceImport holds the existing Terraform Cloud resources related to a workspace
+type workspaceImport struct {
+	workspace    *Workspace
+	variables    []*tfe.Variable
+	teamAccess   []*tfe.TeamAccess
+	runTriggers  []*tfe.RunTrigger
+	notification *tfe.NotificationConfiguration
+}
+
+// fetchWorkspaceImport discovers the existing resources related to the passed workspace and adds them to the passed module
+func fetchWorkspaceImport(ctx context.Context, client *tfe.Client, module *tfconfig.Module, workspace *Workspace, notification *NotificationInput) (*workspaceImport, error) {
+	wi := &workspaceImport{workspace: workspace}
 
 	variables, err := FetchRelatedVariables(ctx, client, workspace)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	wi.variables = variables
+
 	for _, variable := range variables {
 		v := ToVariable(variable, workspace)
 
 		module.AppendResource("tfe_variable", fmt.Sprintf("%s-%s", workspace.Workspace, v.Key), v.ToResource())
 	}
 
-	tfeTeams, err := FetchRelatedTeams(ctx, client, workspace, organization)
+	tfeTeamAccess, err := FetchRelatedTeamAccess(ctx, client, workspace)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tfeTeamAccess, err := FetchRelatedTeamAccess(ctx, client, workspace)
+	wi.teamAccess = tfeTeamAccess
+
+	tfeTriggers, err := FetchInboundRunTriggers(ctx, client, *workspace.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	teamAccess, err := ToTeamAccessItems(tfeTeamAccess, tfeTeams, workspace)
-	if err != nil {
-		return err
+	wi.runTriggers = tfeTriggers
+
+	if notification != nil {
+		wi.notification, err = FetchNotification(ctx, client, workspace, notification.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if wi.notification != nil {
+			module.AppendResource("tfe_notification_configuration", workspace.Workspace, ToNotificationResource(wi.notification, workspace))
+		}
 	}
 
-	AppendTeamAccess(module, teamAccess, organization)
+	return wi, nil
+}
 
-	tfeTriggers, err := FetchInboundRunTriggers(ctx, client, *workspace.ID)
+// ImportResources discovers and imports resources related to the passed workspaces.
+// Resources are imported in phases, all workspaces first, followed by variables, team access, run triggers and notifications.
+// Up to the passed concurrency of workspaces import their variables at once, the variables of a workspace are imported one after another.
+func ImportResources(ctx context.Context, client *tfe.Client, tf *tfexec.Terraform, module *tfconfig.Module, filePath string, workspaces []*Workspace, organization string, providers []Provider, notification *NotificationInput, initRetries int, concurrency int, results *ImportResults) error {
+	var existing []*Workspace
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			githubactions.Infof("Workspace %q is not found, skipping import\n", ws.Name)
+			continue
+		}
+
+		existing = append(existing, ws)
+	}
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	importModule := NewModule()
+
+	wsConfig, err := NewWorkspaceResource(ctx, client, existing, &WorkspaceResourceOptions{})
 	if err != nil {
 		return err
 	}
 
-	AppendRunTriggers(module, ToRunTriggers(tfeTriggers, workspace))
+	importModule.AppendResource("tfe_workspace", "workspace", wsConfig)
 
-	AddProviders(module, providers)
+	teamsByOrg := map[string][]*tfe.Team{}
 
-	if err := TerraformInit(ctx, tf, module, filePath); err != nil {
-		return err
+	for _, org := range distinctOrganizations(existing, organization) {
+		teams, err := FetchRelatedTeams(ctx, client, nil, org)
+		if err != nil {
+			return err
+		}
+
+		teamsByOrg[org] = teams
+	}
+
+	var (
+		wsImports  []*workspaceImport
+		teamAccess TeamAccess
+		triggers   RunTriggers
+	)
+
+	for _, ws := range existing {
+		wi, err := fetchWorkspaceImport(ctx, client, importModule, ws, notification)
+		if err != nil {
+			return fmt.Errorf("failed to discover resources of workspace %q: %w", ws.Name, err)
+		}
+
+		access, err := ToTeamAccessItems(wi.teamAccess, teamsByOrg[workspaceOrganization(ws, organization)], ws)
+		if err != nil {
+			return err
+		}
+
+		teamAccess = append(teamAccess, access...)
+		triggers = append(triggers, ToRunTriggers(wi.runTriggers, ws)...)
+
+		wsImports = append(wsImports, wi)
 	}
 
-	if err := ImportWorkspace(ctx, tf, client, workspace, organization); err != nil {
+	AppendTeamAccess(importModule, teamAccess, organization)
+	AppendRunTriggers(importModule, triggers)
+	AddProviders(importModule, providers)
+
+	if err := TerraformInit(ctx, tf, importModule, filePath, initRetries); err != nil {
 		return err
 	}
 
-	for _, variable := range variables {
-		if err := ImportVariable(ctx, tf, variable, workspace, organization); err != nil {
-			return err
+	phases := []importPhase{
+		{name: "workspaces"},
+		{name: "variables"},
+		{name: "team access"},
+		{name: "run triggers"},
+		{name: "notifications"},
+	}
+
+	// concurrent variable imports check a snapshot of the state, as the imports of the phase have distinct addresses, and wait for the state lock of each other
+	var (
+		varTF   TerraformCLI = tf
+		varOpts []tfexec.ImportOption
+	)
+
+	if concurrency > 1 {
+		phases[1].concurrency = concurrency
+
+		state, err := tf.Show(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read state: %w", err)
 		}
+
+		varTF = &stateSnapshot{TerraformCLI: tf, state: state}
+		varOpts = append(varOpts, tfexec.LockTimeout(importLockTimeout))
 	}
 
-	for _, access := range tfeTeamAccess {
-		if err := ImportTeamAccess(ctx, tf, access, workspace, organization); err != nil {
-			return err
+	for _, wi := range wsImports {
+		wi := wi
+
+		phases[0].imports = append(phases[0].imports, func() error {
+			return ImportWorkspace(ctx, tf, results, client, wi.workspace, organization)
+		})
+
+		for _, variable := range wi.variables {
+			variable := variable
+
+			phases[1].imports = append(phases[1].imports, func() error {
+				return ImportVariable(ctx, varTF, results, variable, wi.workspace, organization, varOpts...)
+			})
+			phases[1].groups = append(phases[1].groups, wi.workspace.Workspace)
+		}
+
+		for _, access := range wi.teamAccess {
+			access := access
+
+			phases[2].imports = append(phases[2].imports, func() error {
+				return ImportTeamAccess(ctx, tf, results, access, wi.workspace, organization)
+			})
+		}
+
+		for _, trigger := range wi.runTriggers {
+			trigger := trigger
+
+			phases[3].imports = append(phases[3].imports, func() error {
+				return ImportRunTrigger(ctx, tf, results, trigger, wi.workspace)
+			})
+		}
+
+		if wi.notification != nil {
+			phases[4].imports = append(phases[4].imports, func() error {
+				return ImportNotification(ctx, tf, results, wi.notification, wi.workspace)
+			})
 		}
 	}
 
-	if err := ImportRunTriggers(ctx, tf, tfeTriggers, client, workspace); err != nil {
+	if err := runImportPhases(phases); err != nil {
 		return err
 	}
 
-	return nil
+	return TerraformInit(ctx, tf, module, filePath, initRetries)
 }
 
-// ImportResources discovers and imports resources related to the passed workspaces
-func ImportResources(ctx context.Context, client *tfe.Client, tf *tfexec.Terraform, module *tfconfig.Module, filePath string, workspaces []*Workspace, organization string, providers []Provider) error {
-	for _, ws := range workspaces {
-		if err := ImportWorkspaceResources(ctx, client, tf, filePath, ws, organization, providers); err != nil {
-			return err
-		}
+// ImportMapping is an existing resource imported with a known ID, such as a renamed workspace
+type ImportMapping struct {
+	Address string `yaml:"address"`
+	ID      string `yaml:"id"`
+}
 
-		if err := TerraformInit(ctx, tf, module, filePath); err != nil {
-			return err
+// ImportMappings imports each passed resource verbatim, skipping resources that already exist in state
+func ImportMappings(ctx context.Context, tf TerraformCLI, results *ImportResults, mappings []ImportMapping) error {
+	phase := importPhase{name: "import mappings"}
+
+	for _, m := range mappings {
+		if m.Address == "" || m.ID == "" {
+			return fmt.Errorf("import mappings must set both address and id, got address %q and id %q", m.Address, m.ID)
 		}
+
+		m := m
+
+		phase.imports = append(phase.imports, func() error {
+			imp, err := shouldImport(ctx, tf, m.Address)
+			if err != nil {
+				return err
+			}
+
+			if !imp {
+				githubactions.Infof("Resource %q already exists in state, skipping import\n", m.Address)
+				results.record(m.Address, m.ID, importSkipped, "already in state")
+
+				return nil
+			}
+
+			githubactions.Infof("Importing %s with ID %s\n", m.Address, m.ID)
+
+			if err = tf.Import(ctx, m.Address, m.ID); err != nil {
+				results.record(m.Address, m.ID, importFailed, err.Error())
+				return fmt.Errorf("failed to import %q: %w", m.Address, err)
+			}
+
+			results.record(m.Address, m.ID, importImported, "")
+
+			return nil
+		})
 	}
 
-	return nil
+	return runImportPhases([]importPhase{phase})
 }
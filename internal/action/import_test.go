@@ -2,14 +2,20 @@ package action
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-tfe"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TestTFExec struct {
@@ -66,7 +72,7 @@ func TestImportWorkspace(t *testing.T) {
 			},
 		}
 
-		if err := ImportWorkspace(ctx, &tf, client, &Workspace{Name: "ws", Workspace: "default", ID: strPtr("ws-abc123")}, "org"); err != nil {
+		if err := ImportWorkspace(ctx, &tf, nil, client, &Workspace{Name: "ws", Workspace: "default", ID: strPtr("ws-abc123")}, "org"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -78,7 +84,7 @@ func TestImportWorkspace(t *testing.T) {
 			State: &tfjson.State{},
 		}
 
-		if err := ImportWorkspace(ctx, &tf, client, &Workspace{Name: "ws", Workspace: "default", ID: strPtr("ws-abc123")}, "org"); err != nil {
+		if err := ImportWorkspace(ctx, &tf, nil, client, &Workspace{Name: "ws", Workspace: "default", ID: strPtr("ws-abc123")}, "org"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -95,7 +101,7 @@ func TestImportWorkspace(t *testing.T) {
 			State: &tfjson.State{},
 		}
 
-		if err := ImportWorkspace(ctx, &tf, client, &Workspace{Name: "ws", Workspace: "default", ID: nil}, "org"); err != nil {
+		if err := ImportWorkspace(ctx, &tf, nil, client, &Workspace{Name: "ws", Workspace: "default", ID: nil}, "org"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -119,7 +125,7 @@ func TestImportVariable(t *testing.T) {
 			},
 		}
 
-		if err := ImportVariable(ctx, &tf, &tfe.Variable{
+		if err := ImportVariable(ctx, &tf, nil, &tfe.Variable{
 			Key: "foo",
 			ID:  "var-abc123",
 		}, &Workspace{Name: "ws", Workspace: "default", ID: strPtr("ws-abc123")}, "org"); err != nil {
@@ -139,7 +145,7 @@ func TestImportVariable(t *testing.T) {
 			State: &tfjson.State{},
 		}
 
-		if err := ImportVariable(ctx, &tf, &tfe.Variable{Key: "foo", ID: "var-abc123"}, &Workspace{Name: "ws", ID: nil}, "org"); err != nil {
+		if err := ImportVariable(ctx, &tf, nil, &tfe.Variable{Key: "foo", ID: "var-abc123"}, &Workspace{Name: "ws", ID: nil}, "org"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -163,7 +169,7 @@ func TestImportTeamAccess(t *testing.T) {
 			},
 		}
 
-		if err := ImportTeamAccess(ctx, &tf, &tfe.TeamAccess{
+		if err := ImportTeamAccess(ctx, &tf, nil, &tfe.TeamAccess{
 			ID: "tws-abc123",
 			Team: &tfe.Team{
 				ID: "team-abc123",
@@ -185,7 +191,7 @@ func TestImportTeamAccess(t *testing.T) {
 			State: &tfjson.State{},
 		}
 
-		if err := ImportTeamAccess(ctx, &tf, &tfe.TeamAccess{
+		if err := ImportTeamAccess(ctx, &tf, nil, &tfe.TeamAccess{
 			ID:   "tws-abc123",
 			Team: &tfe.Team{ID: "team-abc123"},
 		}, &Workspace{Name: "ws", Workspace: "default", ID: nil}, "org"); err != nil {
@@ -209,7 +215,7 @@ func TestImportTeamAccess(t *testing.T) {
 			},
 		}
 
-		if err := ImportTeamAccess(ctx, &tf, &tfe.TeamAccess{
+		if err := ImportTeamAccess(ctx, &tf, nil, &tfe.TeamAccess{
 			ID:   "tws-abc123",
 			Team: &tfe.Team{ID: "team-abc123"},
 		}, &Workspace{Name: "ws", Workspace: "default", ID: tfe.String("ws-abc123")}, "org"); err != nil {
@@ -243,7 +249,7 @@ func TestImportRelatedRunTriggers(t *testing.T) {
 		triggers, err := FetchInboundRunTriggers(ctx, client, *workspace.ID)
 		assert.NoError(t, err)
 
-		err = ImportRunTriggers(ctx, &tf, triggers, client, workspace)
+		err = ImportRunTriggers(ctx, &tf, nil, triggers, workspace)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -277,13 +283,339 @@ func TestImportRelatedRunTriggers(t *testing.T) {
 		triggers, err := FetchInboundRunTriggers(ctx, client, *workspace.ID)
 		assert.NoError(t, err)
 
-		err = ImportRunTriggers(ctx, &tf, triggers, client, workspace)
+		err = ImportRunTriggers(ctx, &tf, nil, triggers, workspace)
 		assert.NoError(t, err)
 
 		assert.Len(t, tf.ImportArgs, 0)
 	})
 }
 
+func TestImportRunTrigger(t *testing.T) {
+	ctx := context.Background()
+
+	trigger := &tfe.RunTrigger{
+		ID:         "rt-abc123",
+		Sourceable: &tfe.Workspace{ID: "ws-def456"},
+	}
+
+	t.Run("skip import if the run trigger already exists in state", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_run_trigger.trigger[\"default-ws-def456\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		err := ImportRunTrigger(ctx, &tf, nil, trigger, newTestWorkspace())
+		assert.NoError(t, err)
+
+		assert.Len(t, tf.ImportArgs, 0)
+	})
+
+	t.Run("continue importing remaining run triggers when one already exists in state", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_run_trigger.trigger[\"default-ws-def456\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		err := ImportRunTriggers(ctx, &tf, nil, []*tfe.RunTrigger{
+			trigger,
+			{ID: "rt-def456", Sourceable: &tfe.Workspace{ID: "ws-ghi789"}},
+		}, newTestWorkspace())
+		assert.NoError(t, err)
+
+		assert.Len(t, tf.ImportArgs, 1)
+		assert.Equal(t, &ImportArgs{
+			Address: "tfe_run_trigger.trigger[\"default-ws-ghi789\"]",
+			ID:      "rt-def456",
+			Opts:    ([]tfexec.ImportOption)(nil),
+		}, tf.ImportArgs[0])
+	})
+}
+
+func TestImportNotification(t *testing.T) {
+	ctx := context.Background()
+
+	notification := &tfe.NotificationConfiguration{
+		ID:   "nc-abc123",
+		Name: "foo",
+	}
+
+	t.Run("import a notification configuration", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{},
+		}
+
+		err := ImportNotification(ctx, &tf, nil, notification, newTestWorkspace())
+		assert.NoError(t, err)
+
+		assert.Len(t, tf.ImportArgs, 1)
+		assert.Equal(t, &ImportArgs{
+			Address: "tfe_notification_configuration.default",
+			ID:      "nc-abc123",
+			Opts:    ([]tfexec.ImportOption)(nil),
+		}, tf.ImportArgs[0])
+	})
+
+	t.Run("skip import if the notification configuration already exists in state", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_notification_configuration.default"},
+						},
+					},
+				},
+			},
+		}
+
+		err := ImportNotification(ctx, &tf, nil, notification, newTestWorkspace())
+		assert.NoError(t, err)
+
+		assert.Len(t, tf.ImportArgs, 0)
+	})
+
+	t.Run("skip import if the workspace is not set with an ID", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{},
+		}
+
+		err := ImportNotification(ctx, &tf, nil, notification, &Workspace{Name: "ws", Workspace: "default", ID: nil})
+		assert.NoError(t, err)
+
+		assert.Len(t, tf.ImportArgs, 0)
+	})
+}
+
+func TestImportMappings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("import each mapping not yet in state", func(t *testing.T) {
+		tf := TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_workspace.workspace[\"staging\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		err := ImportMappings(ctx, &tf, nil, []ImportMapping{
+			{Address: "tfe_workspace.workspace[\"staging\"]", ID: "ws-abc123"},
+			{Address: "tfe_workspace.workspace[\"production\"]", ID: "ws-def456"},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []*ImportArgs{
+			{Address: "tfe_workspace.workspace[\"production\"]", ID: "ws-def456"},
+		}, tf.ImportArgs)
+	})
+
+	t.Run("record the result of each mapping", func(t *testing.T) {
+		results := &ImportResults{}
+
+		tf := TestTFExec{
+			State: &tfjson.State{
+				Values: &tfjson.StateValues{
+					RootModule: &tfjson.StateModule{
+						Resources: []*tfjson.StateResource{
+							{Address: "tfe_workspace.workspace[\"staging\"]"},
+						},
+					},
+				},
+			},
+		}
+
+		err := ImportMappings(ctx, &tf, results, []ImportMapping{
+			{Address: "tfe_workspace.workspace[\"staging\"]", ID: "ws-abc123"},
+			{Address: "tfe_workspace.workspace[\"production\"]", ID: "ws-def456"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []ImportResult{
+			{Address: "tfe_workspace.workspace[\"staging\"]", ID: "ws-abc123", Status: "skipped", Reason: "already in state"},
+			{Address: "tfe_workspace.workspace[\"production\"]", ID: "ws-def456", Status: "imported"},
+		}, results.Results())
+	})
+
+	t.Run("return an error if a mapping has no ID", func(t *testing.T) {
+		tf := TestTFExec{State: &tfjson.State{}}
+
+		err := ImportMappings(ctx, &tf, nil, []ImportMapping{{Address: "tfe_workspace.workspace[\"staging\"]"}})
+		assert.EqualError(t, err, "import mappings must set both address and id, got address \"tfe_workspace.workspace[\\\"staging\\\"]\" and id \"\"")
+
+		assert.Empty(t, tf.ImportArgs)
+	})
+}
+
+func TestRunImportPhases(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("run every phase in order", func(t *testing.T) {
+		var calls []string
+
+		err := runImportPhases([]importPhase{
+			{name: "workspaces", imports: []func() error{
+				func() error { calls = append(calls, "workspace"); return nil },
+			}},
+			{name: "variables", imports: []func() error{
+				func() error { calls = append(calls, "variable"); return nil },
+			}},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"workspace", "variable"}, calls)
+	})
+
+	t.Run("aggregate all failures of a phase and skip later phases", func(t *testing.T) {
+		var calls []string
+
+		err := runImportPhases([]importPhase{
+			{name: "workspaces", imports: []func() error{
+				func() error { calls = append(calls, "first"); return errors.New("first failure") },
+				func() error { calls = append(calls, "second"); return errors.New("second failure") },
+			}},
+			{name: "variables", imports: []func() error{
+				func() error { calls = append(calls, "variable"); return nil },
+			}},
+		})
+
+		var importErr *ImportError
+
+		assert.ErrorAs(t, err, &importErr)
+		assert.Equal(t, "workspaces", importErr.Phase)
+		assert.Len(t, importErr.Errors, 2)
+		assert.EqualError(t, err, "failed to import workspaces (2 errors): first failure; second failure")
+		assert.Equal(t, []string{"first", "second"}, calls)
+	})
+
+	t.Run("run groups concurrently and the imports of a group in order", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			calls   = map[string][]string{}
+			running int32
+			maxRun  int32
+		)
+
+		imp := func(group string, name string, err error) func() error {
+			return func() error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+
+				for {
+					m := atomic.LoadInt32(&maxRun)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRun, m, n) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				calls[group] = append(calls[group], name)
+				mu.Unlock()
+
+				return err
+			}
+		}
+
+		err := runImportPhases([]importPhase{
+			{
+				name: "variables",
+				imports: []func() error{
+					imp("staging", "a", nil),
+					imp("production", "b", errors.New("b failed")),
+					imp("staging", "c", errors.New("c failed")),
+					imp("development", "d", nil),
+				},
+				groups:      []string{"staging", "production", "staging", "development"},
+				concurrency: 2,
+			},
+		})
+
+		assert.EqualError(t, err, "failed to import variables (2 errors): b failed; c failed")
+		assert.Equal(t, []string{"a", "c"}, calls["staging"])
+		assert.LessOrEqual(t, maxRun, int32(2))
+	})
+
+	t.Run("record the result of each concurrent variable import", func(t *testing.T) {
+		results := &ImportResults{}
+
+		tf := &lockedTFExec{}
+		snapshot := &stateSnapshot{TerraformCLI: tf, state: &tfjson.State{}}
+
+		phase := importPhase{name: "variables", concurrency: 2}
+
+		for _, ws := range []*Workspace{
+			{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")},
+			{Name: "foo-production", Workspace: "production", ID: strPtr("ws-def456")},
+		} {
+			for _, key := range []string{"a", "b", "c"} {
+				ws, v := ws, &tfe.Variable{ID: "var-" + ws.Workspace + "-" + key, Key: key}
+
+				phase.imports = append(phase.imports, func() error {
+					return ImportVariable(ctx, snapshot, results, v, ws, "org", tfexec.LockTimeout(importLockTimeout))
+				})
+				phase.groups = append(phase.groups, ws.Workspace)
+			}
+		}
+
+		assert.NoError(t, runImportPhases([]importPhase{phase}))
+
+		assert.Len(t, results.Results(), 6)
+		assert.Len(t, tf.imports, 6)
+
+		for _, r := range results.Results() {
+			assert.Equal(t, importImported, r.Status)
+		}
+
+		var staging []string
+
+		for _, address := range tf.imports {
+			if strings.HasPrefix(address, "tfe_variable.staging-") {
+				staging = append(staging, address)
+			}
+		}
+
+		assert.Equal(t, []string{"tfe_variable.staging-a", "tfe_variable.staging-b", "tfe_variable.staging-c"}, staging)
+	})
+}
+
+// lockedTFExec records the addresses of concurrent imports
+type lockedTFExec struct {
+	mu      sync.Mutex
+	imports []string
+}
+
+func (tf *lockedTFExec) Show(ctx context.Context, opts ...tfexec.ShowOption) (*tfjson.State, error) {
+	return &tfjson.State{}, nil
+}
+
+func (tf *lockedTFExec) Import(ctx context.Context, address string, ID string, opts ...tfexec.ImportOption) error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	tf.imports = append(tf.imports, address)
+
+	return nil
+}
+
 var runTriggerAPIResponse string = `{
   "data": [
     {
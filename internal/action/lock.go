@@ -0,0 +1,69 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// lockRetryInterval is the time waited between attempts to lock a workspace that is already locked
+var lockRetryInterval = 10 * time.Second
+
+// WorkspaceLock is a lock held on a coordination workspace to prevent concurrent runs of the action
+type WorkspaceLock struct {
+	client      *tfe.Client
+	workspaceID string
+	name        string
+}
+
+// AcquireWorkspaceLock locks the passed coordination workspace, retrying until the timeout elapses if it is already locked
+func AcquireWorkspaceLock(ctx context.Context, client *tfe.Client, organization string, name string, timeout time.Duration, reason string) (*WorkspaceLock, error) {
+	ws, err := client.Workspaces.Read(ctx, organization, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock workspace %q: %w", name, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		_, err := client.Workspaces.Lock(ctx, ws.ID, tfe.WorkspaceLockOptions{
+			Reason: tfe.String(reason),
+		})
+		if err == nil {
+			githubactions.Infof("Acquired lock on workspace %q\n", name)
+
+			return &WorkspaceLock{client: client, workspaceID: ws.ID, name: name}, nil
+		}
+
+		if !errors.Is(err, tfe.ErrWorkspaceLocked) {
+			return nil, fmt.Errorf("failed to lock workspace %q: %w", name, err)
+		}
+
+		if time.Now().Add(lockRetryInterval).After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on workspace %q", timeout, name)
+		}
+
+		githubactions.Infof("Workspace %q is locked, retrying in %s\n", name, lockRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Release unlocks the coordination workspace
+func (l *WorkspaceLock) Release(ctx context.Context) error {
+	if _, err := l.client.Workspaces.Unlock(ctx, l.workspaceID); err != nil {
+		return fmt.Errorf("failed to unlock workspace %q: %w", l.name, err)
+	}
+
+	githubactions.Infof("Released lock on workspace %q\n", l.name)
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lockWorkspaceAPIResponse string = `{
+  "data": {
+    "id": "ws-lock123",
+    "type": "workspaces",
+    "attributes": {
+      "name": "lock",
+      "locked": false
+    }
+  }
+}`
+
+func TestAcquireWorkspaceLock(t *testing.T) {
+	ctx := context.Background()
+
+	retryInterval := lockRetryInterval
+	lockRetryInterval = time.Millisecond
+
+	t.Cleanup(func() {
+		lockRetryInterval = retryInterval
+	})
+
+	t.Run("lock and release the workspace", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		t.Cleanup(func() {
+			server.Close()
+		})
+
+		unlocked := false
+
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/lock", testServerResHandler(t, 200, lockWorkspaceAPIResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-lock123/actions/lock", testServerResHandler(t, 200, lockWorkspaceAPIResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-lock123/actions/unlock", func(w http.ResponseWriter, r *http.Request) {
+			unlocked = true
+
+			testServerResHandler(t, 200, lockWorkspaceAPIResponse)(w, r)
+		})
+
+		client := newTestTFClient(t, server.URL)
+
+		lock, err := AcquireWorkspaceLock(ctx, client, "org", "lock", time.Second, "test")
+		require.NoError(t, err)
+
+		require.NoError(t, lock.Release(ctx))
+		assert.True(t, unlocked)
+	})
+
+	t.Run("wait for a locked workspace to be released", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		t.Cleanup(func() {
+			server.Close()
+		})
+
+		attempts := 0
+
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/lock", testServerResHandler(t, 200, lockWorkspaceAPIResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-lock123/actions/lock", func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+
+			if attempts < 3 {
+				testServerResHandler(t, 409, `{"errors": [{"status": "409", "title": "conflict"}]}`)(w, r)
+				return
+			}
+
+			testServerResHandler(t, 200, lockWorkspaceAPIResponse)(w, r)
+		})
+
+		client := newTestTFClient(t, server.URL)
+
+		_, err := AcquireWorkspaceLock(ctx, client, "org", "lock", time.Second, "test")
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("fail when the lock is not released before the timeout", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		t.Cleanup(func() {
+			server.Close()
+		})
+
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/lock", testServerResHandler(t, 200, lockWorkspaceAPIResponse))
+		mux.HandleFunc("/api/v2/workspaces/ws-lock123/actions/lock", testServerResHandler(t, 409, `{"errors": [{"status": "409", "title": "conflict"}]}`))
+
+		client := newTestTFClient(t, server.URL)
+
+		_, err := AcquireWorkspaceLock(ctx, client, "org", "lock", 10*time.Millisecond, "test")
+		assert.EqualError(t, err, "timed out after 10ms waiting for the lock on workspace \"lock\"")
+	})
+}
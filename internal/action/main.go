@@ -3,13 +3,21 @@ package action
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-tfe"
 	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/sethvargo/go-githubactions"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
@@ -17,176 +25,423 @@ import (
 )
 
 type Inputs struct {
-	Token                     string
-	Host                      string
-	Name                      string
-	Description               string
-	Tags                      string
-	WorkspaceTags             string
-	Organization              string
-	Apply                     bool
-	RunnerTerraformVersion    string
-	RemoteStates              string
-	Workspaces                string
-	Variables                 string
-	WorkspaceVariables        string
-	TeamAccess                string
-	BackendConfig             string
-	AgentPoolID               string
-	AutoApply                 *bool
-	ExecutionMode             string
-	FileTriggersEnabled       *bool
-	GlobalRemoteState         *bool
-	NotificationConfiguration string
-	QueueAllRuns              *bool
-	RemoteStateConsumerIDs    string
-	SpeculativeEnabled        *bool
-	TerraformVersion          string
-	RunTriggers               string
-	WorkspaceRunTriggers      string
-	SSHKeyID                  string
-	VCSIngressSubmodules      bool
-	VCSRepo                   string
-	VCSTokenID                string
-	VCSType                   string
-	WorkingDirectory          string
-	TFEProviderVersion        string
-	Import                    bool
-	AllowWorkspaceDeletion    bool
-}
-
-func Run(config *Inputs) error {
+	Token                      string
+	Host                       string
+	Name                       string
+	Description                string
+	Tags                       string
+	WorkspaceTags              string
+	TagPolicyFile              string
+	Organization               string
+	Apply                      bool
+	RunnerTerraformVersion     string
+	RemoteStates               string
+	RemoteStatesFile           string
+	CheckRemoteStates          bool
+	Workspaces                 string
+	AllowEmpty                 bool
+	Variables                  string
+	WorkspaceVariables         string
+	VariableSets               string
+	TeamAccess                 string
+	BackendConfig              string
+	AgentPoolID                string
+	AgentPoolName              string
+	SkipAgentCheck             bool
+	AutoApply                  *bool
+	ExecutionMode              string
+	FileTriggersEnabled        *bool
+	GlobalRemoteState          *bool
+	NotificationConfiguration  string
+	QueueAllRuns               *bool
+	RemoteStateConsumerIDs     string
+	RemoteStateConsumerTags    string
+	SpeculativeEnabled         *bool
+	WorkspaceSpeculative       string
+	StructuredRunOutputEnabled *bool
+	AssessmentsEnabled         *bool
+	AutoDestroyAt              string
+	Environment                string
+	SkipPermissionCheck        bool
+	GenerateGraph              bool
+	SSLSkipVerify              bool
+	CABundlePath               string
+	HTTPHeaders                string
+	GitMetadataTags            bool
+	ImportConcurrency          string
+	InitRetries                string
+	MaxNewWorkspaces           string
+	SensitiveKeyPatterns       string
+	WorkspaceTerraformVersions string
+	PlanStepSummary            bool
+	PlanPRComment              bool
+	UseEnvCredentials          bool
+	Env                        string
+	AllowCredentialsEnv        bool
+	PluginCacheDir             string
+	TriggerPatterns            string
+	TriggerPrefixes            string
+	TriggerTagsRegex           string
+	ValidateGeneratedConfig    bool
+	ContinueOnPartialFailure   bool
+	RunMessage                 string
+	ModuleSource               string
+	ModuleVersion              string
+	EmptyConfigurationVersion  bool
+	OnExisting                 string
+	WorkspaceRun               bool
+	WorkspaceRunWait           bool
+	PruneTeamAccess            bool
+	ReportHealth               bool
+	CheckLiveDifferences       bool
+	WorkspaceSettings          string
+	IgnoreDescriptionDrift     bool
+	ParallelPlan               bool
+	ImportMappings             string
+	WorkspacesDir              string
+	TerraformVersion           string
+	RunTriggers                string
+	WorkspaceRunTriggers       string
+	SSHKeyID                   string
+	VCSIngressSubmodules       bool
+	VCSRepo                    string
+	VCSTokenID                 string
+	VCSType                    string
+	WorkingDirectory           string
+	TFEProviderVersion         string
+	TFEProviderSource          string
+	TFEProviderOrganization    bool
+	Import                     bool
+	Destroy                    string
+	AllowWorkspaceDeletion     bool
+	DeletableWorkspaces        string
+	StandaloneWorkspace        bool
+	AutoApplyResourceTypes     string
+	SkipApplyOnDestroy         bool
+	BackendWorkspaceName       string
+	TargetWorkspaces           string
+	ApprovedPlan               string
+	BaselinePlanPath           string
+	KeepWorkDirOnError         bool
+	LockWorkspace              string
+	LockTimeout                string
+	RenderOnly                 bool
+	RenderPath                 string
+	PreviewOnly                bool
+	ApplyRequiresPlanMatch     bool
+	InheritOrgDefaults         bool
+	AllowTagChanges            *bool
+	WorkspaceOrganizations     string
+	WorkspaceRenames           string
+	SARIFOutput                string
+
+	// Stdin is read for an approved_plan of "-", reading fails if nil
+	Stdin io.Reader
+}
+
+// ValidateRenderOnly returns an error if the passed inputs require Terraform Cloud API lookups, which are unavailable when only rendering or previewing the configuration
+func ValidateRenderOnly(config *Inputs) error {
+	mode := "render_only"
+	if config.PreviewOnly {
+		mode = "preview_only"
+	}
+
+	if config.RenderOnly && config.RenderPath == "" {
+		return fmt.Errorf("render_path must be set when render_only is true")
+	}
+
+	if config.VCSType != "" && config.VCSTokenID == "" {
+		return fmt.Errorf("vcs_token_id must be passed instead of vcs_type when %s is true", mode)
+	}
+
+	if config.AgentPoolName != "" {
+		return fmt.Errorf("agent_pool_id must be passed instead of agent_pool_name when %s is true", mode)
+	}
+
+	if strings.TrimSpace(config.VariableSets) != "" {
+		return fmt.Errorf("variable_sets cannot be read when %s is true", mode)
+	}
+
+	return nil
+}
+
+func Run(config *Inputs) (err error) {
 	ctx := context.Background()
 
-	client, err := tfe.NewClient(&tfe.Config{
-		Address: fmt.Sprintf("https://%s", config.Host),
-		Token:   config.Token,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create Terraform client: %w", err)
+	var result *PlanResult
+
+	// the result is set on every return, so failures can be told apart without parsing the log
+	defer func() {
+		var hasChanges, applySkipped bool
+		if result != nil {
+			hasChanges, applySkipped = result.HasChanges, result.ApplySkipped
+		}
+
+		setResultOutput(NewRunResult(err, hasChanges, applySkipped))
+	}()
+
+	if result, err = Plan(ctx, config); err != nil {
+		return err
 	}
 
-	workDir, err := ioutil.TempDir("", config.Name)
+	defer func() {
+		result.Close(err)
+	}()
+
+	return Apply(ctx, config, result)
+}
+
+// PlanResult is the outcome of Plan, passed to Apply to apply the saved plan
+type PlanResult struct {
+	// WorkDir is the Terraform working directory, removed by Close
+	WorkDir string
+	// Destroy is set when the plan destroys the workspaces
+	Destroy bool
+	// PlanPath is the path of the saved plan, relative to WorkDir
+	PlanPath string
+	// Plan is the parsed plan, nil when the plan has no changes
+	Plan         *tfjson.Plan
+	HasChanges   bool
+	ApplySkipped bool
+	// Imports holds the outcome of each import attempted by the plan
+	Imports *ImportResults
+
+	tf             terraformApplier
+	client         *tfe.Client
+	httpClient     *http.Client
+	token          string
+	workspaces     []*Workspace
+	newWorkspaces  []*Workspace
+	targetInputs   []string
+	targetOpts     []tfexec.PlanOption
+	teamAccess     TeamAccess
+	wsOptions      *WorkspaceResourceOptions
+	moduleTemplate *ModuleTemplate
+	backend        map[string]interface{}
+	planStarted    time.Time
+	approved       *tfjson.Plan
+	checks         *PlanChecks
+	cleanups       []func(err error)
+}
+
+// Close releases the workspace lock and removes the working directory, which is retained when err is set and keep_workdir_on_error is true
+func (r *PlanResult) Close(err error) {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i](err)
+	}
+
+	r.cleanups = nil
+}
+
+// Plan generates the workspace configuration and plans it, running the plan checks. The result is also returned with an error, to report whether the plan had changes, and is already closed in that case
+func Plan(ctx context.Context, config *Inputs) (*PlanResult, error) {
+	result := &PlanResult{}
+
+	if err := plan(ctx, config, result); err != nil {
+		result.Close(err)
+
+		return result, err
+	}
+
+	return result, nil
+}
+
+// plan runs Plan, recording its state in the passed result
+func plan(ctx context.Context, config *Inputs, result *PlanResult) (err error) {
+	var client *tfe.Client
+
+	// rendering and previewing the configuration make no Terraform Cloud API requests
+	offline := config.RenderOnly || config.PreviewOnly
+
+	token := config.Token
+	if config.UseEnvCredentials && token == "" {
+		token = os.Getenv("TFE_TOKEN")
+	}
+
+	// the token is written to the Terraform CLI configuration and the environment, mask it wherever it surfaces
+	if token != "" {
+		githubactions.AddMask(token)
+	}
+
+	for _, warning := range DeprecationWarnings(config) {
+		githubactions.Warningf("%s\n", warning)
+	}
+
+	initRetries, importConcurrency, maxNewWorkspaces, err := parsePlanLimits(config)
 	if err != nil {
-		return fmt.Errorf("failed to create working directory: %w", err)
+		return err
 	}
 
-	defer os.RemoveAll(workDir)
+	if result.Destroy, err = DestroyMode(config.Destroy); err != nil {
+		return err
+	}
 
-	tf, err := NewTerraformExec(ctx, workDir, config.RunnerTerraformVersion)
+	onExisting, err := ParseOnExisting(config.OnExisting)
 	if err != nil {
-		return fmt.Errorf("failed to create tfexec instance: %w", err)
+		return err
 	}
 
-	if err := writeTerraformrcFile(config.Host, config.Token); err != nil {
-		return fmt.Errorf("failed to write .terraformrc file")
+	// the execution settings may be inherited from the organization, without modifying the inputs
+	executionMode, agentPoolID := config.ExecutionMode, config.AgentPoolID
+
+	var httpHeaders map[string]string
+	if err = yaml.Unmarshal([]byte(config.HTTPHeaders), &httpHeaders); err != nil {
+		return fmt.Errorf("failed to decode HTTP headers: %w", err)
 	}
 
-	var remoteStates map[string]tfconfig.RemoteState
+	for _, value := range httpHeaders {
+		githubactions.AddMask(value)
+	}
 
-	err = yaml.Unmarshal([]byte(config.RemoteStates), &remoteStates)
+	tfEnv, err := ParseTerraformEnv(config.Env, config.AllowCredentialsEnv)
 	if err != nil {
-		return fmt.Errorf("failed to parse remote state blocks: %w", err)
+		return fmt.Errorf("failed to parse env: %w", err)
 	}
 
-	var wsInputs []string
-
-	err = yaml.Unmarshal([]byte(config.Workspaces), &wsInputs)
+	caBundleFile, err := trustCABundle(config, result)
 	if err != nil {
-		return fmt.Errorf("failed to decode workspaces: %w", err)
+		return err
 	}
 
-	workspaces, err := ParseWorkspaces(wsInputs, config.Name)
+	httpClient, err := NewHTTPClient(config.SSLSkipVerify, config.CABundlePath, httpHeaders)
 	if err != nil {
-		return fmt.Errorf("failed to parse workspaces: %w", err)
+		return fmt.Errorf("failed to create HTTP client: %w", err)
 	}
 
-	if err := SetWorkspaceIDs(ctx, client, workspaces, config.Organization); err != nil {
-		return fmt.Errorf("failed to set workspace IDs: %w", err)
+	if offline {
+		if err := ValidateRenderOnly(config); err != nil {
+			return err
+		}
+	} else {
+		client, err = tfe.NewClient(&tfe.Config{
+			Address:    fmt.Sprintf("https://%s", config.Host),
+			Token:      token,
+			HTTPClient: httpClient,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Terraform client: %w", err)
+		}
 	}
 
-	genVars := VariablesInput{}
+	if config.LockWorkspace != "" && !offline {
+		if err = lockWorkspace(ctx, config, client, result); err != nil {
+			return err
+		}
+	}
 
-	err = yaml.Unmarshal([]byte(config.Variables), &genVars)
+	remoteStates, err := ParseRemoteStates(config.RemoteStates, config.RemoteStatesFile, config.Name)
 	if err != nil {
-		return fmt.Errorf("failed to parse variables %w", err)
+		return fmt.Errorf("failed to parse remote state blocks: %w", err)
+	}
+
+	wsInputs, wsVars, settingsInputs, err := parseWorkspaceInputs(config)
+	if err != nil {
+		return err
 	}
 
-	wsVars := WorkspaceVariablesInput{}
+	workspaces, err := ParseWorkspaces(wsInputs, config.Name)
+	if errors.Is(err, ErrNoWorkspaces) && config.AllowEmpty {
+		githubactions.Infof("No workspaces resolved, nothing to do\n")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to parse workspaces: %w", err)
+	}
 
-	err = yaml.Unmarshal([]byte(config.WorkspaceVariables), &wsVars)
+	renames, err := configureWorkspaces(config, workspaces)
 	if err != nil {
-		return fmt.Errorf("failed to parse workspace variables %w", err)
+		return err
 	}
 
-	wsNames := make([]string, len(workspaces))
-	for i, ws := range workspaces {
-		wsNames[i] = ws.Name
+	moduleTemplate, err := parseModuleTemplate(config)
+	if err != nil {
+		return err
 	}
 
-	variables := Variables{}
+	// workspaces without an ID are created by this run
+	newWorkspaces := []*Workspace{}
 
-	for _, ws := range workspaces {
-		for _, v := range genVars {
-			variables = append(variables, *NewVariable(v, ws))
+	if !offline {
+		if newWorkspaces, err = fetchNewWorkspaces(ctx, client, workspaces, config.Organization); err != nil {
+			return err
 		}
-	}
 
-	for wsName, wvs := range wsVars {
-		ws := FindWorkspace(workspaces, wsName)
+		if err = checkWorkspaces(ctx, config, client, httpClient, token, workspaces, newWorkspaces, settingsInputs, moduleTemplate, remoteStates); err != nil {
+			return err
+		}
 
-		if ws == nil {
-			return fmt.Errorf("failed to match workspace variable with known workspaces. Workspace %s not found", wsName)
+		if renames, err = ValidateWorkspaceRenames(ctx, client, renames, config.Organization); err != nil {
+			return fmt.Errorf("failed to validate workspace renames: %w", err)
 		}
 
-		for _, v := range wvs {
-			variables = append(variables, *NewVariable(v, ws))
+		if config.InheritOrgDefaults {
+			defaults, err := FetchOrganizationDefaults(ctx, httpClient, fmt.Sprintf("https://%s", config.Host), token, config.Organization)
+			if err != nil {
+				return fmt.Errorf("failed to fetch organization defaults: %w", err)
+			}
+
+			var inherited []string
+			executionMode, agentPoolID, inherited = InheritOrganizationDefaults(config, defaults)
+
+			for _, d := range inherited {
+				githubactions.Infof("Inherited %s from the defaults of organization %q\n", d, config.Organization)
+			}
 		}
 	}
 
-	variables.MaskSensitive()
+	if executionMode == "" {
+		executionMode = "remote"
+	}
 
-	var teamInputs TeamAccessInput
+	consumerIDs, err := resolveRemoteStateConsumers(ctx, config, client, offline)
+	if err != nil {
+		return err
+	}
 
-	if err = yaml.Unmarshal([]byte(config.TeamAccess), &teamInputs); err != nil {
-		return fmt.Errorf("failed to parse teams: %w", err)
+	variables, err := resolveVariables(ctx, config, httpClient, token, workspaces, wsVars, offline)
+	if err != nil {
+		return err
 	}
 
-	teamAccess := NewTeamAccess(teamInputs, workspaces)
+	backend, err := parseBackend(config)
+	if err != nil {
+		return err
+	}
 
-	backend, err := tfconfig.ParseBackend(config.BackendConfig)
+	tags, err := resolveTags(config, workspaces)
 	if err != nil {
-		return fmt.Errorf("failed to parse backend configuration: %w", err)
+		return err
 	}
 
-	var tagInputs Tags
-	if err = yaml.Unmarshal([]byte(config.Tags), &tagInputs); err != nil {
-		return fmt.Errorf("failed to decode tag names: %w", err)
+	teamAccess, err := resolveTeamAccess(config, workspaces, tags)
+	if err != nil {
+		return err
 	}
 
-	var wsTagInputs map[string]Tags
-	if err = yaml.Unmarshal([]byte(config.WorkspaceTags), &wsTagInputs); err != nil {
-		return fmt.Errorf("failed to decode workspace tag names: %w", err)
+	var triggerPatterns, triggerPrefixes []string
+	if err = yaml.Unmarshal([]byte(config.TriggerPatterns), &triggerPatterns); err != nil {
+		return fmt.Errorf("failed to decode trigger patterns: %w", err)
 	}
 
-	tags, err := MergeWorkspaceTags(tagInputs, wsTagInputs, workspaces)
-	if err != nil {
-		return fmt.Errorf("failed to format workspace tags: %w", err)
+	if err = yaml.Unmarshal([]byte(config.TriggerPrefixes), &triggerPrefixes); err != nil {
+		return fmt.Errorf("failed to decode trigger prefixes: %w", err)
 	}
 
-	var triggerInputs RunTriggerInputs
-	if err = yaml.Unmarshal([]byte(config.RunTriggers), &triggerInputs); err != nil {
-		return fmt.Errorf("failed to decode workspace tag names: %w", err)
+	if !offline {
+		if err = checkTerraformVersions(ctx, config, httpClient, token, settingsInputs); err != nil {
+			return err
+		}
 	}
 
-	var workspaceTriggerInputs map[string]RunTriggerInputs
-	if err = yaml.Unmarshal([]byte(config.WorkspaceRunTriggers), &workspaceTriggerInputs); err != nil {
-		return fmt.Errorf("failed to decode workspace tag names: %w", err)
+	settingsInputs, autoDestroyAt, err := resolveLiveSettings(ctx, config, client, httpClient, token, workspaces, settingsInputs, offline)
+	if err != nil {
+		return err
 	}
 
-	triggers, err := MergeRunTriggers(triggerInputs, workspaceTriggerInputs, workspaces, config.Organization)
+	triggers, err := resolveRunTriggers(config, workspaces)
 	if err != nil {
-		return fmt.Errorf("failed to merge run triggers: %w", err)
+		return err
 	}
 
 	var notificationInput *NotificationInput
@@ -196,53 +451,181 @@ func Run(config *Inputs) error {
 
 	notifications := MergeNotifications(notificationInput, workspaces)
 
+	var importMappings []ImportMapping
+	if err = yaml.UnmarshalStrict([]byte(config.ImportMappings), &importMappings); err != nil {
+		return fmt.Errorf("failed to decode import mappings: %w", err)
+	}
+
+	var targetInputs []string
+	if err = yaml.Unmarshal([]byte(config.TargetWorkspaces), &targetInputs); err != nil {
+		return fmt.Errorf("failed to decode target workspaces: %w", err)
+	}
+
+	resources := &TargetOptions{
+		Variables:     variables,
+		TeamAccess:    teamAccess,
+		RunTriggers:   triggers,
+		Notifications: notifications,
+		Organization:  config.Organization,
+	}
+
+	if (len(targetInputs) > 0 || config.ParallelPlan) && !offline {
+		resources.TeamIDs, err = FetchTeamIDs(ctx, client, teamAccess, config.Organization)
+		if err != nil {
+			return fmt.Errorf("failed to set target workspaces: %w", err)
+		}
+	}
+
+	targets, err := WorkspaceTargets(targetInputs, workspaces, resources)
+	if err != nil {
+		return fmt.Errorf("failed to set target workspaces: %w", err)
+	}
+
+	moved := append(MovedBlocks(renames, resources), RunTriggerMovedBlocks(triggers, renames)...)
+	moved = append(moved, StandaloneMovedBlocks(workspaces, renames)...)
+
+	var baseline *tfjson.Plan
+
+	if config.BaselinePlanPath != "" {
+		baseline, err = ReadPlanFile(config.BaselinePlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to read baseline plan: %w", err)
+		}
+	}
+
+	var deletionNames []string
+	if err = yaml.Unmarshal([]byte(config.DeletableWorkspaces), &deletionNames); err != nil {
+		return fmt.Errorf("failed to decode workspace deletion names: %w", err)
+	}
+
+	var autoApplyTypes []string
+	if err = yaml.Unmarshal([]byte(config.AutoApplyResourceTypes), &autoApplyTypes); err != nil {
+		return fmt.Errorf("failed to decode auto apply resource types: %w", err)
+	}
+
+	providerOrg, workspaceRun, err := resolveProviderFeatures(config, triggerPatterns)
+	if err != nil {
+		return err
+	}
+
 	providers := []Provider{
 		{
 			Name:    "tfe",
 			Version: config.TFEProviderVersion,
-			Source:  "hashicorp/tfe",
+			Source:  config.TFEProviderSource,
 			Config: tfeprovider.Config{
-				Hostname: config.Host,
+				Hostname:      config.Host,
+				SSLSkipVerify: config.SSLSkipVerify,
+				Organization:  providerOrg,
 			},
 		},
 	}
 
+	wsOptions := &WorkspaceResourceOptions{
+		AgentPoolID:                agentPoolID,
+		AgentPoolName:              config.AgentPoolName,
+		AutoApply:                  config.AutoApply,
+		AssessmentsEnabled:         config.AssessmentsEnabled,
+		AutoDestroyAt:              autoDestroyAt,
+		Description:                config.Description,
+		ExecutionMode:              executionMode,
+		FileTriggersEnabled:        config.FileTriggersEnabled,
+		GlobalRemoteState:          config.GlobalRemoteState,
+		Organization:               config.Organization,
+		ProviderOrganization:       providerOrg,
+		QueueAllRuns:               config.QueueAllRuns,
+		RemoteStateConsumerIDs:     consumerIDs,
+		SpeculativeEnabled:         config.SpeculativeEnabled,
+		StructuredRunOutputEnabled: config.StructuredRunOutputEnabled,
+		Tags:                       tags,
+		TerraformVersion:           config.TerraformVersion,
+		TriggerPatterns:            triggerPatterns,
+		TriggerPrefixes:            triggerPrefixes,
+		TriggerTagsRegex:           config.TriggerTagsRegex,
+		SSHKeyID:                   config.SSHKeyID,
+		VCSIngressSubmodules:       config.VCSIngressSubmodules,
+		VCSRepo:                    config.VCSRepo,
+		VCSTokenID:                 config.VCSTokenID,
+		VCSType:                    config.VCSType,
+		WorkingDirectory:           config.WorkingDirectory,
+		WorkspaceSettings:          settingsInputs,
+	}
+
 	module, err := NewWorkspaceConfig(ctx, client, workspaces, &NewWorkspaceConfigOptions{
-		Backend: backend,
-		WorkspaceResourceOptions: &WorkspaceResourceOptions{
-			AgentPoolID:            config.AgentPoolID,
-			AutoApply:              config.AutoApply,
-			Description:            config.Description,
-			ExecutionMode:          config.ExecutionMode,
-			FileTriggersEnabled:    config.FileTriggersEnabled,
-			GlobalRemoteState:      config.GlobalRemoteState,
-			Organization:           config.Organization,
-			QueueAllRuns:           config.QueueAllRuns,
-			RemoteStateConsumerIDs: config.RemoteStateConsumerIDs,
-			SpeculativeEnabled:     config.SpeculativeEnabled,
-			Tags:                   tags,
-			TerraformVersion:       config.TerraformVersion,
-			SSHKeyID:               config.SSHKeyID,
-			VCSIngressSubmodules:   config.VCSIngressSubmodules,
-			VCSRepo:                config.VCSRepo,
-			VCSTokenID:             config.VCSTokenID,
-			VCSType:                config.VCSType,
-			WorkingDirectory:       config.WorkingDirectory,
-		},
-		RemoteStates:  remoteStates,
-		Variables:     variables,
-		TeamAccess:    teamAccess,
-		RunTriggers:   triggers,
-		Notifications: notifications,
-		Providers:     providers,
+		Backend:                  backend,
+		WorkspaceResourceOptions: wsOptions,
+		RemoteStates:             remoteStates,
+		Variables:                variables,
+		TeamAccess:               teamAccess,
+		RunTriggers:              triggers,
+		Notifications:            notifications,
+		Providers:                providers,
+		Moved:                    moved,
+		WorkspaceRun:             workspaceRun,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create new workspace configuration: %w", err)
 	}
 
+	resolvedInputs := NewResolvedInputs(config, workspaces, variables, teamAccess, wsOptions, httpHeaders, targetInputs)
+
+	resolved, err := json.Marshal(resolvedInputs)
+	if err != nil {
+		return fmt.Errorf("failed to convert resolved inputs to JSON: %w", err)
+	}
+
+	githubactions.SetOutput("resolved_inputs_json", string(resolved))
+
+	if config.ValidateGeneratedConfig {
+		if err = module.Validate(); err != nil {
+			return fmt.Errorf("invalid workspace configuration: %w", err)
+		}
+	}
+
+	if config.PreviewOnly {
+		githubactions.Infof("%s\n", PreviewTree(resolvedInputs, wsOptions))
+
+		return nil
+	}
+
+	if config.RenderOnly {
+		if err = WriteModuleFile(module, config.RenderPath); err != nil {
+			return fmt.Errorf("failed to write the rendered configuration: %w", err)
+		}
+
+		githubactions.Infof("Rendered configuration to %s\n", config.RenderPath)
+
+		return nil
+	}
+
+	if config.CheckLiveDifferences {
+		setLiveDifferencesOutput(ctx, client, workspaces, wsOptions, variables)
+	}
+
+	workDir, err := ioutil.TempDir("", config.Name)
+	if err != nil {
+		return fmt.Errorf("failed to create working directory: %w", err)
+	}
+
+	result.WorkDir = workDir
+
+	result.cleanups = append(result.cleanups, func(err error) {
+		if err != nil && config.KeepWorkDirOnError {
+			githubactions.Warningf("Retaining working directory for debugging: %s\n", workDir)
+			return
+		}
+
+		os.RemoveAll(workDir)
+	})
+
+	tf, err := newPlanTerraform(ctx, config, workDir, token, caBundleFile, tfEnv)
+	if err != nil {
+		return err
+	}
+
 	filePath := path.Join(workDir, "main.tf.json")
 
-	if err = TerraformInit(ctx, tf, module, filePath); err != nil {
+	if err = TerraformInit(ctx, tf, module, filePath, initRetries); err != nil {
 		return fmt.Errorf("failed to initialize the Terraform configuration: %w", err)
 	}
 
@@ -250,65 +633,1211 @@ func Run(config *Inputs) error {
 		// copy state to local backend to avoid mutating state when apply=false
 		module.Terraform.Backend = nil
 
-		if err = TerraformInit(ctx, tf, module, filePath); err != nil {
+		if err = TerraformInit(ctx, tf, module, filePath, initRetries); err != nil {
 			return fmt.Errorf("failed to initialize the Terraform configuration: %w", err)
 		}
 	}
 
-	if config.Import {
-		if err = ImportResources(ctx, client, tf, module, filePath, workspaces, config.Organization, providers); err != nil {
-			return fmt.Errorf("failed to import resources: %w", err)
+	var approved *tfjson.Plan
+
+	if config.ApprovedPlan != "" {
+		if approved, err = ReadApprovedPlan(ctx, tf, workDir, config.ApprovedPlan, config.Stdin); err != nil {
+			return fmt.Errorf("failed to read approved plan: %w", err)
+		}
+	}
+
+	result.Imports = &ImportResults{}
+
+	if err = importExisting(ctx, config, client, tf, module, filePath, workspaces, moved, providers, notificationInput, importMappings, onExisting, initRetries, importConcurrency, result); err != nil {
+		return err
+	}
+
+	if config.GenerateGraph {
+		graph, err := tf.Graph(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate graph: %w", err)
 		}
+
+		githubactions.SetOutput("graph", graph)
 	}
 
 	planPath := "plan.txt"
 
-	planOpts := []tfexec.PlanOption{
-		tfexec.Out(planPath),
+	targetOpts := []tfexec.PlanOption{}
+
+	// targets are only passed to the plan, applying the saved plan is scoped to the same resources
+	for _, target := range targets {
+		targetOpts = append(targetOpts, tfexec.Target(target))
 	}
 
-	diff, err := tf.Plan(ctx, planOpts...)
-	if err != nil {
+	// passed with the targets, so the plans made to verify or continue the apply also destroy
+	if result.Destroy {
+		githubactions.Infof("Planning to destroy the workspaces\n")
+
+		targetOpts = append(targetOpts, tfexec.Destroy(true))
+	}
+
+	planStarted := time.Now()
+
+	var (
+		diff    bool
+		planStr string
+		plan    *tfjson.Plan
+	)
+
+	if config.ParallelPlan && config.Apply {
+		githubactions.Warningf("parallel_plan only applies when apply is false, planning all workspaces together\n")
+	}
+
+	if config.ParallelPlan && !config.Apply && len(targets) == 0 && !result.Destroy {
+		if diff, planStr, plan, err = parallelPlan(ctx, tf, workDir, workspaces, resources); err != nil {
+			return err
+		}
+	} else if diff, err = tf.Plan(ctx, append([]tfexec.PlanOption{tfexec.Out(planPath)}, targetOpts...)...); err != nil {
 		return fmt.Errorf("failed to plan: %w", err)
 	}
 
-	if diff {
-		planStr, err := tf.ShowPlanFileRaw(ctx, planPath)
-		if err != nil {
-			return fmt.Errorf("failed to show plan: %w", err)
+	if err = setCostEstimateOutput(ctx, client, backend, planStarted); err != nil {
+		return err
+	}
+
+	// a run without changes still updates the comment, so it does not show the changes of an earlier commit
+	if !diff && config.PlanPRComment {
+		if err = CommentPlanOnPullRequest(ctx, config.Name, ""); err != nil {
+			return err
 		}
+	}
 
-		githubactions.Infof(planStr)
-		githubactions.SetOutput("plan", planStr)
+	result.HasChanges = diff
+	result.PlanPath = planPath
+	result.tf = tf
+	result.client = client
+	result.httpClient = httpClient
+	result.token = token
+	result.workspaces = workspaces
+	result.newWorkspaces = newWorkspaces
+	result.targetInputs = targetInputs
+	result.targetOpts = targetOpts
+	result.wsOptions = wsOptions
+	result.teamAccess = teamAccess
+	result.moduleTemplate = moduleTemplate
+	result.backend = backend
+	result.planStarted = planStarted
+	result.approved = approved
+	result.checks = NewPlanChecks(config, deletionNames, maxNewWorkspaces)
 
-		plan, err := tf.ShowPlanFile(ctx, planPath)
-		if err != nil {
+	if !diff {
+		githubactions.Infof("No changes\n")
+
+		if err = VerifyApprovedPlan(&tfjson.Plan{}, approved); err != nil {
+			return err
+		}
+
+		if config.SARIFOutput != "" {
+			if err = WriteSARIFReport(&tfjson.Plan{}, config.SARIFOutput); err != nil {
+				return fmt.Errorf("failed to write SARIF report: %w", err)
+			}
+		}
+
+		if baseline != nil {
+			setBaselineOutput(&tfjson.Plan{}, baseline)
+		}
+
+		return nil
+	}
+
+	// a parallel plan is already shown and merged
+	if plan == nil {
+		if planStr, err = tf.ShowPlanFileRaw(ctx, planPath); err != nil {
+			return fmt.Errorf("failed to show plan: %w", err)
+		}
+
+		if plan, err = tf.ShowPlanFile(ctx, planPath); err != nil {
 			return fmt.Errorf("failed to create plan struct: %w", err)
 		}
+	}
 
-		b, err := json.Marshal(plan)
-		if err != nil {
-			return fmt.Errorf("failed to convert plan to JSON: %w", err)
+	result.Plan = plan
+
+	if err = VerifyApprovedPlan(plan, approved); err != nil {
+		return err
+	}
+
+	if err = setPlanOutputs(ctx, config, plan, planStr, workspaces, baseline); err != nil {
+		return err
+	}
+
+	if err = result.checks.Check(plan); err != nil {
+		return err
+	}
+
+	result.ApplySkipped = skipApply(config, plan, autoApplyTypes)
+
+	return nil
+}
+
+// parsePlanLimits parses the numeric inputs bounding the run, defaulting the ones that are not set
+func parsePlanLimits(config *Inputs) (initRetries int, importConcurrency int, maxNewWorkspaces int, err error) {
+	initRetries = defaultInitRetries
+	if config.InitRetries != "" {
+		if initRetries, err = strconv.Atoi(config.InitRetries); err != nil || initRetries < 0 {
+			return 0, 0, 0, fmt.Errorf("init_retries must be a non-negative integer, got %q", config.InitRetries)
 		}
+	}
 
-		githubactions.SetOutput("plan_json", string(b))
+	importConcurrency = 1
+	if config.ImportConcurrency != "" {
+		if importConcurrency, err = strconv.Atoi(config.ImportConcurrency); err != nil || importConcurrency < 1 {
+			return 0, 0, 0, fmt.Errorf("import_concurrency must be a positive integer, got %q", config.ImportConcurrency)
+		}
+	}
 
-		if !config.AllowWorkspaceDeletion && WillDestroy(plan, "tfe_workspace") {
-			return fmt.Errorf("error: allow_workspace_deletion must be true to allow workspace deletion. Deleting a workspace will permanently, irrecoverably delete all of its stored Terraform state versions")
+	maxNewWorkspaces = -1
+	if config.MaxNewWorkspaces != "" {
+		if maxNewWorkspaces, err = strconv.Atoi(config.MaxNewWorkspaces); err != nil || maxNewWorkspaces < 0 {
+			return 0, 0, 0, fmt.Errorf("max_new_workspaces must be a non-negative integer, got %q", config.MaxNewWorkspaces)
 		}
+	}
+
+	return initRetries, importConcurrency, maxNewWorkspaces, nil
+}
 
-		if config.Apply {
-			githubactions.Infof("Applying...\n")
+// trustCABundle writes the combined CA bundle and points SSL_CERT_FILE at it until the result is closed, returning the bundle path, or an empty path without a CA bundle.
+// The system roots are loaded once per process, so this runs before the first TLS connection for the CA bundle to also be trusted by the Terraform download
+func trustCABundle(config *Inputs, result *PlanResult) (string, error) {
+	if config.CABundlePath == "" {
+		return "", nil
+	}
 
-			if err = tf.Apply(ctx, tfexec.DirOrPlan(planPath)); err != nil {
-				return fmt.Errorf("failed to apply: %w", err)
-			}
+	caDir, err := os.MkdirTemp("", "ca-bundle")
+	if err != nil {
+		return "", fmt.Errorf("failed to create CA bundle directory: %w", err)
+	}
+
+	prevCertFile, hadCertFile := os.LookupEnv("SSL_CERT_FILE")
 
-			githubactions.Infof("Success\n")
+	result.cleanups = append(result.cleanups, func(error) {
+		if hadCertFile {
+			os.Setenv("SSL_CERT_FILE", prevCertFile)
+		} else {
+			os.Unsetenv("SSL_CERT_FILE")
 		}
-	} else {
-		githubactions.Infof("No changes\n")
+
+		os.RemoveAll(caDir)
+	})
+
+	caBundleFile, err := WriteCABundle(config.CABundlePath, caDir)
+	if err != nil {
+		return "", err
 	}
 
+	if err = os.Setenv("SSL_CERT_FILE", caBundleFile); err != nil {
+		return "", fmt.Errorf("failed to set SSL_CERT_FILE: %w", err)
+	}
+
+	return caBundleFile, nil
+}
+
+// lockWorkspace acquires the lock workspace, releasing it when the result is closed
+func lockWorkspace(ctx context.Context, config *Inputs, client *tfe.Client, result *PlanResult) error {
+	timeout, err := time.ParseDuration(config.LockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to parse lock timeout: %w", err)
+	}
+
+	lock, err := AcquireWorkspaceLock(ctx, client, config.Organization, config.LockWorkspace, timeout, fmt.Sprintf("Locked by the workspace action for %s", config.Name))
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	result.cleanups = append(result.cleanups, func(error) {
+		if err := lock.Release(context.Background()); err != nil {
+			githubactions.Warningf("%s\n", err)
+		}
+	})
+
 	return nil
 }
+
+// parseWorkspaceInputs decodes the workspaces with their variables and settings, merging the workspaces directory and the deprecated per workspace settings
+func parseWorkspaceInputs(config *Inputs) (wsInputs []string, wsVars WorkspaceVariablesInput, settings map[string]WorkspaceSettings, err error) {
+	if err = yaml.Unmarshal([]byte(config.Workspaces), &wsInputs); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode workspaces: %w", err)
+	}
+
+	wsVars = WorkspaceVariablesInput{}
+
+	if err = yaml.Unmarshal([]byte(config.WorkspaceVariables), &wsVars); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse workspace variables %w", err)
+	}
+
+	if err = yaml.UnmarshalStrict([]byte(config.WorkspaceSettings), &settings); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode workspace settings: %w", err)
+	}
+
+	if config.WorkspacesDir != "" {
+		files, err := ReadWorkspacesDir(config.WorkspacesDir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if wsInputs, wsVars, settings, err = MergeWorkspaceFiles(files, wsInputs, wsVars, settings); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to merge the workspaces directory: %w", err)
+		}
+	}
+
+	var tfVersionInputs map[string]string
+	if err = yaml.Unmarshal([]byte(config.WorkspaceTerraformVersions), &tfVersionInputs); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode workspace Terraform versions: %w", err)
+	}
+
+	var speculativeInputs map[string]bool
+	if err = yaml.Unmarshal([]byte(config.WorkspaceSpeculative), &speculativeInputs); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode workspace speculative plan settings: %w", err)
+	}
+
+	return wsInputs, wsVars, MergeDeprecatedWorkspaceSettings(settings, tfVersionInputs, speculativeInputs), nil
+}
+
+// configureWorkspaces applies the standalone mode and the workspace organizations to the passed workspaces, returning their renames
+func configureWorkspaces(config *Inputs, workspaces []*Workspace) ([]WorkspaceRename, error) {
+	if config.StandaloneWorkspace {
+		SetStandaloneWorkspace(workspaces)
+	}
+
+	var wsOrgInputs map[string]string
+	if err := yaml.Unmarshal([]byte(config.WorkspaceOrganizations), &wsOrgInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace organizations: %w", err)
+	}
+
+	if err := SetWorkspaceOrganizations(workspaces, wsOrgInputs); err != nil {
+		return nil, fmt.Errorf("failed to set workspace organizations: %w", err)
+	}
+
+	var renameInputs map[string]string
+	if err := yaml.Unmarshal([]byte(config.WorkspaceRenames), &renameInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace renames: %w", err)
+	}
+
+	renames, err := ParseWorkspaceRenames(renameInputs, workspaces, config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workspace renames: %w", err)
+	}
+
+	return renames, nil
+}
+
+// parseModuleTemplate parses the module the workspaces run, or returns nil when module_source is not set
+func parseModuleTemplate(config *Inputs) (*ModuleTemplate, error) {
+	if config.ModuleSource == "" {
+		return nil, nil
+	}
+
+	if config.VCSType != "" || config.VCSTokenID != "" {
+		return nil, fmt.Errorf("module_source cannot be set with a VCS integration, VCS workspaces run the configuration of their repository")
+	}
+
+	if config.EmptyConfigurationVersion {
+		return nil, fmt.Errorf("empty_configuration_version cannot be set with module_source, the module configuration is uploaded instead")
+	}
+
+	moduleTemplate, err := ParseModuleTemplate(config.ModuleSource, config.ModuleVersion, config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse module template: %w", err)
+	}
+
+	return moduleTemplate, nil
+}
+
+// fetchNewWorkspaces sets the IDs of the existing workspaces and returns the workspaces created by this run
+func fetchNewWorkspaces(ctx context.Context, client *tfe.Client, workspaces []*Workspace, organization string) ([]*Workspace, error) {
+	if err := SetWorkspaceIDs(ctx, client, workspaces, organization); err != nil {
+		return nil, fmt.Errorf("failed to set workspace IDs: %w", err)
+	}
+
+	newWorkspaces := []*Workspace{}
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			newWorkspaces = append(newWorkspaces, ws)
+		}
+	}
+
+	return newWorkspaces, nil
+}
+
+// checkWorkspaces checks the token permissions, the module template, the health assessment settings and the remote states before planning
+func checkWorkspaces(ctx context.Context, config *Inputs, client *tfe.Client, httpClient *http.Client, token string, workspaces []*Workspace, newWorkspaces []*Workspace, settings map[string]WorkspaceSettings, moduleTemplate *ModuleTemplate, remoteStates map[string]tfconfig.RemoteState) error {
+	if !config.SkipPermissionCheck {
+		creating := map[string]bool{}
+		for _, ws := range newWorkspaces {
+			creating[workspaceOrganization(ws, config.Organization)] = true
+		}
+
+		for _, org := range distinctOrganizations(workspaces, config.Organization) {
+			if err := CheckTokenPermissions(ctx, client, org, creating[org]); err != nil {
+				githubactions.Warningf("Permission check failed: %s\n", err)
+			}
+		}
+	}
+
+	if moduleTemplate != nil {
+		if err := moduleTemplate.Validate(ctx, client); err != nil {
+			return fmt.Errorf("failed to validate module template: %w", err)
+		}
+	}
+
+	if err := checkAssessments(ctx, config, httpClient, token, workspaces, settings); err != nil {
+		return err
+	}
+
+	if config.CheckRemoteStates {
+		missing, err := MissingRemoteStates(ctx, client, remoteStates, config.Host)
+		if err != nil {
+			return fmt.Errorf("failed to check remote states: %w", err)
+		}
+
+		for _, m := range missing {
+			githubactions.Warningf("%s, the plan will fail until it is created", m)
+		}
+	}
+
+	return nil
+}
+
+// checkAssessments returns an error if health assessments are enabled for an organization not entitled to them, logging the effective assessment settings
+func checkAssessments(ctx context.Context, config *Inputs, httpClient *http.Client, token string, workspaces []*Workspace, settings map[string]WorkspaceSettings) error {
+	address := fmt.Sprintf("https://%s", config.Host)
+
+	if config.AssessmentsEnabled == nil {
+		for _, org := range distinctOrganizations(workspaces, config.Organization) {
+			enforced, err := FetchAssessmentsEnforced(ctx, httpClient, address, token, org)
+			if err != nil {
+				githubactions.Warningf("Failed to fetch the assessment settings of organization %q: %s\n", org, err)
+				continue
+			}
+
+			githubactions.Debugf("assessments_enabled is not set, health assessments are effectively %s for workspaces in organization %q\n", EffectiveAssessments(enforced), org)
+		}
+	}
+
+	for _, org := range AssessmentOrganizations(workspaces, config.Organization, config.AssessmentsEnabled, settings) {
+		entitled, err := FetchAssessmentsEntitled(ctx, httpClient, address, token, org)
+		if err != nil {
+			return err
+		}
+
+		if !entitled {
+			return fmt.Errorf("assessments_enabled is true, but the plan of organization %q does not include health assessments", org)
+		}
+	}
+
+	// reading every workspace is only worth it when the debug log is shown
+	if os.Getenv("RUNNER_DEBUG") != "1" {
+		return nil
+	}
+
+	assessments, err := FetchWorkspaceAssessments(ctx, httpClient, address, token, workspaces)
+	if err != nil {
+		return fmt.Errorf("failed to fetch workspace assessment settings: %w", err)
+	}
+
+	for _, ws := range workspaces {
+		enabled, ok := assessments[ws.Name]
+		if !ok {
+			continue
+		}
+
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+
+		githubactions.Debugf("Health assessments are currently %s for workspace %q\n", state, ws.Name)
+	}
+
+	return nil
+}
+
+// resolveRemoteStateConsumers returns the remote state consumer IDs, adding the workspaces with the remote state consumer tags
+func resolveRemoteStateConsumers(ctx context.Context, config *Inputs, client *tfe.Client, offline bool) (string, error) {
+	consumerIDs := config.RemoteStateConsumerIDs
+
+	tags := strings.FieldsFunc(config.RemoteStateConsumerTags, func(c rune) bool { return c == ',' })
+	if len(tags) == 0 || offline {
+		return consumerIDs, nil
+	}
+
+	ids, err := FetchWorkspaceIDsByTags(ctx, client, config.Organization, tags)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve remote state consumer tags: %w", err)
+	}
+
+	githubactions.Infof("Resolved %d remote state consumers tagged %s\n", len(ids), strings.Join(tags, ", "))
+
+	return strings.Join(append(strings.FieldsFunc(consumerIDs, func(c rune) bool { return c == ',' }), ids...), ","), nil
+}
+
+// resolveVariables returns the variables of every workspace, from the variable sets, the variables and the workspace variables in increasing precedence, masking the sensitive values
+func resolveVariables(ctx context.Context, config *Inputs, httpClient *http.Client, token string, workspaces []*Workspace, wsVars WorkspaceVariablesInput, offline bool) (Variables, error) {
+	var sensitiveKeyInputs []string
+	if err := yaml.Unmarshal([]byte(config.SensitiveKeyPatterns), &sensitiveKeyInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode sensitive key patterns: %w", err)
+	}
+
+	sensitiveKeyPatterns, err := ParseSensitiveKeyPatterns(sensitiveKeyInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	genVars := VariablesInput{}
+
+	if err = yaml.Unmarshal([]byte(config.Variables), &genVars); err != nil {
+		return nil, fmt.Errorf("failed to parse variables %w", err)
+	}
+
+	var varSetNames []string
+	if err = yaml.Unmarshal([]byte(config.VariableSets), &varSetNames); err != nil {
+		return nil, fmt.Errorf("failed to decode variable sets: %w", err)
+	}
+
+	if len(varSetNames) > 0 && !offline {
+		setVars, warnings, err := VariableSetVariables(ctx, httpClient, fmt.Sprintf("https://%s", config.Host), token, config.Organization, varSetNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read variable sets: %w", err)
+		}
+
+		for _, w := range warnings {
+			githubactions.Warningf("%s\n", w)
+		}
+
+		// added first, so variables of the same key passed to the action override them
+		genVars = append(setVars, genVars...)
+	}
+
+	variables := Variables{}
+
+	for _, ws := range workspaces {
+		for _, v := range genVars {
+			variable, err := NewVariable(v, ws, sensitiveKeyPatterns)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create variable: %w", err)
+			}
+
+			variables = append(variables, *variable)
+		}
+	}
+
+	wsVarNames := make([]string, 0, len(wsVars))
+	for wsName := range wsVars {
+		wsVarNames = append(wsVarNames, wsName)
+	}
+
+	// variables of exactly named workspaces are added last, overriding variables of the same key set by a pattern
+	for _, wsName := range SortWorkspacePatterns(wsVarNames) {
+		matches, err := MatchWorkspaces(workspaces, wsName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match workspace variables: %w", err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("failed to match workspace variable with known workspaces. Workspace %s not found", wsName)
+		}
+
+		for _, ws := range matches {
+			for _, v := range wsVars[wsName] {
+				variable, err := NewVariable(v, ws, sensitiveKeyPatterns)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create workspace variable: %w", err)
+				}
+
+				variables = append(variables, *variable)
+			}
+		}
+	}
+
+	variables.MaskSensitive()
+
+	return variables, nil
+}
+
+// parseBackend parses the backend configuration, naming the remote backend workspace when backend_workspace_name is set
+func parseBackend(config *Inputs) (map[string]interface{}, error) {
+	backend, err := tfconfig.ParseBackend(config.BackendConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse backend configuration: %w", err)
+	}
+
+	if config.BackendWorkspaceName != "" {
+		if err = tfconfig.SetRemoteBackendWorkspaceName(backend, config.BackendWorkspaceName, config.Name); err != nil {
+			return nil, fmt.Errorf("failed to set backend workspace name: %w", err)
+		}
+	}
+
+	return backend, nil
+}
+
+// resolveTags returns the tags of each workspace, merging the shared, git metadata and workspace tags and applying the tag policy
+func resolveTags(config *Inputs, workspaces []*Workspace) (map[string]Tags, error) {
+	var tagInputs Tags
+	if err := yaml.Unmarshal([]byte(config.Tags), &tagInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode tag names: %w", err)
+	}
+
+	if config.GitMetadataTags {
+		tagInputs = append(tagInputs, GitMetadataTags()...)
+	}
+
+	var wsTagInputs map[string]Tags
+	if err := yaml.Unmarshal([]byte(config.WorkspaceTags), &wsTagInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace tag names: %w", err)
+	}
+
+	tags, err := MergeWorkspaceTags(tagInputs, wsTagInputs, workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format workspace tags: %w", err)
+	}
+
+	if config.TagPolicyFile != "" {
+		policy, err := ReadTagPolicy(config.TagPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if tags, err = ApplyTagPolicy(tags, policy, workspaces); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// resolveTeamAccess returns the team access of the workspaces, resolving the teams granted access by workspace tag
+func resolveTeamAccess(config *Inputs, workspaces []*Workspace, tags map[string]Tags) (TeamAccess, error) {
+	var teamInputs TeamAccessInput
+
+	if err := yaml.Unmarshal([]byte(config.TeamAccess), &teamInputs); err != nil {
+		return nil, fmt.Errorf("failed to parse teams: %w", err)
+	}
+
+	if err := ValidateTeamAccessWorkspaces(teamInputs, workspaces); err != nil {
+		return nil, fmt.Errorf("failed to parse teams: %w", err)
+	}
+
+	teamInputs, err := ResolveTeamAccessTags(teamInputs, tags, workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse teams: %w", err)
+	}
+
+	return NewTeamAccess(teamInputs, workspaces), nil
+}
+
+// checkTerraformVersions returns an error if a requested Terraform version is not available, skipping the check when the token cannot list the versions
+func checkTerraformVersions(ctx context.Context, config *Inputs, httpClient *http.Client, token string, settings map[string]WorkspaceSettings) error {
+	requested := []string{}
+	if config.TerraformVersion != "" {
+		requested = append(requested, config.TerraformVersion)
+	}
+
+	for _, s := range settings {
+		if s.TerraformVersion != nil {
+			requested = append(requested, *s.TerraformVersion)
+		}
+	}
+
+	if len(requested) == 0 {
+		return nil
+	}
+
+	sort.Strings(requested)
+
+	available, accessible, err := FetchTerraformVersions(ctx, httpClient, fmt.Sprintf("https://%s", config.Host), token)
+	if err != nil {
+		return err
+	}
+
+	if !accessible {
+		githubactions.Infof("Terraform versions cannot be listed with this token, skipping the Terraform version check\n")
+		return nil
+	}
+
+	return ValidateTerraformVersions(requested, available)
+}
+
+// resolveLiveSettings preserves the settings kept from the live workspaces and resolves the auto destroy time, returning the updated workspace settings.
+// The auto destroy time is resolved per workspace, it is only returned for all workspaces when the live workspaces cannot be read
+func resolveLiveSettings(ctx context.Context, config *Inputs, client *tfe.Client, httpClient *http.Client, token string, workspaces []*Workspace, settings map[string]WorkspaceSettings, offline bool) (map[string]WorkspaceSettings, string, error) {
+	// each workspace is read once for all settings preserved from the live workspaces
+	if (config.QueueAllRuns == nil || config.IgnoreDescriptionDrift) && !offline {
+		live, err := ReadLiveWorkspaces(ctx, client, workspaces)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read live workspace settings: %w", err)
+		}
+
+		if config.QueueAllRuns == nil {
+			settings = PreserveLiveQueueAllRuns(live, settings)
+		}
+
+		if config.IgnoreDescriptionDrift {
+			settings = PreserveLiveDescriptions(live, settings)
+		}
+	}
+
+	if config.AutoDestroyAt == "" {
+		return settings, "", nil
+	}
+
+	if err := ValidateProviderAutoDestroyAt(config.TFEProviderVersion); err != nil {
+		return nil, "", err
+	}
+
+	if offline {
+		autoDestroyAt, err := ParseAutoDestroyAt(config.AutoDestroyAt, time.Now())
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse auto destroy time: %w", err)
+		}
+
+		return settings, autoDestroyAt, nil
+	}
+
+	settings, err := ResolveAutoDestroyAt(ctx, httpClient, fmt.Sprintf("https://%s", config.Host), token, workspaces, settings, config.AutoDestroyAt, time.Now())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve auto destroy time: %w", err)
+	}
+
+	return settings, "", nil
+}
+
+// resolveRunTriggers merges the run triggers of all workspaces with the run triggers of each workspace
+func resolveRunTriggers(config *Inputs, workspaces []*Workspace) (RunTriggers, error) {
+	var triggerInputs RunTriggerInputs
+	if err := yaml.Unmarshal([]byte(config.RunTriggers), &triggerInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace tag names: %w", err)
+	}
+
+	var workspaceTriggerInputs map[string]RunTriggerInputs
+	if err := yaml.Unmarshal([]byte(config.WorkspaceRunTriggers), &workspaceTriggerInputs); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace tag names: %w", err)
+	}
+
+	triggers, err := MergeRunTriggers(triggerInputs, workspaceTriggerInputs, workspaces, config.Organization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge run triggers: %w", err)
+	}
+
+	return triggers, nil
+}
+
+// resolveProviderFeatures returns an error if the provider version does not support a requested feature, returning the provider organization and the workspace run options
+func resolveProviderFeatures(config *Inputs, triggerPatterns []string) (providerOrg string, workspaceRun *tfeprovider.WorkspaceRunOptions, err error) {
+	if config.TFEProviderOrganization {
+		if err = ValidateProviderOrganization(config.TFEProviderVersion); err != nil {
+			return "", nil, err
+		}
+
+		providerOrg = config.Organization
+	}
+
+	if len(triggerPatterns) > 0 {
+		if err = ValidateProviderTriggerPatterns(config.TFEProviderVersion); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if config.TriggerTagsRegex != "" {
+		if err = ValidateProviderTagsRegex(config.TFEProviderVersion); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if config.WorkspaceRun {
+		if err = ValidateProviderWorkspaceRun(config.TFEProviderVersion); err != nil {
+			return "", nil, err
+		}
+
+		workspaceRun = &tfeprovider.WorkspaceRunOptions{
+			WaitForRun: config.WorkspaceRunWait,
+		}
+	}
+
+	return providerOrg, workspaceRun, nil
+}
+
+// setLiveDifferencesOutput sets the "no_changes_expected" output by comparing the live workspaces to the configuration, only warning when they cannot be compared
+func setLiveDifferencesOutput(ctx context.Context, client *tfe.Client, workspaces []*Workspace, wsOptions *WorkspaceResourceOptions, variables Variables) {
+	liveDiffs, err := LiveDifferences(ctx, client, workspaces, wsOptions, variables)
+	if err != nil {
+		githubactions.Warningf("Failed to compare the live workspaces: %s\n", err)
+		return
+	}
+
+	for _, d := range liveDiffs {
+		githubactions.Debugf("Live difference: %s\n", d)
+	}
+
+	githubactions.SetOutput("no_changes_expected", strconv.FormatBool(len(liveDiffs) == 0))
+}
+
+// newPlanTerraform returns the Terraform executable of the working directory, with the credentials, CA bundle and env inputs set in its environment
+func newPlanTerraform(ctx context.Context, config *Inputs, workDir string, token string, caBundleFile string, tfEnv map[string]string) (*tfexec.Terraform, error) {
+	tf, err := NewTerraformExec(ctx, workDir, config.RunnerTerraformVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tfexec instance: %w", err)
+	}
+
+	env := processEnv()
+
+	if config.UseEnvCredentials {
+		env = CredentialsEnv(config.Host, token)
+	} else if err := writeTerraformrcFile(config.Host, token); err != nil {
+		return nil, fmt.Errorf("failed to write .terraformrc file")
+	}
+
+	if caBundleFile != "" {
+		env["SSL_CERT_FILE"] = caBundleFile
+	}
+
+	if config.PluginCacheDir != "" {
+		if err = SetPluginCacheDir(tfEnv, config.PluginCacheDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = SetTerraformEnv(tf, env, tfEnv); err != nil {
+		return nil, fmt.Errorf("failed to set the Terraform environment: %w", err)
+	}
+
+	return tf, nil
+}
+
+// importExisting imports the mapped resources and the existing resources of the workspaces into the state, recording the outcome of each import in the result.
+// The "imports_json" output is set before failing, so the imports that did succeed are still reported
+func importExisting(ctx context.Context, config *Inputs, client *tfe.Client, tf *tfexec.Terraform, module *tfconfig.Module, filePath string, workspaces []*Workspace, moved []tfconfig.Moved, providers []Provider, notificationInput *NotificationInput, importMappings []ImportMapping, onExisting string, initRetries int, importConcurrency int, result *PlanResult) error {
+	var (
+		importErr        error
+		importEnabled    = config.Import
+		importWorkspaces = workspaces
+	)
+
+	// explicit mappings are imported first, so discovery skips the resources they import
+	if len(importMappings) > 0 {
+		if err := ImportMappings(ctx, tf, result.Imports, importMappings); err != nil {
+			importErr = fmt.Errorf("failed to import mapped resources: %w", err)
+		}
+	}
+
+	// destroyed workspaces that are not in the state are left alone
+	if !config.Import && !result.Destroy && importErr == nil {
+		unmanaged, err := UnmanagedWorkspaces(ctx, tf, workspaces, moved)
+		if err != nil {
+			return fmt.Errorf("failed to check for existing workspaces: %w", err)
+		}
+
+		if len(unmanaged) > 0 {
+			names := make([]string, len(unmanaged))
+			for i, ws := range unmanaged {
+				names[i] = ws.Name
+			}
+
+			switch onExisting {
+			case OnExistingError:
+				importErr = fmt.Errorf("workspaces %s already exist but are not managed by this configuration, set import to true or on_existing to import or adopt to import them", strings.Join(names, ", "))
+			case OnExistingImport:
+				githubactions.Infof("Workspaces %s already exist, importing resources\n", strings.Join(names, ", "))
+
+				importEnabled = true
+			case OnExistingAdopt:
+				githubactions.Infof("Workspaces %s already exist, adopting them\n", strings.Join(names, ", "))
+
+				importEnabled = true
+				importWorkspaces = unmanaged
+			}
+		}
+	}
+
+	if importEnabled && importErr == nil {
+		if err := ImportResources(ctx, client, tf, module, filePath, importWorkspaces, config.Organization, providers, notificationInput, initRetries, importConcurrency, result.Imports); err != nil {
+			importErr = fmt.Errorf("failed to import resources: %w", err)
+		}
+	}
+
+	if len(importMappings) > 0 || importEnabled {
+		b, err := json.Marshal(result.Imports.Results())
+		if err != nil {
+			return fmt.Errorf("failed to convert import results to JSON: %w", err)
+		}
+
+		githubactions.SetOutput("imports_json", string(b))
+	}
+
+	return importErr
+}
+
+// parallelPlan plans the independent groups of workspaces in parallel, returning whether there are changes with the merged plan
+func parallelPlan(ctx context.Context, tf *tfexec.Terraform, workDir string, workspaces []*Workspace, resources *TargetOptions) (bool, string, *tfjson.Plan, error) {
+	state, err := tf.Show(ctx)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	groups, err := ParallelPlanGroups(workspaces, resources, state)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("failed to split the plan: %w", err)
+	}
+
+	githubactions.Infof("Planning %d groups in parallel\n", len(groups))
+
+	diff, planStr, plan, err := ParallelPlan(ctx, tf, workDir, groups)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("failed to plan: %w", err)
+	}
+
+	return diff, planStr, plan, nil
+}
+
+// setCostEstimateOutput sets the "cost_estimate_json" output from the run of the remote backend workspace, only warning when the cost estimate cannot be fetched
+func setCostEstimateOutput(ctx context.Context, client *tfe.Client, backend map[string]interface{}, planStarted time.Time) error {
+	org, name := tfconfig.RemoteBackendWorkspace(backend)
+	if name == "" {
+		githubactions.Infof("Cost estimates are only available for the remote backend with a named workspace, skipping cost estimate\n")
+		return nil
+	}
+
+	costEstimate, err := FetchCostEstimate(ctx, client, org, name, planStarted)
+	if err != nil {
+		githubactions.Warningf("Failed to fetch the cost estimate: %s\n", err)
+		return nil
+	}
+
+	if costEstimate == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(costEstimate)
+	if err != nil {
+		return fmt.Errorf("failed to convert cost estimate to JSON: %w", err)
+	}
+
+	githubactions.SetOutput("cost_estimate_json", string(b))
+
+	return nil
+}
+
+// setPlanOutputs sets the outputs describing a plan with changes and writes its summaries, to the step summary, the pull request and the SARIF report
+func setPlanOutputs(ctx context.Context, config *Inputs, plan *tfjson.Plan, planStr string, workspaces []*Workspace, baseline *tfjson.Plan) error {
+	githubactions.Infof(planStr)
+	githubactions.SetOutput("plan", planStr)
+
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to convert plan to JSON: %w", err)
+	}
+
+	githubactions.SetOutput("plan_json", string(b))
+
+	summary := PlanSummary(plan)
+
+	githubactions.SetOutput("plan_summary", summary)
+
+	b, err = json.Marshal(PerWorkspacePlan(plan, workspaces))
+	if err != nil {
+		return fmt.Errorf("failed to convert per workspace plan to JSON: %w", err)
+	}
+
+	githubactions.SetOutput("per_workspace_plan_json", string(b))
+
+	if config.PlanStepSummary {
+		if err = WriteStepSummary(fmt.Sprintf("### Terraform Cloud workspace changes\n\n```\n%s\n```", summary)); err != nil {
+			return fmt.Errorf("failed to write step summary: %w", err)
+		}
+	}
+
+	if config.PlanPRComment {
+		if err = CommentPlanOnPullRequest(ctx, config.Name, summary); err != nil {
+			return err
+		}
+	}
+
+	if baseline != nil {
+		setBaselineOutput(plan, baseline)
+	}
+
+	if config.SARIFOutput != "" {
+		if err = WriteSARIFReport(plan, config.SARIFOutput); err != nil {
+			return fmt.Errorf("failed to write SARIF report: %w", err)
+		}
+	}
+
+	if destroyed := DestroyedWorkspaces(plan); len(destroyed) > 0 {
+		githubactions.Infof("Workspaces to be deleted: %s\n", strings.Join(destroyed, ", "))
+	}
+
+	tagChanges := WorkspaceTagChanges(plan)
+
+	if len(tagChanges) > 0 {
+		for _, tc := range tagChanges {
+			githubactions.Infof("Workspace tag changes for %s: added %v, removed %v\n", tc.Address, tc.Added, tc.Removed)
+		}
+
+		b, err := json.Marshal(tagChanges)
+		if err != nil {
+			return fmt.Errorf("failed to convert tag changes to JSON: %w", err)
+		}
+
+		githubactions.SetOutput("tag_changes", string(b))
+	}
+
+	return nil
+}
+
+// skipApply reports whether the apply of a plan is skipped to require manual approval, because it deletes resources or changes resources that are not auto applied
+func skipApply(config *Inputs, plan *tfjson.Plan, autoApplyTypes []string) bool {
+	if !config.Apply {
+		return false
+	}
+
+	if config.SkipApplyOnDestroy {
+		if destroyed := DestroyedResourceTypes(plan); len(destroyed) > 0 {
+			githubactions.Warningf("Skipping apply, the plan deletes resources of type %s and requires manual approval", strings.Join(destroyed, ", "))
+
+			return true
+		}
+	}
+
+	if len(autoApplyTypes) > 0 {
+		if unapproved := UnapprovedResourceTypes(plan, autoApplyTypes); len(unapproved) > 0 {
+			githubactions.Infof("Skipping apply, changes to %s are not listed in auto_apply_resource_types and require manual approval\n", strings.Join(unapproved, ", "))
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply applies the saved plan of a Plan result and verifies the applied workspaces. It does nothing when apply is false, the plan has no changes or a plan check skipped the apply
+func Apply(ctx context.Context, config *Inputs, result *PlanResult) (err error) {
+	if !config.Apply || !result.HasChanges || result.ApplySkipped {
+		return nil
+	}
+
+	var (
+		tf             = result.tf
+		client         = result.client
+		httpClient     = result.httpClient
+		token          = result.token
+		workspaces     = result.workspaces
+		newWorkspaces  = result.newWorkspaces
+		targetInputs   = result.targetInputs
+		targetOpts     = result.targetOpts
+		teamAccess     = result.teamAccess
+		wsOptions      = result.wsOptions
+		moduleTemplate = result.moduleTemplate
+		backend        = result.backend
+		planStarted    = result.planStarted
+		plan           = result.Plan
+		planPath       = result.PlanPath
+	)
+
+	if config.ApplyRequiresPlanMatch {
+		githubactions.Infof("Planning again to verify the plan is unchanged...\n")
+
+		if err = VerifyPlanUnchanged(ctx, tf, plan, targetOpts); err != nil {
+			return err
+		}
+	}
+
+	if !config.SkipAgentCheck && (wsOptions.AgentPoolID != "" || config.AgentPoolName != "") {
+		warnings, err := AgentPoolsWithoutAgents(ctx, client, httpClient, fmt.Sprintf("https://%s", config.Host), token, distinctOrganizations(workspaces, config.Organization), wsOptions.AgentPoolID, config.AgentPoolName)
+		if err != nil {
+			return fmt.Errorf("failed to check agent availability: %w", err)
+		}
+
+		for _, w := range warnings {
+			githubactions.Warningf("The apply may hang, %s", w)
+		}
+	}
+
+	var (
+		deployments  *GitHubDeployments
+		deploymentID int64
+	)
+
+	if config.Environment != "" {
+		deployments, err = NewGitHubDeploymentsFromEnv()
+		if err != nil {
+			return err
+		}
+
+		deploymentID, err = deployments.Create(ctx, os.Getenv("GITHUB_SHA"), config.Environment)
+		if err != nil {
+			return err
+		}
+
+		githubactions.Infof("Created deployment %d to environment %q\n", deploymentID, config.Environment)
+	}
+
+	githubactions.Infof("Applying...\n")
+
+	applyErr := tf.Apply(ctx, tfexec.DirOrPlan(planPath))
+
+	if applyErr != nil && config.ContinueOnPartialFailure {
+		// the remaining changes pass the same checks as the saved plan, and must be approved if an approved plan is passed
+		check := func(remaining *tfjson.Plan) error {
+			if err := result.checks.Check(remaining); err != nil {
+				return err
+			}
+
+			if result.approved == nil {
+				return nil
+			}
+
+			if diffs := PlanSubsetDifferences(remaining, result.approved); len(diffs) > 0 {
+				return planBlocked("error: the remaining changes differ from the approved plan, not applying: %s", strings.Join(diffs, "; "))
+			}
+
+			return nil
+		}
+
+		if err = ContinueAfterPartialFailure(ctx, tf, plan, applyErr, targetOpts, check); err != nil {
+			githubactions.Warningf("%s\n", err)
+		}
+	}
+
+	runMessage := config.RunMessage
+	if runMessage == "" {
+		runMessage = DefaultRunMessage()
+	}
+
+	if org, name := tfconfig.RemoteBackendWorkspace(backend); name != "" && runMessage != "" {
+		if err = AddRunMessage(ctx, client, httpClient, fmt.Sprintf("https://%s", config.Host), token, org, name, runMessage, planStarted); err != nil {
+			githubactions.Warningf("Failed to add the run message: %s\n", err)
+		}
+	}
+
+	if deployments != nil {
+		state := "success"
+		if applyErr != nil {
+			state = "failure"
+		}
+
+		if err = deployments.SetStatus(ctx, deploymentID, state); err != nil {
+			githubactions.Warningf("%s\n", err)
+		}
+	}
+
+	if applyErr != nil {
+		return fmt.Errorf("failed to apply: %w", applyErr)
+	}
+
+	githubactions.Infof("Success\n")
+
+	// the destroyed workspaces are not verified
+	if result.Destroy {
+		return nil
+	}
+
+	// new workspaces are initialized first, as they are no longer seen as new by the next run
+	if moduleTemplate != nil || config.EmptyConfigurationVersion {
+		for _, ws := range newWorkspaces {
+			created, err := client.Workspaces.Read(ctx, workspaceOrganization(ws, config.Organization), ws.Name)
+			if err != nil {
+				if errors.Is(err, tfe.ErrResourceNotFound) {
+					// not created by this run, e.g. excluded by target_workspaces
+					continue
+				}
+
+				return fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+			}
+
+			if moduleTemplate != nil {
+				if err = moduleTemplate.Upload(ctx, client, created.ID); err != nil {
+					return fmt.Errorf("failed to initialize workspace %q with module %q: %w", ws.Name, moduleTemplate.Source(), err)
+				}
+
+				githubactions.Infof("Initialized workspace %q with module %s version %s\n", ws.Name, moduleTemplate.Source(), moduleTemplate.Version)
+
+				continue
+			}
+
+			// VCS-driven workspaces ingest their configuration versions from the repository
+			if created.VCSRepo != nil {
+				continue
+			}
+
+			if err = UploadEmptyConfigurationVersion(ctx, client, created.ID); err != nil {
+				return fmt.Errorf("failed to upload an empty configuration version to workspace %q: %w", ws.Name, err)
+			}
+
+			githubactions.Infof("Uploaded an empty configuration version to workspace %q\n", ws.Name)
+		}
+	}
+
+	verifyWorkspaces := workspaces
+	if len(targetInputs) > 0 {
+		verifyWorkspaces = []*Workspace{}
+
+		for _, target := range targetInputs {
+			verifyWorkspaces = append(verifyWorkspaces, FindWorkspace(workspaces, target))
+		}
+	}
+
+	// workspaces created by the apply have no ID yet
+	if err = SetWorkspaceIDs(ctx, client, verifyWorkspaces, config.Organization); err != nil {
+		return fmt.Errorf("failed to set workspace IDs: %w", err)
+	}
+
+	if config.PruneTeamAccess {
+		if err = PruneTeamAccess(ctx, client, verifyWorkspaces, teamAccess, config.Organization); err != nil {
+			return fmt.Errorf("failed to prune team access: %w", err)
+		}
+	}
+
+	// verification is advisory, the apply already succeeded
+	problems, err := VerifyWorkspaces(ctx, client, verifyWorkspaces, config.Organization, wsOptions)
+	if err != nil {
+		githubactions.Warningf("Failed to verify workspaces: %s\n", err)
+	} else {
+		for _, p := range problems {
+			githubactions.Warningf("Workspace verification failed after apply: %s\n", p)
+		}
+
+		if len(problems) == 0 {
+			githubactions.Infof("Verified %d workspaces\n", len(verifyWorkspaces))
+		}
+
+		if err = WriteStepSummary(VerificationSummary(verifyWorkspaces, problems)); err != nil {
+			return fmt.Errorf("failed to write step summary: %w", err)
+		}
+	}
+
+	if !config.ReportHealth {
+		return nil
+	}
+
+	// health is reported for information only, the apply already succeeded
+	health, err := FetchWorkspaceHealth(ctx, httpClient, fmt.Sprintf("https://%s", config.Host), token, verifyWorkspaces)
+	if err != nil {
+		githubactions.Warningf("Failed to fetch workspace health: %s\n", err)
+		return nil
+	}
+
+	b, err := json.Marshal(health)
+	if err != nil {
+		return fmt.Errorf("failed to convert workspace health to JSON: %w", err)
+	}
+
+	githubactions.SetOutput("health_json", string(b))
+
+	return nil
+}
+
+// setBaselineOutput sets the "plan_changed_since_baseline" output by comparing the passed plan to the baseline plan
+func setBaselineOutput(plan *tfjson.Plan, baseline *tfjson.Plan) {
+	changed := PlanChangedSinceBaseline(plan, baseline)
+	if changed {
+		githubactions.Infof("Plan has changed since the baseline plan\n")
+	}
+
+	githubactions.SetOutput("plan_changed_since_baseline", strconv.FormatBool(changed))
+}
@@ -2,14 +2,21 @@ package action
 
 import (
 	"context"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"strconv"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	yaml "gopkg.in/yaml.v2"
@@ -42,8 +49,10 @@ func newTestInputs(t *testing.T) *Inputs {
 		Host:                   action.Inputs["terraform_host"].Default,
 		Name:                   fmt.Sprintf("%s-%s", testWorkspacePrefix, uuid.New()),
 		Import:                 imp,
+		AllowTagChanges:        boolPtr(true),
 		Apply:                  true,
 		TFEProviderVersion:     action.Inputs["tfe_provider_version"].Default,
+		TFEProviderSource:      action.Inputs["tfe_provider_source"].Default,
 		RunnerTerraformVersion: action.Inputs["runner_terraform_version"].Default,
 		TerraformVersion:       action.Inputs["terraform_version"].Default,
 	}
@@ -58,17 +67,11 @@ func removeTestWorkspacesFunc(t *testing.T, ctx context.Context, client *tfe.Cli
 
 // removeTestWorkspaces deletes matching test workspaces created by the integration tests
 func removeTestWorkspaces(t *testing.T, ctx context.Context, client *tfe.Client, match string) {
-	workspaces, err := client.Workspaces.List(ctx, os.Getenv("TF_ORGANIZATION"), tfe.WorkspaceListOptions{
-		Search: tfe.String(match),
-		ListOptions: tfe.ListOptions{
-			PageSize: maxPageSize,
-		},
-	})
+	ids, err := FetchWorkspaceIDsBySearch(ctx, client, os.Getenv("TF_ORGANIZATION"), match)
 	require.NoError(t, err)
 
-	for _, ws := range workspaces.Items {
-		err := client.Workspaces.DeleteByID(ctx, ws.ID)
-		assert.NoError(t, err)
+	for _, id := range ids {
+		assert.NoError(t, DeleteWorkspace(ctx, client, id))
 	}
 }
 
@@ -373,3 +376,177 @@ func TestWorkspaceRunTriggers(t *testing.T) {
 
 	assert.Len(t, triggers.Items, 1)
 }
+
+func TestValidateRenderOnly(t *testing.T) {
+	t.Run("pass with a render path", func(t *testing.T) {
+		assert.NoError(t, ValidateRenderOnly(&Inputs{RenderOnly: true, RenderPath: "main.tf.json", VCSType: "github", VCSTokenID: "ot-abc123"}))
+	})
+
+	t.Run("error without a render path", func(t *testing.T) {
+		assert.Error(t, ValidateRenderOnly(&Inputs{RenderOnly: true}))
+	})
+
+	t.Run("error when the VCS token must be looked up", func(t *testing.T) {
+		assert.Error(t, ValidateRenderOnly(&Inputs{RenderOnly: true, RenderPath: "main.tf.json", VCSType: "github"}))
+	})
+
+	t.Run("error when the agent pool must be looked up", func(t *testing.T) {
+		assert.Error(t, ValidateRenderOnly(&Inputs{RenderOnly: true, RenderPath: "main.tf.json", AgentPoolName: "pool"}))
+	})
+
+	t.Run("error when variable sets must be read", func(t *testing.T) {
+		assert.Error(t, ValidateRenderOnly(&Inputs{RenderOnly: true, RenderPath: "main.tf.json", VariableSets: "- shared"}))
+	})
+}
+
+func TestRenderOnly(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "main.tf.json")
+
+	err := Run(&Inputs{
+		Name:         "foo",
+		Organization: "org",
+		Host:         "app.terraform.io",
+		RenderOnly:   true,
+		RenderPath:   filePath,
+		Variables: `---
+- key: foo
+  value: bar
+  category: env`,
+	})
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	var module map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(b, &module))
+	assert.Contains(t, module["resource"], "tfe_workspace")
+	assert.Contains(t, module["resource"], "tfe_variable")
+}
+
+// testTFApplier records the Terraform commands run by Apply, planning no changes
+type testTFApplier struct {
+	calls *[]string
+}
+
+func (tf *testTFApplier) Plan(ctx context.Context, opts ...tfexec.PlanOption) (bool, error) {
+	*tf.calls = append(*tf.calls, "plan")
+
+	return false, nil
+}
+
+func (tf *testTFApplier) ShowPlanFile(ctx context.Context, planPath string, opts ...tfexec.ShowOption) (*tfjson.Plan, error) {
+	*tf.calls = append(*tf.calls, "show")
+
+	return &tfjson.Plan{}, nil
+}
+
+func (tf *testTFApplier) Apply(ctx context.Context, opts ...tfexec.ApplyOption) error {
+	*tf.calls = append(*tf.calls, "apply")
+
+	return nil
+}
+
+func TestPlanAndApply(t *testing.T) {
+	t.Run("do nothing without changes", func(t *testing.T) {
+		config := &Inputs{
+			Name:         "foo",
+			Organization: "org",
+			Host:         "app.terraform.io",
+			RenderOnly:   true,
+			RenderPath:   path.Join(t.TempDir(), "main.tf.json"),
+			Apply:        true,
+		}
+
+		result, err := Plan(context.Background(), config)
+		require.NoError(t, err)
+
+		defer result.Close(nil)
+
+		assert.False(t, result.HasChanges)
+		assert.Nil(t, result.Plan)
+
+		// nothing is applied without changes
+		assert.NoError(t, Apply(context.Background(), config, result))
+	})
+
+	t.Run("apply a plan with changes before closing the result", func(t *testing.T) {
+		t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+		var calls []string
+
+		config := &Inputs{
+			Organization:           "org",
+			Apply:                  true,
+			ApplyRequiresPlanMatch: true,
+		}
+
+		result := &PlanResult{
+			PlanPath:   "plan.txt",
+			Plan:       &tfjson.Plan{},
+			HasChanges: true,
+			tf:         &testTFApplier{calls: &calls},
+			wsOptions:  &WorkspaceResourceOptions{},
+			checks:     &PlanChecks{MaxNewWorkspaces: -1},
+			cleanups: []func(error){
+				func(err error) { calls = append(calls, "release lock") },
+				func(err error) { calls = append(calls, fmt.Sprintf("remove workdir: %v", err)) },
+			},
+		}
+
+		err := Apply(context.Background(), config, result)
+		result.Close(err)
+
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"plan", "apply", "remove workdir: <nil>", "release lock"}, calls)
+	})
+
+	t.Run("trust the CA bundle in the process until the result is closed", func(t *testing.T) {
+		t.Setenv("SSL_CERT_FILE", "/etc/ssl/custom.pem")
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+		bundlePath := path.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundlePath, cert, 0644))
+
+		result, err := Plan(context.Background(), &Inputs{
+			Name:         "foo",
+			Organization: "org",
+			Host:         "app.terraform.io",
+			RenderOnly:   true,
+			RenderPath:   path.Join(t.TempDir(), "main.tf.json"),
+			CABundlePath: bundlePath,
+		})
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(os.Getenv("SSL_CERT_FILE"))
+		require.NoError(t, err)
+
+		assert.Contains(t, string(b), string(cert))
+
+		result.Close(nil)
+
+		assert.Equal(t, "/etc/ssl/custom.pem", os.Getenv("SSL_CERT_FILE"))
+	})
+}
+
+func TestPlanResultClose(t *testing.T) {
+	var calls []string
+
+	result := &PlanResult{
+		cleanups: []func(error){
+			func(err error) { calls = append(calls, "lock") },
+			func(err error) { calls = append(calls, fmt.Sprintf("workdir: %v", err)) },
+		},
+	}
+
+	result.Close(fmt.Errorf("failed"))
+	result.Close(nil)
+
+	assert.Equal(t, []string{"workdir: failed", "lock"}, calls)
+}
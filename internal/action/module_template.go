@@ -0,0 +1,126 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// ModuleTemplate is a private registry module that new workspaces are initialized with
+type ModuleTemplate struct {
+	Host         string
+	Organization string
+	Name         string
+	Provider     string
+	Version      string
+}
+
+// ParseModuleTemplate parses a private registry module source, either "<host>/<organization>/<name>/<provider>" or "<organization>/<name>/<provider>" on the passed host
+func ParseModuleTemplate(source string, version string, host string) (*ModuleTemplate, error) {
+	parts := strings.Split(source, "/")
+
+	if len(parts) == 3 {
+		parts = append([]string{host}, parts...)
+	}
+
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid module source %q, expected <host>/<organization>/<name>/<provider>", source)
+	}
+
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("invalid module source %q, expected <host>/<organization>/<name>/<provider>", source)
+		}
+	}
+
+	if version == "" {
+		return nil, fmt.Errorf("module_version must be set with module_source")
+	}
+
+	return &ModuleTemplate{
+		Host:         parts[0],
+		Organization: parts[1],
+		Name:         parts[2],
+		Provider:     parts[3],
+		Version:      version,
+	}, nil
+}
+
+// Source returns the registry source address of the module
+func (m ModuleTemplate) Source() string {
+	return strings.Join([]string{m.Host, m.Organization, m.Name, m.Provider}, "/")
+}
+
+// Validate checks that the module exists in the organization's private registry with the template version published
+func (m ModuleTemplate) Validate(ctx context.Context, client *tfe.Client) error {
+	module, err := client.RegistryModules.Read(ctx, m.Organization, m.Name, m.Provider)
+	if err != nil {
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			return fmt.Errorf("module %q not found in the private registry of organization %q", m.Source(), m.Organization)
+		}
+
+		return fmt.Errorf("failed to read module %q: %w", m.Source(), err)
+	}
+
+	for _, v := range module.VersionStatuses {
+		if v.Version == m.Version {
+			if v.Status != tfe.RegistryModuleVersionStatusOk {
+				return fmt.Errorf("version %s of module %q is not available, status %q", m.Version, m.Source(), v.Status)
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("version %s of module %q not found", m.Version, m.Source())
+}
+
+// Configuration returns the JSON configuration of a root module that calls the template module
+func (m ModuleTemplate) Configuration() ([]byte, error) {
+	return json.MarshalIndent(map[string]interface{}{
+		"module": map[string]interface{}{
+			m.Name: map[string]string{
+				"source":  m.Source(),
+				"version": m.Version,
+			},
+		},
+	}, "", "  ")
+}
+
+// Upload uploads the template configuration to the passed workspace, queueing its first run
+func (m ModuleTemplate) Upload(ctx context.Context, client *tfe.Client, workspaceID string) error {
+	dir, err := ioutil.TempDir("", "module-template")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := m.Configuration()
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(path.Join(dir, "main.tf.json"), b, 0644); err != nil {
+		return err
+	}
+
+	cv, err := client.ConfigurationVersions.Create(ctx, workspaceID, tfe.ConfigurationVersionCreateOptions{
+		AutoQueueRuns: tfe.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create configuration version: %w", err)
+	}
+
+	if err = client.ConfigurationVersions.Upload(ctx, cv.UploadURL, dir); err != nil {
+		return fmt.Errorf("failed to upload configuration version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModuleTemplate(t *testing.T) {
+	t.Run("parse a source with a host", func(t *testing.T) {
+		m, err := ParseModuleTemplate("tfe.example.com/org/network/aws", "1.0.0", "app.terraform.io")
+		require.NoError(t, err)
+
+		assert.Equal(t, &ModuleTemplate{Host: "tfe.example.com", Organization: "org", Name: "network", Provider: "aws", Version: "1.0.0"}, m)
+	})
+
+	t.Run("default to the passed host", func(t *testing.T) {
+		m, err := ParseModuleTemplate("org/network/aws", "1.0.0", "app.terraform.io")
+		require.NoError(t, err)
+
+		assert.Equal(t, "app.terraform.io/org/network/aws", m.Source())
+	})
+
+	t.Run("error on an invalid source", func(t *testing.T) {
+		_, err := ParseModuleTemplate("org/network", "1.0.0", "app.terraform.io")
+		assert.EqualError(t, err, `invalid module source "org/network", expected <host>/<organization>/<name>/<provider>`)
+	})
+
+	t.Run("error without a version", func(t *testing.T) {
+		_, err := ParseModuleTemplate("org/network/aws", "", "app.terraform.io")
+		assert.EqualError(t, err, "module_version must be set with module_source")
+	})
+}
+
+func TestModuleTemplateValidate(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/registry-modules/show/org/network/aws", testServerResHandler(t, 200, `{"data": {"id": "mod-abc123", "type": "registry-modules", "attributes": {"name": "network", "provider": "aws", "version-statuses": [
+		{"version": "1.0.0", "status": "ok"},
+		{"version": "1.1.0", "status": "reg_ingress_failed"}
+	]}}}`))
+	mux.HandleFunc("/api/v2/registry-modules/show/org/missing/aws", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	newTemplate := func(name string, version string) ModuleTemplate {
+		return ModuleTemplate{Host: "app.terraform.io", Organization: "org", Name: name, Provider: "aws", Version: version}
+	}
+
+	t.Run("accept a published version", func(t *testing.T) {
+		assert.NoError(t, newTemplate("network", "1.0.0").Validate(ctx, client))
+	})
+
+	t.Run("error on a version that failed to publish", func(t *testing.T) {
+		err := newTemplate("network", "1.1.0").Validate(ctx, client)
+		assert.EqualError(t, err, `version 1.1.0 of module "app.terraform.io/org/network/aws" is not available, status "reg_ingress_failed"`)
+	})
+
+	t.Run("error on an unknown version", func(t *testing.T) {
+		err := newTemplate("network", "2.0.0").Validate(ctx, client)
+		assert.EqualError(t, err, `version 2.0.0 of module "app.terraform.io/org/network/aws" not found`)
+	})
+
+	t.Run("error on an unknown module", func(t *testing.T) {
+		err := newTemplate("missing", "1.0.0").Validate(ctx, client)
+		assert.EqualError(t, err, `module "app.terraform.io/org/missing/aws" not found in the private registry of organization "org"`)
+	})
+}
+
+func TestModuleTemplateConfiguration(t *testing.T) {
+	b, err := ModuleTemplate{Host: "app.terraform.io", Organization: "org", Name: "network", Provider: "aws", Version: "1.0.0"}.Configuration()
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"module": {"network": {"source": "app.terraform.io/org/network/aws", "version": "1.0.0"}}}`, string(b))
+}
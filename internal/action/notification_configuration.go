@@ -1,6 +1,12 @@
 package action
 
-import "github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
+import (
+	"context"
+	"strconv"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
+)
 
 type NotificationInput struct {
 	Name            string `yaml:"name"`
@@ -33,12 +39,18 @@ func MergeNotifications(input *NotificationInput, workspaces []*Workspace) (noti
 	return notifications
 }
 
+// ToResource converts the notification to a Terraform resource, referencing the workspace resource if the workspace does not exist yet
 func (n Notification) ToResource() *tfeprovider.NotificationConfiguration {
+	workspaceID := n.Workspace.IDRef()
+	if n.Workspace.ID != nil {
+		workspaceID = *n.Workspace.ID
+	}
+
 	return &tfeprovider.NotificationConfiguration{
 		Name:            n.Input.Name,
 		DestinationType: n.Input.DestinationType,
 		URL:             n.Input.URL,
-		WorkspaceID:     *n.Workspace.ID,
+		WorkspaceID:     workspaceID,
 		EmailAddresses:  n.Input.EmailAddresses,
 		EmailUserIDs:    n.Input.EmailUserIDs,
 		Enabled:         n.Input.Enabled,
@@ -46,3 +58,36 @@ func (n Notification) ToResource() *tfeprovider.NotificationConfiguration {
 		Triggers:        n.Input.Triggers,
 	}
 }
+
+// ToNotificationResource converts an existing tfe.NotificationConfiguration to a Terraform resource
+func ToNotificationResource(n *tfe.NotificationConfiguration, workspace *Workspace) *tfeprovider.NotificationConfiguration {
+	return &tfeprovider.NotificationConfiguration{
+		Name:            n.Name,
+		DestinationType: string(n.DestinationType),
+		URL:             n.URL,
+		WorkspaceID:     *workspace.ID,
+		EmailAddresses:  n.EmailAddresses,
+		Enabled:         strconv.FormatBool(n.Enabled),
+		Triggers:        n.Triggers,
+	}
+}
+
+// FetchNotification returns the notification configuration of the passed workspace matching the passed name, nil is returned if none is found
+func FetchNotification(ctx context.Context, client *tfe.Client, workspace *Workspace, name string) (*tfe.NotificationConfiguration, error) {
+	list, err := client.NotificationConfigurations.List(ctx, *workspace.ID, tfe.NotificationConfigurationListOptions{
+		ListOptions: tfe.ListOptions{
+			PageSize: maxPageSize,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range list.Items {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -1,6 +1,9 @@
 package action
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,6 +26,18 @@ func TestNotificationToResource(t *testing.T) {
 			WorkspaceID:     "ws-abc123",
 		}, n.ToResource())
 	})
+
+	t.Run("reference the workspace resource if the workspace does not exist yet", func(t *testing.T) {
+		n := Notification{
+			Input: &NotificationInput{
+				Name:            "foo",
+				DestinationType: "email",
+			},
+			Workspace: &Workspace{Name: "ws", Workspace: "default"},
+		}
+
+		assert.Equal(t, "${tfe_workspace.workspace[\"default\"].id}", n.ToResource().WorkspaceID)
+	})
 }
 
 func TestMergeNotifications(t *testing.T) {
@@ -53,3 +68,45 @@ func TestMergeNotifications(t *testing.T) {
 		assert.Len(t, notifications, 0)
 	})
 }
+
+func TestFetchNotification(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123/notification-configurations", testServerResHandler(t, 200, `{
+  "data": [
+    {
+      "id": "nc-abc123",
+      "type": "notification-configurations",
+      "attributes": {
+        "destination-type": "email",
+        "enabled": true,
+        "name": "foo",
+        "triggers": []
+      }
+    }
+  ]
+}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("return the notification matching the passed name", func(t *testing.T) {
+		n, err := FetchNotification(ctx, client, newTestWorkspace(), "foo")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "nc-abc123", n.ID)
+	})
+
+	t.Run("return nil if no notification matches the passed name", func(t *testing.T) {
+		n, err := FetchNotification(ctx, client, newTestWorkspace(), "bar")
+		assert.NoError(t, err)
+
+		assert.Nil(t, n)
+	})
+}
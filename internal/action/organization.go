@@ -0,0 +1,187 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// organizationSettings is the subset of the organization API response containing its assessment settings
+type organizationSettings struct {
+	Data struct {
+		Attributes struct {
+			AssessmentsEnforced bool `json:"assessments-enforced"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// FetchAssessmentsEnforced returns whether the organization enforces health assessments on all of its workspaces.
+// The setting is not exposed by the go-tfe client, so the organization is read from the API directly.
+func FetchAssessmentsEnforced(ctx context.Context, httpClient *http.Client, address string, token string, organization string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/v2/organizations/%s", address, url.QueryEscape(organization)), nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to read organization %q: %s", organization, resp.Status)
+	}
+
+	var settings organizationSettings
+
+	if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+		return false, fmt.Errorf("failed to decode organization %q: %w", organization, err)
+	}
+
+	return settings.Data.Attributes.AssessmentsEnforced, nil
+}
+
+// EffectiveAssessments describes the health assessment behavior of a workspace without an explicit assessments_enabled setting
+func EffectiveAssessments(enforced bool) string {
+	if enforced {
+		return "enabled, enforced by the organization"
+	}
+
+	return "disabled, the Terraform Cloud default"
+}
+
+// entitlementSet is the subset of the organization entitlement set API response containing the assessments entitlement, which the go-tfe client does not expose
+type entitlementSet struct {
+	Data struct {
+		Attributes struct {
+			Assessments bool `json:"assessments"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// FetchAssessmentsEntitled returns whether the plan of the organization includes health assessments
+func FetchAssessmentsEntitled(ctx context.Context, httpClient *http.Client, address string, token string, organization string) (bool, error) {
+	var entitlements entitlementSet
+
+	status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("organizations/%s/entitlement-set", url.PathEscape(organization)), &entitlements)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the entitlements of organization %q: %w", organization, err)
+	}
+
+	if status != http.StatusOK {
+		return false, fmt.Errorf("failed to read the entitlements of organization %q: %d %s", organization, status, http.StatusText(status))
+	}
+
+	return entitlements.Data.Attributes.Assessments, nil
+}
+
+// AssessmentOrganizations returns the sorted organizations of the workspaces with health assessments enabled, by assessments_enabled or by their workspace settings
+func AssessmentOrganizations(workspaces []*Workspace, organization string, enabled *bool, settings map[string]WorkspaceSettings) []string {
+	seen := map[string]bool{}
+	orgs := []string{}
+
+	for _, ws := range workspaces {
+		e := enabled
+		if s, ok := settings[ws.Workspace]; ok && s.AssessmentsEnabled != nil {
+			e = s.AssessmentsEnabled
+		}
+
+		if org := workspaceOrganization(ws, organization); e != nil && *e && !seen[org] {
+			seen[org] = true
+			orgs = append(orgs, org)
+		}
+	}
+
+	sort.Strings(orgs)
+
+	return orgs
+}
+
+// CheckTokenPermissions returns an error if the API token cannot read the passed organization, or cannot create workspaces in it if create is true
+func CheckTokenPermissions(ctx context.Context, client *tfe.Client, organization string, create bool) error {
+	org, err := client.Organizations.Read(ctx, organization)
+	if err != nil {
+		return fmt.Errorf("token cannot read organization %q: %w", organization, err)
+	}
+
+	if create && (org.Permissions == nil || !org.Permissions.CanCreateWorkspace) {
+		return fmt.Errorf("token cannot create workspaces in organization %q", organization)
+	}
+
+	return nil
+}
+
+// organizationDefaultSettings is the subset of the organization API response containing the defaults of new workspaces
+type organizationDefaultSettings struct {
+	Data struct {
+		Attributes struct {
+			DefaultExecutionMode string `json:"default-execution-mode"`
+		} `json:"attributes"`
+		Relationships struct {
+			DefaultAgentPool struct {
+				Data *struct {
+					ID string `json:"id"`
+				} `json:"data"`
+			} `json:"default-agent-pool"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+// OrganizationDefaults are the execution settings an organization applies to its workspaces by default
+type OrganizationDefaults struct {
+	ExecutionMode string
+	AgentPoolID   string
+}
+
+// FetchOrganizationDefaults returns the default execution mode and agent pool of the organization
+func FetchOrganizationDefaults(ctx context.Context, httpClient *http.Client, address string, token string, organization string) (*OrganizationDefaults, error) {
+	var settings organizationDefaultSettings
+
+	status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("organizations/%s", url.PathEscape(organization)), &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organization %q: %w", organization, err)
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to read organization %q: %d %s", organization, status, http.StatusText(status))
+	}
+
+	defaults := &OrganizationDefaults{
+		ExecutionMode: settings.Data.Attributes.DefaultExecutionMode,
+	}
+
+	if pool := settings.Data.Relationships.DefaultAgentPool.Data; pool != nil {
+		defaults.AgentPoolID = pool.ID
+	}
+
+	return defaults, nil
+}
+
+// InheritOrganizationDefaults returns the execution mode and agent pool ID to use, which are the organization defaults if none of the execution inputs are set, and a description of each inherited default.
+// The default agent pool is only inherited with the agent execution mode. The passed inputs are not modified.
+func InheritOrganizationDefaults(config *Inputs, defaults *OrganizationDefaults) (executionMode string, agentPoolID string, inherited []string) {
+	inherited = []string{}
+
+	if config.ExecutionMode != "" || config.AgentPoolID != "" || config.AgentPoolName != "" || defaults.ExecutionMode == "" {
+		return config.ExecutionMode, config.AgentPoolID, inherited
+	}
+
+	executionMode = defaults.ExecutionMode
+	inherited = append(inherited, fmt.Sprintf("execution_mode %q", defaults.ExecutionMode))
+
+	if defaults.ExecutionMode == "agent" && defaults.AgentPoolID != "" {
+		agentPoolID = defaults.AgentPoolID
+		inherited = append(inherited, fmt.Sprintf("agent_pool_id %q", defaults.AgentPoolID))
+	}
+
+	return executionMode, agentPoolID, inherited
+}
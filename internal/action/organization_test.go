@@ -0,0 +1,195 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchAssessmentsEnforced(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("return the organization assessment setting", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer 12345", r.Header.Get("Authorization"))
+
+			testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"assessments-enforced": true}}}`)(w, r)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		enforced, err := FetchAssessmentsEnforced(ctx, http.DefaultClient, server.URL, "12345", "org")
+		require.NoError(t, err)
+
+		assert.True(t, enforced)
+	})
+
+	t.Run("error when the organization cannot be read", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		_, err := FetchAssessmentsEnforced(ctx, http.DefaultClient, server.URL, "12345", "org")
+		assert.EqualError(t, err, "failed to read organization \"org\": 404 Not Found")
+	})
+}
+
+func TestFetchAssessmentsEntitled(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/free/entitlement-set", testServerResHandler(t, 200, `{"data": {"id": "org-abc123", "type": "entitlement-sets", "attributes": {"assessments": false}}}`))
+	mux.HandleFunc("/api/v2/organizations/business/entitlement-set", testServerResHandler(t, 200, `{"data": {"id": "org-def456", "type": "entitlement-sets", "attributes": {"assessments": true}}}`))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	entitled, err := FetchAssessmentsEntitled(ctx, http.DefaultClient, server.URL, "12345", "free")
+	require.NoError(t, err)
+
+	assert.False(t, entitled)
+
+	entitled, err = FetchAssessmentsEntitled(ctx, http.DefaultClient, server.URL, "12345", "business")
+	require.NoError(t, err)
+
+	assert.True(t, entitled)
+
+	_, err = FetchAssessmentsEntitled(ctx, http.DefaultClient, server.URL, "12345", "missing")
+	assert.EqualError(t, err, "failed to read the entitlements of organization \"missing\": 404 Not Found")
+}
+
+func TestAssessmentOrganizations(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: "foo-staging", Workspace: "staging"},
+		{Name: "foo-production", Workspace: "production", Organization: "other"},
+	}
+
+	t.Run("none when assessments are not enabled", func(t *testing.T) {
+		assert.Empty(t, AssessmentOrganizations(workspaces, "org", nil, nil))
+	})
+
+	t.Run("organizations of all workspaces when enabled", func(t *testing.T) {
+		assert.Equal(t, []string{"org", "other"}, AssessmentOrganizations(workspaces, "org", boolPtr(true), nil))
+	})
+
+	t.Run("workspace settings override the input", func(t *testing.T) {
+		settings := map[string]WorkspaceSettings{
+			"staging": {AssessmentsEnabled: boolPtr(false)},
+		}
+
+		assert.Equal(t, []string{"other"}, AssessmentOrganizations(workspaces, "org", boolPtr(true), settings))
+	})
+}
+
+func TestEffectiveAssessments(t *testing.T) {
+	assert.Equal(t, "enabled, enforced by the organization", EffectiveAssessments(true))
+	assert.Equal(t, "disabled, the Terraform Cloud default", EffectiveAssessments(false))
+}
+
+func TestCheckTokenPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	orgResponse := func(canCreateWorkspace bool) string {
+		return fmt.Sprintf(`{"data": {"id": "org", "type": "organizations", "attributes": {"name": "org", "permissions": {"can-create-workspace": %t}}}}`, canCreateWorkspace)
+	}
+
+	t.Run("pass when the token can create workspaces", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, orgResponse(true)))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		assert.NoError(t, CheckTokenPermissions(ctx, newTestTFClient(t, server.URL), "org", true))
+	})
+
+	t.Run("error when the token cannot create workspaces", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, orgResponse(false)))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		assert.EqualError(t, CheckTokenPermissions(ctx, newTestTFClient(t, server.URL), "org", true), "token cannot create workspaces in organization \"org\"")
+	})
+
+	t.Run("pass without create permission when no workspaces are created", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, orgResponse(false)))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		assert.NoError(t, CheckTokenPermissions(ctx, newTestTFClient(t, server.URL), "org", false))
+	})
+
+	t.Run("error when the token cannot read the organization", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		assert.ErrorContains(t, CheckTokenPermissions(ctx, newTestTFClient(t, server.URL), "org", false), "token cannot read organization \"org\"")
+	})
+}
+
+func TestFetchOrganizationDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("return the default execution mode and agent pool", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 200, `{"data": {"id": "org", "type": "organizations", "attributes": {"default-execution-mode": "agent"}, "relationships": {"default-agent-pool": {"data": {"id": "apool-abc123", "type": "agent-pools"}}}}}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		defaults, err := FetchOrganizationDefaults(ctx, http.DefaultClient, server.URL, "12345", "org")
+		require.NoError(t, err)
+
+		assert.Equal(t, &OrganizationDefaults{ExecutionMode: "agent", AgentPoolID: "apool-abc123"}, defaults)
+	})
+
+	t.Run("error when the organization cannot be read", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		_, err := FetchOrganizationDefaults(ctx, http.DefaultClient, server.URL, "12345", "org")
+		assert.EqualError(t, err, "failed to read organization \"org\": 404 Not Found")
+	})
+}
+
+func TestInheritOrganizationDefaults(t *testing.T) {
+	defaults := &OrganizationDefaults{ExecutionMode: "agent", AgentPoolID: "apool-abc123"}
+
+	t.Run("inherit the defaults when no execution settings are set", func(t *testing.T) {
+		config := &Inputs{}
+
+		executionMode, agentPoolID, inherited := InheritOrganizationDefaults(config, defaults)
+
+		assert.Equal(t, []string{`execution_mode "agent"`, `agent_pool_id "apool-abc123"`}, inherited)
+		assert.Equal(t, "agent", executionMode)
+		assert.Equal(t, "apool-abc123", agentPoolID)
+		assert.Equal(t, &Inputs{}, config)
+	})
+
+	t.Run("keep explicitly set execution settings", func(t *testing.T) {
+		executionMode, agentPoolID, inherited := InheritOrganizationDefaults(&Inputs{ExecutionMode: "remote"}, defaults)
+
+		assert.Empty(t, inherited)
+		assert.Equal(t, "remote", executionMode)
+		assert.Empty(t, agentPoolID)
+	})
+}
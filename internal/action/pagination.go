@@ -0,0 +1,80 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// pageRetryAttempts is the number of attempts made to fetch a single page of a paginated list
+var pageRetryAttempts = 3
+
+// pageRetryInterval is the time waited between attempts to fetch a page, after the API client's own rate limit retries are exhausted
+var pageRetryInterval = 5 * time.Second
+
+// transientPageErrors are lowercased substrings of the errors of rate limited and failed server responses, go-tfe reports either the response status or the JSON:API error title
+var transientPageErrors = []string{
+	"too many requests",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+}
+
+// isTransientPageError returns true if the passed error was caused by a rate limit, a server error or a network failure
+func isTransientPageError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, s := range transientPageErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fetchPage calls the passed function to fetch a single page, retrying it after transient failures so that pagination continues from the failed page instead of restarting.
+// Other errors are returned without retrying, and retries stop once the passed context is done.
+func fetchPage(ctx context.Context, page int, fetch func() error) error {
+	var err error
+
+	for attempt := 1; attempt <= pageRetryAttempts; attempt++ {
+		if err = fetch(); err == nil {
+			return nil
+		}
+
+		if !isTransientPageError(err) {
+			return err
+		}
+
+		if attempt == pageRetryAttempts {
+			break
+		}
+
+		githubactions.Infof("Failed to fetch page %d, retrying in %s: %s\n", page, pageRetryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pageRetryInterval):
+		}
+	}
+
+	return fmt.Errorf("failed to fetch page %d after %d attempts: %w", page, pageRetryAttempts, err)
+}
@@ -0,0 +1,85 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchPage(t *testing.T) {
+	ctx := context.Background()
+
+	retryInterval := pageRetryInterval
+	pageRetryInterval = time.Millisecond
+
+	t.Cleanup(func() {
+		pageRetryInterval = retryInterval
+	})
+
+	t.Run("retry a transient error", func(t *testing.T) {
+		calls := 0
+
+		err := fetchPage(ctx, 2, func() error {
+			calls++
+
+			if calls == 1 {
+				return errors.New("503 Service Unavailable")
+			}
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("return other errors without retrying", func(t *testing.T) {
+		calls := 0
+
+		err := fetchPage(ctx, 2, func() error {
+			calls++
+
+			return tfe.ErrUnauthorized
+		})
+
+		assert.ErrorIs(t, err, tfe.ErrUnauthorized)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stop retrying once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		calls := 0
+
+		err := fetchPage(ctx, 2, func() error {
+			calls++
+			cancel()
+
+			return errors.New("too many requests")
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestIsTransientPageError(t *testing.T) {
+	assert.True(t, isTransientPageError(errors.New("429 Too Many Requests")))
+	assert.True(t, isTransientPageError(errors.New("internal server error")))
+	assert.True(t, isTransientPageError(&url.Error{Op: "Get", URL: "https://app.terraform.io", Err: &timeoutError{}}))
+
+	assert.False(t, isTransientPageError(tfe.ErrResourceNotFound))
+	assert.False(t, isTransientPageError(errors.New("invalid attribute")))
+	assert.False(t, isTransientPageError(context.DeadlineExceeded))
+}
+
+// timeoutError is a network error of a timed out request
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,173 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// PlanGroup is a set of resources planned together, separately from the other groups of a parallel plan
+type PlanGroup struct {
+	Name    string
+	Targets []string
+}
+
+// ParallelPlanGroups returns a group per workspace with the resources of that workspace.
+// Managed resources in the state that belong to no configured workspace, such as the resources of a removed workspace, are returned in an additional group so their deletion is planned.
+func ParallelPlanGroups(workspaces []*Workspace, config *TargetOptions, state *tfjson.State) ([]PlanGroup, error) {
+	groups := []PlanGroup{}
+	covered := []string{}
+
+	for _, ws := range workspaces {
+		targets, err := WorkspaceTargets([]string{ws.Workspace}, workspaces, config)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, PlanGroup{Name: ws.Workspace, Targets: targets})
+		covered = append(covered, targets...)
+	}
+
+	if state == nil || state.Values == nil || state.Values.RootModule == nil {
+		return groups, nil
+	}
+
+	remaining := []string{}
+
+	for _, r := range state.Values.RootModule.Resources {
+		if r.Mode != tfjson.ManagedResourceMode || coveredAddress(r.Address, covered) {
+			continue
+		}
+
+		remaining = appendUnique(remaining, r.Address)
+	}
+
+	if len(remaining) > 0 {
+		groups = append(groups, PlanGroup{Name: "resources of removed workspaces", Targets: remaining})
+	}
+
+	return groups, nil
+}
+
+// coveredAddress returns true if the passed resource instance address is one of the targets, or an instance of a targeted resource
+func coveredAddress(address string, targets []string) bool {
+	for _, t := range targets {
+		if address == t || strings.HasPrefix(address, t+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// planGroupResult is the outcome of planning a single group
+type planGroupResult struct {
+	diff bool
+	raw  string
+	plan *tfjson.Plan
+	err  error
+}
+
+// ParallelPlan plans each group concurrently, saving each plan in its own directory below the working directory.
+// It returns whether any group has changes, the human readable plans of the groups with changes and the merged plan.
+// The state is not locked, so it must only be used with a local copy of the state.
+func ParallelPlan(ctx context.Context, tf *tfexec.Terraform, workDir string, groups []PlanGroup) (bool, string, *tfjson.Plan, error) {
+	results := make([]planGroupResult, len(groups))
+
+	var wg sync.WaitGroup
+
+	for i, group := range groups {
+		wg.Add(1)
+
+		go func(i int, group PlanGroup) {
+			defer wg.Done()
+
+			results[i] = planGroup(ctx, tf, path.Join(workDir, "parallel", strconv.Itoa(i)), group)
+		}(i, group)
+	}
+
+	wg.Wait()
+
+	diff := false
+	raw := []string{}
+	plans := []*tfjson.Plan{}
+
+	for i, r := range results {
+		if r.err != nil {
+			return false, "", nil, fmt.Errorf("failed to plan %q: %w", groups[i].Name, r.err)
+		}
+
+		if !r.diff {
+			continue
+		}
+
+		diff = true
+
+		raw = append(raw, fmt.Sprintf("# %s\n\n%s", groups[i].Name, r.raw))
+		plans = append(plans, r.plan)
+	}
+
+	return diff, strings.Join(raw, "\n"), MergePlans(plans), nil
+}
+
+// planGroup plans the resources of a single group into the passed directory
+func planGroup(ctx context.Context, tf *tfexec.Terraform, dir string, group PlanGroup) planGroupResult {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return planGroupResult{err: err}
+	}
+
+	planPath := path.Join(dir, "plan.txt")
+
+	opts := []tfexec.PlanOption{tfexec.Out(planPath), tfexec.Lock(false)}
+
+	for _, target := range group.Targets {
+		opts = append(opts, tfexec.Target(target))
+	}
+
+	diff, err := tf.Plan(ctx, opts...)
+	if err != nil || !diff {
+		return planGroupResult{err: err}
+	}
+
+	raw, err := tf.ShowPlanFileRaw(ctx, planPath)
+	if err != nil {
+		return planGroupResult{err: fmt.Errorf("failed to show plan: %w", err)}
+	}
+
+	plan, err := tf.ShowPlanFile(ctx, planPath)
+	if err != nil {
+		return planGroupResult{err: fmt.Errorf("failed to create plan struct: %w", err)}
+	}
+
+	return planGroupResult{diff: true, raw: raw, plan: plan}
+}
+
+// MergePlans merges plans of the same configuration into the first plan, keeping the first change of each resource address
+func MergePlans(plans []*tfjson.Plan) *tfjson.Plan {
+	if len(plans) == 0 {
+		return &tfjson.Plan{}
+	}
+
+	merged := *plans[0]
+	merged.ResourceChanges = nil
+
+	changes := map[string]bool{}
+
+	for _, p := range plans {
+		for _, rc := range p.ResourceChanges {
+			if !changes[rc.Address] {
+				changes[rc.Address] = true
+				merged.ResourceChanges = append(merged.ResourceChanges, rc)
+			}
+		}
+	}
+
+	return &merged
+}
@@ -0,0 +1,85 @@
+package action
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelPlanGroups(t *testing.T) {
+	workspaces := newTestMultiWorkspaceList()
+
+	config := &TargetOptions{
+		Variables: Variables{
+			{Key: "foo", Workspace: workspaces[0]},
+		},
+	}
+
+	t.Run("group the resources of each workspace", func(t *testing.T) {
+		groups, err := ParallelPlanGroups(workspaces, config, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []PlanGroup{
+			{Name: "staging", Targets: []string{"tfe_workspace.workspace[\"staging\"]", "tfe_variable.staging-foo"}},
+			{Name: "production", Targets: []string{"tfe_workspace.workspace[\"production\"]"}},
+		}, groups)
+	})
+
+	t.Run("group resources in the state that belong to no workspace", func(t *testing.T) {
+		state := &tfjson.State{
+			Values: &tfjson.StateValues{
+				RootModule: &tfjson.StateModule{
+					Resources: []*tfjson.StateResource{
+						{Address: "tfe_workspace.workspace[\"staging\"]", Mode: tfjson.ManagedResourceMode},
+						{Address: "tfe_workspace.workspace[\"development\"]", Mode: tfjson.ManagedResourceMode},
+						{Address: "tfe_variable.development-foo", Mode: tfjson.ManagedResourceMode},
+						{Address: "data.tfe_team.teams", Mode: tfjson.DataResourceMode},
+					},
+				},
+			},
+		}
+
+		groups, err := ParallelPlanGroups(workspaces, config, state)
+		require.NoError(t, err)
+
+		require.Len(t, groups, 3)
+		assert.Equal(t, PlanGroup{
+			Name:    "resources of removed workspaces",
+			Targets: []string{"tfe_workspace.workspace[\"development\"]", "tfe_variable.development-foo"},
+		}, groups[2])
+	})
+}
+
+func TestMergePlans(t *testing.T) {
+	t.Run("merge the resource changes of each plan once", func(t *testing.T) {
+		merged := MergePlans([]*tfjson.Plan{
+			{
+				TerraformVersion: "1.1.0",
+				ResourceChanges: []*tfjson.ResourceChange{
+					{Address: "tfe_workspace.workspace[\"staging\"]"},
+					{Address: "tfe_team_access.teams[\"staging-team\"]"},
+				},
+			},
+			{
+				TerraformVersion: "1.1.0",
+				ResourceChanges: []*tfjson.ResourceChange{
+					{Address: "tfe_workspace.workspace[\"production\"]"},
+					{Address: "tfe_team_access.teams[\"staging-team\"]"},
+				},
+			},
+		})
+
+		assert.Equal(t, "1.1.0", merged.TerraformVersion)
+		assert.Equal(t, []*tfjson.ResourceChange{
+			{Address: "tfe_workspace.workspace[\"staging\"]"},
+			{Address: "tfe_team_access.teams[\"staging-team\"]"},
+			{Address: "tfe_workspace.workspace[\"production\"]"},
+		}, merged.ResourceChanges)
+	})
+
+	t.Run("return an empty plan if no plan is passed", func(t *testing.T) {
+		assert.Equal(t, &tfjson.Plan{}, MergePlans(nil))
+	})
+}
@@ -0,0 +1,430 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ChangedResourceTypes returns the sorted, distinct resource types that have pending changes in the passed plan
+func ChangedResourceTypes(plan *tfjson.Plan) []string {
+	seen := map[string]bool{}
+	types := []string{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+
+		if !seen[rc.Type] {
+			seen[rc.Type] = true
+			types = append(types, rc.Type)
+		}
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+// DestroyedResourceTypes returns the sorted, distinct resource types that the passed plan deletes, including replacements
+func DestroyedResourceTypes(plan *tfjson.Plan) []string {
+	types := []string{}
+
+	for _, t := range ChangedResourceTypes(plan) {
+		if WillDestroy(plan, t) {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+// UnapprovedResourceTypes returns the changed resource types in the plan that are not in the passed allowlist
+func UnapprovedResourceTypes(plan *tfjson.Plan, allowed []string) []string {
+	unapproved := []string{}
+
+	for _, t := range ChangedResourceTypes(plan) {
+		approved := false
+
+		for _, a := range allowed {
+			if t == a {
+				approved = true
+				break
+			}
+		}
+
+		if !approved {
+			unapproved = append(unapproved, t)
+		}
+	}
+
+	return unapproved
+}
+
+// ReadPlanFile reads a JSON encoded plan, as set in the "plan_json" output, from the passed file path
+func ReadPlanFile(filePath string) (*tfjson.Plan, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan tfjson.Plan
+
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan %s: %w", filePath, err)
+	}
+
+	return &plan, nil
+}
+
+// planShower shows a binary plan file, implemented by *tfexec.Terraform
+type planShower interface {
+	ShowPlanFile(context.Context, string, ...tfexec.ShowOption) (*tfjson.Plan, error)
+}
+
+// binaryPlanSignature is the zip file signature at the start of binary plan files
+var binaryPlanSignature = []byte("PK\x03\x04")
+
+// ReadApprovedPlan reads a plan captured by an earlier run from the passed file path, or from the passed stdin if the path is "-". It only gates the apply of the plan of this run.
+// The JSON plan of the "plan_json" output and binary plan files are accepted, binary plans are shown by Terraform from the passed working directory.
+func ReadApprovedPlan(ctx context.Context, tf planShower, workDir string, source string, stdin io.Reader) (*tfjson.Plan, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	if source == "-" && stdin == nil {
+		return nil, fmt.Errorf("no stdin to read the plan from")
+	}
+
+	if source == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(source)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	if bytes.HasPrefix(b, binaryPlanSignature) {
+		planPath := path.Join(workDir, "approved.plan.txt")
+
+		if err = os.WriteFile(planPath, b, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write binary plan: %w", err)
+		}
+
+		plan, err := tf.ShowPlanFile(ctx, planPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to show binary plan: %w", err)
+		}
+
+		return plan, nil
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return nil, fmt.Errorf("unrecognized plan format, expected the JSON plan of the plan_json output or a binary plan file")
+	}
+
+	var plan tfjson.Plan
+
+	// decoding also validates the plan format version
+	if err = json.Unmarshal(b, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// pendingChanges returns the resource changes of the passed plan keyed by address, excluding no-op and read actions
+func pendingChanges(plan *tfjson.Plan) map[string]*tfjson.Change {
+	changes := map[string]*tfjson.Change{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+
+		changes[rc.Address] = rc.Change
+	}
+
+	return changes
+}
+
+// PlanChangedSinceBaseline returns true if the pending resource changes of the passed plan differ from those of the baseline plan
+func PlanChangedSinceBaseline(plan *tfjson.Plan, baseline *tfjson.Plan) bool {
+	return len(PlanDifferences(plan, baseline)) > 0
+}
+
+// PlanDifferences describes each resource whose pending change in the passed plan differs from its change in the baseline plan, sorted by address
+func PlanDifferences(plan *tfjson.Plan, baseline *tfjson.Plan) []string {
+	current := pendingChanges(plan)
+	previous := pendingChanges(baseline)
+
+	diffs := []string{}
+
+	for address, change := range current {
+		prev, ok := previous[address]
+
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s is now planned to %s", address, planActionName(change.Actions)))
+		case !reflect.DeepEqual(change.Actions, prev.Actions):
+			diffs = append(diffs, fmt.Sprintf("%s is now planned to %s instead of %s", address, planActionName(change.Actions), planActionName(prev.Actions)))
+		case !reflect.DeepEqual(change.After, prev.After):
+			diffs = append(diffs, fmt.Sprintf("%s has different planned values", address))
+		}
+	}
+
+	for address, prev := range previous {
+		if _, ok := current[address]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s is no longer planned to %s", address, planActionName(prev.Actions)))
+		}
+	}
+
+	sort.Strings(diffs)
+
+	return diffs
+}
+
+// planActionName returns a readable name of the passed change actions
+func planActionName(actions tfjson.Actions) string {
+	switch {
+	case actions.Create():
+		return "create"
+	case actions.Update():
+		return "update"
+	case actions.Delete():
+		return "delete"
+	case actions.Replace():
+		return "replace"
+	}
+
+	return fmt.Sprintf("%v", actions)
+}
+
+// TagChange describes the tags added to and removed from an existing workspace in a plan
+type TagChange struct {
+	Address string   `json:"address"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// tagNames returns the tag names of a tfe_workspace resource from a planned before or after value
+func tagNames(values interface{}) []string {
+	attrs, ok := values.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	list, ok := attrs["tag_names"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+
+	for _, v := range list {
+		if name, ok := v.(string); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// difference returns the items of a that are not in b
+func difference(a []string, b []string) []string {
+	diff := []string{}
+
+	for _, item := range a {
+		found := false
+
+		for _, other := range b {
+			if item == other {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			diff = append(diff, item)
+		}
+	}
+
+	sort.Strings(diff)
+
+	return diff
+}
+
+// WorkspaceTagChanges returns the tag changes planned for existing workspaces
+func WorkspaceTagChanges(plan *tfjson.Plan) []TagChange {
+	changes := []TagChange{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Type != "tfe_workspace" || rc.Change == nil || !rc.Change.Actions.Update() {
+			continue
+		}
+
+		before := tagNames(rc.Change.Before)
+		after := tagNames(rc.Change.After)
+
+		added := difference(after, before)
+		removed := difference(before, after)
+
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, TagChange{
+				Address: rc.Address,
+				Added:   added,
+				Removed: removed,
+			})
+		}
+	}
+
+	return changes
+}
+
+// planActionSymbol returns the symbol Terraform uses for the passed change actions in plan output
+func planActionSymbol(actions tfjson.Actions) string {
+	switch {
+	case actions.Replace():
+		return "-/+"
+	case actions.Create():
+		return "+"
+	case actions.Update():
+		return "~"
+	case actions.Delete():
+		return "-"
+	default:
+		return ""
+	}
+}
+
+// PlanSummary returns a condensed summary of the passed plan, with a line per changed resource such as `+ tfe_workspace.workspace["prod"]`
+func PlanSummary(plan *tfjson.Plan) string {
+	lines := []string{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+
+		if symbol := planActionSymbol(rc.Change.Actions); symbol != "" {
+			lines = append(lines, fmt.Sprintf("%s %s", symbol, rc.Address))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// WorkspacePlanChange is a pending resource change of a single workspace
+type WorkspacePlanChange struct {
+	Address string         `json:"address"`
+	Actions tfjson.Actions `json:"actions"`
+}
+
+// PerWorkspacePlan groups the pending resource changes of the passed plan by the key of the workspace they belong to, with an empty list for workspaces without changes
+func PerWorkspacePlan(plan *tfjson.Plan, workspaces []*Workspace) map[string][]WorkspacePlanChange {
+	changes := map[string][]WorkspacePlanChange{}
+
+	for _, ws := range workspaces {
+		changes[ws.Workspace] = []WorkspacePlanChange{}
+	}
+
+	resources := configResources(plan)
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+
+		ws := changeWorkspace(rc, workspaces, resources[rc.Type+"."+rc.Name])
+		if ws == nil {
+			continue
+		}
+
+		changes[ws.Workspace] = append(changes[ws.Workspace], WorkspacePlanChange{
+			Address: rc.Address,
+			Actions: rc.Change.Actions,
+		})
+	}
+
+	return changes
+}
+
+// changeWorkspace returns the workspace the passed resource change belongs to, the workspace whose key is its for_each key, or its resource name when it has none.
+// Otherwise the single workspace referenced by the configuration of a resource without for_each is returned, falling back to the longest workspace key followed by "-" that prefixes the key.
+func changeWorkspace(rc *tfjson.ResourceChange, workspaces []*Workspace, config *tfjson.ConfigResource) *Workspace {
+	if len(workspaces) == 1 {
+		return workspaces[0]
+	}
+
+	key, ok := rc.Index.(string)
+	if !ok {
+		key = rc.Name
+	}
+
+	for _, ws := range workspaces {
+		if key == ws.Workspace {
+			return ws
+		}
+	}
+
+	if rc.Index == nil && config != nil {
+		refs := expressionReferences(config.Expressions)
+		referenced := []*Workspace{}
+
+		for _, ws := range workspaces {
+			if referencesAny(refs, []string{ws.Address()}) {
+				referenced = append(referenced, ws)
+			}
+		}
+
+		if len(referenced) == 1 {
+			return referenced[0]
+		}
+	}
+
+	var match *Workspace
+
+	for _, ws := range workspaces {
+		if !strings.HasPrefix(key, ws.Workspace+"-") {
+			continue
+		}
+
+		if match == nil || len(ws.Workspace) > len(match.Workspace) {
+			match = ws
+		}
+	}
+
+	return match
+}
+
+// WriteStepSummary appends the passed markdown to the GitHub Actions job summary, if the runner supports it
+func WriteStepSummary(markdown string) error {
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, markdown)
+
+	return err
+}
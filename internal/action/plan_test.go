@@ -0,0 +1,400 @@
+package action
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPlan returns a plan with a single resource change per passed resource type and action
+func newTestPlan(changes map[string]tfjson.Action) *tfjson.Plan {
+	plan := &tfjson.Plan{}
+
+	for t, a := range changes {
+		plan.ResourceChanges = append(plan.ResourceChanges, &tfjson.ResourceChange{
+			Type:   t,
+			Change: &tfjson.Change{Actions: tfjson.Actions{a}},
+		})
+	}
+
+	return plan
+}
+
+func TestChangedResourceTypes(t *testing.T) {
+	t.Run("return changed resource types", func(t *testing.T) {
+		plan := newTestPlan(map[string]tfjson.Action{
+			"tfe_workspace":   tfjson.ActionUpdate,
+			"tfe_variable":    tfjson.ActionCreate,
+			"tfe_team_access": tfjson.ActionNoop,
+			"tfe_team":        tfjson.ActionRead,
+		})
+
+		assert.Equal(t, []string{"tfe_variable", "tfe_workspace"}, ChangedResourceTypes(plan))
+	})
+
+	t.Run("return an empty list when nothing changes", func(t *testing.T) {
+		plan := newTestPlan(map[string]tfjson.Action{
+			"tfe_workspace": tfjson.ActionNoop,
+		})
+
+		assert.Equal(t, []string{}, ChangedResourceTypes(plan))
+	})
+}
+
+func TestUnapprovedResourceTypes(t *testing.T) {
+	plan := newTestPlan(map[string]tfjson.Action{
+		"tfe_workspace":   tfjson.ActionUpdate,
+		"tfe_variable":    tfjson.ActionCreate,
+		"tfe_team_access": tfjson.ActionDelete,
+	})
+
+	t.Run("return changed types missing from the allowlist", func(t *testing.T) {
+		assert.Equal(t, []string{"tfe_workspace"}, UnapprovedResourceTypes(plan, []string{"tfe_variable", "tfe_team_access"}))
+	})
+
+	t.Run("return an empty list when all changed types are allowed", func(t *testing.T) {
+		assert.Equal(t, []string{}, UnapprovedResourceTypes(plan, []string{"tfe_variable", "tfe_team_access", "tfe_workspace"}))
+	})
+}
+
+func TestDestroyedResourceTypes(t *testing.T) {
+	t.Run("return the deleted and replaced types", func(t *testing.T) {
+		plan := &tfjson.Plan{
+			ResourceChanges: []*tfjson.ResourceChange{
+				{Type: "tfe_workspace", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+				{Type: "tfe_variable", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+				{Type: "tfe_team_access", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+			},
+		}
+
+		assert.Equal(t, []string{"tfe_team_access", "tfe_variable"}, DestroyedResourceTypes(plan))
+	})
+
+	t.Run("return an empty list for additive changes", func(t *testing.T) {
+		plan := newTestPlan(map[string]tfjson.Action{
+			"tfe_workspace": tfjson.ActionUpdate,
+			"tfe_variable":  tfjson.ActionCreate,
+		})
+
+		assert.Equal(t, []string{}, DestroyedResourceTypes(plan))
+	})
+}
+
+func TestReadPlanFile(t *testing.T) {
+	t.Run("read a JSON plan", func(t *testing.T) {
+		filePath := path.Join(t.TempDir(), "plan.json")
+
+		require.NoError(t, os.WriteFile(filePath, []byte(`{"format_version": "1.0", "resource_changes": [{"address": "tfe_workspace.workspace[\"default\"]", "type": "tfe_workspace", "change": {"actions": ["create"]}}]}`), 0644))
+
+		plan, err := ReadPlanFile(filePath)
+		require.NoError(t, err)
+
+		assert.Len(t, plan.ResourceChanges, 1)
+		assert.Equal(t, "tfe_workspace", plan.ResourceChanges[0].Type)
+	})
+
+	t.Run("error if the plan is not valid JSON", func(t *testing.T) {
+		filePath := path.Join(t.TempDir(), "plan.json")
+
+		require.NoError(t, os.WriteFile(filePath, []byte("foo"), 0644))
+
+		_, err := ReadPlanFile(filePath)
+		assert.Error(t, err)
+	})
+}
+
+// testPlanShower records the binary plan file it is asked to show
+type testPlanShower struct {
+	path string
+}
+
+func (s *testPlanShower) ShowPlanFile(ctx context.Context, planPath string, opts ...tfexec.ShowOption) (*tfjson.Plan, error) {
+	s.path = planPath
+
+	return newTestPlan(map[string]tfjson.Action{"tfe_variable": tfjson.ActionCreate}), nil
+}
+
+func TestReadApprovedPlan(t *testing.T) {
+	ctx := context.Background()
+	planJSON := `{"format_version": "1.0", "resource_changes": [{"address": "tfe_workspace.workspace[\"default\"]", "type": "tfe_workspace", "change": {"actions": ["create"]}}]}`
+
+	t.Run("read a JSON plan from stdin", func(t *testing.T) {
+		plan, err := ReadApprovedPlan(ctx, &testPlanShower{}, t.TempDir(), "-", strings.NewReader(planJSON))
+		require.NoError(t, err)
+
+		assert.Equal(t, "tfe_workspace", plan.ResourceChanges[0].Type)
+	})
+
+	t.Run("error reading stdin if none is passed", func(t *testing.T) {
+		_, err := ReadApprovedPlan(ctx, &testPlanShower{}, t.TempDir(), "-", nil)
+		assert.EqualError(t, err, "no stdin to read the plan from")
+	})
+
+	t.Run("read a JSON plan from a file", func(t *testing.T) {
+		filePath := path.Join(t.TempDir(), "plan.json")
+
+		require.NoError(t, os.WriteFile(filePath, []byte(planJSON), 0644))
+
+		plan, err := ReadApprovedPlan(ctx, &testPlanShower{}, t.TempDir(), filePath, nil)
+		require.NoError(t, err)
+
+		assert.Len(t, plan.ResourceChanges, 1)
+	})
+
+	t.Run("show a binary plan", func(t *testing.T) {
+		workDir := t.TempDir()
+		shower := &testPlanShower{}
+
+		plan, err := ReadApprovedPlan(ctx, shower, workDir, "-", strings.NewReader("PK\x03\x04binary"))
+		require.NoError(t, err)
+
+		assert.Equal(t, path.Join(workDir, "approved.plan.txt"), shower.path)
+		assert.Equal(t, "tfe_variable", plan.ResourceChanges[0].Type)
+	})
+
+	t.Run("error on an unsupported plan format version", func(t *testing.T) {
+		_, err := ReadApprovedPlan(ctx, &testPlanShower{}, t.TempDir(), "-", strings.NewReader(`{"format_version": "9.0"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("error on an unrecognized format", func(t *testing.T) {
+		_, err := ReadApprovedPlan(ctx, &testPlanShower{}, t.TempDir(), "-", strings.NewReader("Terraform will perform the following actions"))
+		assert.ErrorContains(t, err, "unrecognized plan format")
+	})
+}
+
+func TestPlanChangedSinceBaseline(t *testing.T) {
+	newPlan := func(value string) *tfjson.Plan {
+		return &tfjson.Plan{
+			ResourceChanges: []*tfjson.ResourceChange{
+				{
+					Address: "tfe_variable.default-foo",
+					Type:    "tfe_variable",
+					Change: &tfjson.Change{
+						Actions: tfjson.Actions{tfjson.ActionUpdate},
+						After:   map[string]interface{}{"value": value},
+					},
+				},
+				{
+					Address: "tfe_workspace.workspace[\"default\"]",
+					Type:    "tfe_workspace",
+					Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}},
+				},
+			},
+		}
+	}
+
+	t.Run("return false for matching changes", func(t *testing.T) {
+		assert.False(t, PlanChangedSinceBaseline(newPlan("bar"), newPlan("bar")))
+	})
+
+	t.Run("return true when a planned value differs", func(t *testing.T) {
+		assert.True(t, PlanChangedSinceBaseline(newPlan("baz"), newPlan("bar")))
+	})
+
+	t.Run("return true when the changes differ in resources", func(t *testing.T) {
+		assert.True(t, PlanChangedSinceBaseline(&tfjson.Plan{}, newPlan("bar")))
+	})
+
+	t.Run("ignore no-op changes", func(t *testing.T) {
+		assert.False(t, PlanChangedSinceBaseline(&tfjson.Plan{}, newTestPlan(map[string]tfjson.Action{
+			"tfe_workspace": tfjson.ActionNoop,
+		})))
+	})
+}
+
+func TestPlanDifferences(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_variable.default-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}, After: map[string]interface{}{"value": "baz"}}},
+			{Address: "tfe_variable.default-bar", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+			{Address: "tfe_variable.default-qux", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+		},
+	}
+
+	saved := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_variable.default-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}, After: map[string]interface{}{"value": "bar"}}},
+			{Address: "tfe_variable.default-bar", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+			{Address: "tfe_workspace.workspace[\"default\"]", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+		},
+	}
+
+	t.Run("describe each differing resource", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"tfe_variable.default-bar is now planned to delete instead of update",
+			"tfe_variable.default-foo has different planned values",
+			"tfe_variable.default-qux is now planned to create",
+			"tfe_workspace.workspace[\"default\"] is no longer planned to update",
+		}, PlanDifferences(plan, saved))
+	})
+
+	t.Run("return no differences for matching plans", func(t *testing.T) {
+		assert.Empty(t, PlanDifferences(saved, saved))
+	})
+}
+
+func TestWorkspaceTagChanges(t *testing.T) {
+	newTagPlan := func(actions tfjson.Actions, before []interface{}, after []interface{}) *tfjson.Plan {
+		return &tfjson.Plan{
+			ResourceChanges: []*tfjson.ResourceChange{
+				{
+					Address: "tfe_workspace.workspace[\"default\"]",
+					Type:    "tfe_workspace",
+					Change: &tfjson.Change{
+						Actions: actions,
+						Before:  map[string]interface{}{"tag_names": before},
+						After:   map[string]interface{}{"tag_names": after},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("return added and removed tags", func(t *testing.T) {
+		plan := newTagPlan(tfjson.Actions{tfjson.ActionUpdate}, []interface{}{"all", "staging"}, []interface{}{"all", "production"})
+
+		assert.Equal(t, []TagChange{{
+			Address: "tfe_workspace.workspace[\"default\"]",
+			Added:   []string{"production"},
+			Removed: []string{"staging"},
+		}}, WorkspaceTagChanges(plan))
+	})
+
+	t.Run("return removal of all tags", func(t *testing.T) {
+		plan := newTagPlan(tfjson.Actions{tfjson.ActionUpdate}, []interface{}{"all"}, []interface{}{})
+
+		assert.Equal(t, []TagChange{{
+			Address: "tfe_workspace.workspace[\"default\"]",
+			Added:   []string{},
+			Removed: []string{"all"},
+		}}, WorkspaceTagChanges(plan))
+	})
+
+	t.Run("ignore updates without tag changes", func(t *testing.T) {
+		plan := newTagPlan(tfjson.Actions{tfjson.ActionUpdate}, []interface{}{"all"}, []interface{}{"all"})
+
+		assert.Len(t, WorkspaceTagChanges(plan), 0)
+	})
+
+	t.Run("ignore tags of created workspaces", func(t *testing.T) {
+		plan := newTagPlan(tfjson.Actions{tfjson.ActionCreate}, nil, []interface{}{"all"})
+
+		assert.Len(t, WorkspaceTagChanges(plan), 0)
+	})
+}
+
+func TestPlanSummary(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_workspace.workspace[\"prod\"]", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+			{Address: "tfe_variable.prod-foo", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+			{Address: "tfe_variable.prod-bar", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+			{Address: "tfe_team_access.teams[\"prod-readers\"]", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+			{Address: "tfe_notification_configuration.prod", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+		},
+	}
+
+	assert.Equal(t, `+ tfe_workspace.workspace["prod"]
+~ tfe_variable.prod-foo
+- tfe_team_access.teams["prod-readers"]
+-/+ tfe_notification_configuration.prod`, PlanSummary(plan))
+}
+
+func TestPerWorkspacePlan(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: "foo-prod", Workspace: "prod"},
+		{Name: "foo-prod-eu", Workspace: "prod-eu"},
+		{Name: "foo-staging", Workspace: "staging"},
+	}
+
+	create := tfjson.Actions{tfjson.ActionCreate}
+	update := tfjson.Actions{tfjson.ActionUpdate}
+	replace := tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}
+
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_workspace.workspace[\"prod\"]", Type: "tfe_workspace", Name: "workspace", Index: "prod", Change: &tfjson.Change{Actions: create}},
+			{Address: "tfe_workspace.workspace[\"prod-eu\"]", Type: "tfe_workspace", Name: "workspace", Index: "prod-eu", Change: &tfjson.Change{Actions: create}},
+			{Address: "tfe_variable.prod-eu-foo", Type: "tfe_variable", Name: "prod-eu-foo", Change: &tfjson.Change{Actions: update}},
+			{Address: "tfe_variable.prod-bar", Type: "tfe_variable", Name: "prod-bar", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+			{Address: "tfe_team_access.teams[\"prod-readers\"]", Type: "tfe_team_access", Name: "teams", Index: "prod-readers", Change: &tfjson.Change{Actions: update}},
+			{Address: "tfe_notification_configuration.prod", Type: "tfe_notification_configuration", Name: "prod", Change: &tfjson.Change{Actions: replace}},
+		},
+	}
+
+	assert.Equal(t, map[string][]WorkspacePlanChange{
+		"prod": {
+			{Address: "tfe_workspace.workspace[\"prod\"]", Actions: create},
+			{Address: "tfe_team_access.teams[\"prod-readers\"]", Actions: update},
+			{Address: "tfe_notification_configuration.prod", Actions: replace},
+		},
+		"prod-eu": {
+			{Address: "tfe_workspace.workspace[\"prod-eu\"]", Actions: create},
+			{Address: "tfe_variable.prod-eu-foo", Actions: update},
+		},
+		"staging": {},
+	}, PerWorkspacePlan(plan, workspaces))
+
+	t.Run("assign a variable to the workspace it references over a longer matching key", func(t *testing.T) {
+		referenced := &tfjson.Plan{
+			ResourceChanges: []*tfjson.ResourceChange{
+				{Address: "tfe_variable.prod-eu-foo", Type: "tfe_variable", Name: "prod-eu-foo", Change: &tfjson.Change{Actions: create}},
+			},
+			Config: &tfjson.Config{
+				RootModule: &tfjson.ConfigModule{
+					Resources: []*tfjson.ConfigResource{
+						{
+							Address: "tfe_variable.prod-eu-foo",
+							Expressions: map[string]*tfjson.Expression{
+								"workspace_id": {ExpressionData: &tfjson.ExpressionData{References: []string{
+									`tfe_workspace.workspace["prod"].id`,
+									`tfe_workspace.workspace["prod"]`,
+									"tfe_workspace.workspace",
+								}}},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		assert.Equal(t, map[string][]WorkspacePlanChange{
+			"prod":    {{Address: "tfe_variable.prod-eu-foo", Actions: create}},
+			"prod-eu": {},
+			"staging": {},
+		}, PerWorkspacePlan(referenced, workspaces))
+	})
+
+	t.Run("assign every change to a single workspace", func(t *testing.T) {
+		single := &tfjson.Plan{
+			ResourceChanges: []*tfjson.ResourceChange{
+				{Address: "tfe_workspace.workspace", Type: "tfe_workspace", Name: "workspace", Change: &tfjson.Change{Actions: create}},
+			},
+		}
+
+		assert.Equal(t, map[string][]WorkspacePlanChange{
+			"prod": {{Address: "tfe_workspace.workspace", Actions: create}},
+		}, PerWorkspacePlan(single, workspaces[:1]))
+	})
+}
+
+func TestWriteStepSummary(t *testing.T) {
+	summaryPath := path.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryPath)
+
+	require.NoError(t, WriteStepSummary("foo"))
+	require.NoError(t, WriteStepSummary("bar"))
+
+	b, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo\nbar\n", string(b))
+}
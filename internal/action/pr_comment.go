@@ -0,0 +1,149 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// planCommentMarker identifies the plan comment of the action on a pull request, so later runs update it instead of adding comments
+const planCommentMarker = "<!-- terraform-cloud-workspace-action: %s -->"
+
+// GitHubPullRequestComments creates and updates comments on the pull requests of the repository running the action
+type GitHubPullRequestComments struct {
+	*GitHubClient
+}
+
+// maxPlanCommentSummary is the number of characters of the plan summary included in a plan comment, leaving room for the rest of the comment below the GitHub limit of 65536 characters
+const maxPlanCommentSummary = 65000
+
+// issueComment is the subset of a GitHub issue comment used to find an existing plan comment
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// NewGitHubPullRequestCommentsFromEnv configures a GitHubPullRequestComments client from the GitHub Actions environment
+func NewGitHubPullRequestCommentsFromEnv() (*GitHubPullRequestComments, error) {
+	c, err := NewGitHubClientFromEnv("comment on pull requests")
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitHubPullRequestComments{c}, nil
+}
+
+// PullRequestNumber returns the number of the pull request that triggered the workflow, or 0 if it was not triggered by a pull request event
+func PullRequestNumber() int {
+	if event := os.Getenv("GITHUB_EVENT_NAME"); event != "pull_request" && event != "pull_request_target" {
+		return 0
+	}
+
+	b, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		githubactions.Debugf("Failed to read the GitHub event payload: %s\n", err)
+		return 0
+	}
+
+	var event githubEvent
+
+	if err := json.Unmarshal(b, &event); err != nil {
+		githubactions.Debugf("Failed to decode the GitHub event payload: %s\n", err)
+		return 0
+	}
+
+	if event.PullRequest == nil {
+		return 0
+	}
+
+	return event.PullRequest.Number
+}
+
+// findComment returns the ID of the first comment of the pull request containing the passed marker, or 0 if there is none
+func (c *GitHubPullRequestComments) findComment(ctx context.Context, number int, marker string) (int64, error) {
+	for page := 1; ; page++ {
+		var comments []issueComment
+
+		if err := c.do(ctx, http.MethodGet, fmt.Sprintf("issues/%d/comments?per_page=%d&page=%d", number, maxPageSize, page), nil, http.StatusOK, &comments); err != nil {
+			return 0, err
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, marker) {
+				return comment.ID, nil
+			}
+		}
+
+		if len(comments) < maxPageSize {
+			return 0, nil
+		}
+	}
+}
+
+// UpsertPlanComment sets the passed body as the plan comment of the action with the passed name on the pull request, updating the existing comment if there is one
+func (c *GitHubPullRequestComments) UpsertPlanComment(ctx context.Context, number int, name string, body string) error {
+	marker := fmt.Sprintf(planCommentMarker, name)
+	body = fmt.Sprintf("%s\n%s", marker, body)
+
+	id, err := c.findComment(ctx, number, marker)
+	if err != nil {
+		return fmt.Errorf("failed to list pull request comments: %w", err)
+	}
+
+	if id != 0 {
+		if err = c.do(ctx, http.MethodPatch, fmt.Sprintf("issues/comments/%d", id), map[string]string{"body": body}, http.StatusOK, nil); err != nil {
+			return fmt.Errorf("failed to update pull request comment: %w", err)
+		}
+
+		return nil
+	}
+
+	if err = c.do(ctx, http.MethodPost, fmt.Sprintf("issues/%d/comments", number), map[string]string{"body": body}, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("failed to comment on pull request: %w", err)
+	}
+
+	return nil
+}
+
+// CommentPlanOnPullRequest posts the plan summary as a comment on the pull request that triggered the workflow, skipping other events
+func CommentPlanOnPullRequest(ctx context.Context, name string, summary string) error {
+	number := PullRequestNumber()
+	if number == 0 {
+		githubactions.Infof("Not running on a pull request event, skipping the plan comment\n")
+		return nil
+	}
+
+	comments, err := NewGitHubPullRequestCommentsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	body := "### Terraform Cloud workspace changes\n\nNo changes."
+	if summary != "" {
+		summary = truncatePlanSummary(summary, maxPlanCommentSummary)
+		body = fmt.Sprintf("### Terraform Cloud workspace changes\n\n```\n%s\n```", summary)
+	}
+
+	if err = comments.UpsertPlanComment(ctx, number, name, body); err != nil {
+		return err
+	}
+
+	githubactions.Infof("Commented the plan on pull request #%d\n", number)
+
+	return nil
+}
+
+// truncatePlanSummary cuts the passed summary to the passed number of characters, noting that the rest is omitted
+func truncatePlanSummary(summary string, max int) string {
+	runes := []rune(summary)
+	if len(runes) <= max {
+		return summary
+	}
+
+	return fmt.Sprintf("%s\n... (truncated, the full plan is in the plan_summary output)", string(runes[:max]))
+}
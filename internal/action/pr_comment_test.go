@@ -0,0 +1,99 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestNumber(t *testing.T) {
+	eventPath := path.Join(t.TempDir(), "event.json")
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 12, "title": "Add workspace"}}`), 0644))
+
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+
+	t.Run("read the number of the pull request", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+
+		assert.Equal(t, 12, PullRequestNumber())
+	})
+
+	t.Run("return 0 for other events", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "push")
+
+		assert.Equal(t, 0, PullRequestNumber())
+	})
+}
+
+func TestUpsertPlanComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create a comment when the pull request has none", func(t *testing.T) {
+		var created string
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/org/repo/issues/12/comments", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				testServerResHandler(t, 200, `[{"id": 1, "body": "looks good"}]`)(w, r)
+				return
+			}
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			created = body["body"]
+
+			testServerResHandler(t, 201, `{}`)(w, r)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		c := &GitHubPullRequestComments{&GitHubClient{APIURL: server.URL, Token: "token", Repository: "org/repo"}}
+
+		require.NoError(t, c.UpsertPlanComment(ctx, 12, "foo", "changes"))
+		assert.Equal(t, "<!-- terraform-cloud-workspace-action: foo -->\nchanges", created)
+	})
+
+	t.Run("update the comment of an earlier run", func(t *testing.T) {
+		var updated string
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/org/repo/issues/12/comments", testServerResHandler(t, 200, `[{"id": 1, "body": "looks good"}, {"id": 2, "body": "<!-- terraform-cloud-workspace-action: foo -->\nold changes"}]`))
+		mux.HandleFunc("/repos/org/repo/issues/comments/2", func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPatch, r.Method)
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			updated = body["body"]
+
+			testServerResHandler(t, 200, `{}`)(w, r)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		c := &GitHubPullRequestComments{&GitHubClient{APIURL: server.URL, Token: "token", Repository: "org/repo"}}
+
+		require.NoError(t, c.UpsertPlanComment(ctx, 12, "foo", "new changes"))
+		assert.Equal(t, "<!-- terraform-cloud-workspace-action: foo -->\nnew changes", updated)
+	})
+}
+
+func TestTruncatePlanSummary(t *testing.T) {
+	t.Run("keep a summary within the limit", func(t *testing.T) {
+		assert.Equal(t, "ü changes", truncatePlanSummary("ü changes", 9))
+	})
+
+	t.Run("truncate a summary over the limit", func(t *testing.T) {
+		assert.Equal(t, "ü c\n... (truncated, the full plan is in the plan_summary output)", truncatePlanSummary("ü changes", 3))
+	})
+}
@@ -0,0 +1,109 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// LiveDifferences compares the desired workspace settings and variables against the live workspaces and describes each difference found.
+// The comparison is advisory, settings that are not set are not compared and sensitive variables are always reported as they cannot be read back.
+// The values of raw and HCL variables are not compared, as the live value is the rendered expression rather than the desired one.
+func LiveDifferences(ctx context.Context, client *tfe.Client, workspaces []*Workspace, config *WorkspaceResourceOptions, variables Variables) ([]string, error) {
+	diffs := []string{}
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			diffs = append(diffs, fmt.Sprintf("workspace %q does not exist", ws.Name))
+			continue
+		}
+
+		live, err := client.Workspaces.ReadByID(ctx, *ws.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+		}
+
+		diffs = append(diffs, workspaceSettingDifferences(ws, live, config)...)
+
+		liveVars, err := FetchRelatedVariables(ctx, client, ws)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch variables of workspace %q: %w", ws.Name, err)
+		}
+
+		diffs = append(diffs, variableDifferences(ws, liveVars, variables)...)
+	}
+
+	return diffs, nil
+}
+
+// workspaceSettingDifferences describes the settings of the live workspace that differ from the desired ones
+func workspaceSettingDifferences(ws *Workspace, live *tfe.Workspace, config *WorkspaceResourceOptions) []string {
+	diffs := []string{}
+
+	compare := func(name string, desired, actual interface{}) {
+		if desired != actual {
+			diffs = append(diffs, fmt.Sprintf("workspace %q setting %s is %v, expected %v", ws.Name, name, actual, desired))
+		}
+	}
+
+	compareBool := func(name string, desired *bool, actual bool) {
+		if desired != nil {
+			compare(name, *desired, actual)
+		}
+	}
+
+	compareString := func(name string, desired, actual string) {
+		if desired != "" {
+			compare(name, desired, actual)
+		}
+	}
+
+	config = config.ForWorkspace(ws.Workspace)
+
+	compareBool("auto_apply", config.AutoApply, live.AutoApply)
+	compareBool("file_triggers_enabled", config.FileTriggersEnabled, live.FileTriggersEnabled)
+	compareBool("global_remote_state", config.GlobalRemoteState, live.GlobalRemoteState)
+	compareBool("queue_all_runs", config.QueueAllRuns, live.QueueAllRuns)
+	compareBool("speculative_enabled", config.SpeculativeEnabled, live.SpeculativeEnabled)
+	compareBool("structured_run_output_enabled", config.StructuredRunOutputEnabled, live.StructuredRunOutputEnabled)
+	compareString("description", config.Description, live.Description)
+	compareString("execution_mode", config.ExecutionMode, live.ExecutionMode)
+	compareString("terraform_version", config.TerraformVersion, live.TerraformVersion)
+	compareString("working_directory", config.WorkingDirectory, live.WorkingDirectory)
+
+	return diffs
+}
+
+// variableDifferences describes the desired variables of the workspace that are missing from or differ in the live variables
+func variableDifferences(ws *Workspace, live []*tfe.Variable, variables Variables) []string {
+	diffs := []string{}
+
+	for _, v := range variables {
+		if v.Workspace != ws {
+			continue
+		}
+
+		var match *tfe.Variable
+
+		for _, lv := range live {
+			if lv.Key == v.Key && string(lv.Category) == v.Category {
+				match = lv
+				break
+			}
+		}
+
+		switch {
+		case match == nil:
+			diffs = append(diffs, fmt.Sprintf("workspace %q variable %q does not exist", ws.Name, v.Key))
+		case v.Sensitive || match.Sensitive:
+			diffs = append(diffs, fmt.Sprintf("workspace %q variable %q is sensitive and cannot be compared", ws.Name, v.Key))
+		case v.Raw || v.HCL:
+			continue
+		case match.Value != v.Value || match.Description != v.Description || match.HCL != v.HCL:
+			diffs = append(diffs, fmt.Sprintf("workspace %q variable %q differs", ws.Name, v.Key))
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,92 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveDifferences(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo-staging", "auto-apply": true, "terraform-version": "1.1.0", "execution-mode": "remote"}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123/vars", testServerResHandler(t, 200, `{"data": [
+		{"id": "var-1", "type": "vars", "attributes": {"key": "foo", "value": "bar", "category": "env"}},
+		{"id": "var-2", "type": "vars", "attributes": {"key": "secret", "value": "", "category": "env", "sensitive": true}},
+		{"id": "var-3", "type": "vars", "attributes": {"key": "vpc_id", "value": "vpc-abc123", "category": "terraform"}},
+		{"id": "var-4", "type": "vars", "attributes": {"key": "tags", "value": "{\"team\" = \"foo\"}", "category": "terraform", "hcl": true}}
+	]}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	newWorkspace := func() *Workspace {
+		return &Workspace{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")}
+	}
+
+	t.Run("return no differences when the live workspace matches", func(t *testing.T) {
+		ws := newWorkspace()
+
+		diffs, err := LiveDifferences(ctx, client, []*Workspace{ws}, &WorkspaceResourceOptions{
+			AutoApply:        boolPtr(true),
+			TerraformVersion: "1.1.0",
+		}, Variables{
+			{Key: "foo", Value: "bar", Category: "env", Workspace: ws},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("skip the values of raw and HCL variables", func(t *testing.T) {
+		ws := newWorkspace()
+
+		diffs, err := LiveDifferences(ctx, client, []*Workspace{ws}, &WorkspaceResourceOptions{}, Variables{
+			{Key: "vpc_id", Value: "${data.terraform_remote_state.network.outputs.vpc_id}", Category: "terraform", Raw: true, Workspace: ws},
+			{Key: "tags", Value: `{ team = "foo" }`, Category: "terraform", HCL: true, Workspace: ws},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("return differing settings and variables", func(t *testing.T) {
+		ws := newWorkspace()
+
+		diffs, err := LiveDifferences(ctx, client, []*Workspace{ws}, &WorkspaceResourceOptions{
+			AutoApply:        boolPtr(false),
+			TerraformVersion: "1.1.0",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {TerraformVersion: strPtr("1.2.0")},
+			},
+		}, Variables{
+			{Key: "foo", Value: "baz", Category: "env", Workspace: ws},
+			{Key: "secret", Value: "hunter2", Category: "env", Sensitive: true, Workspace: ws},
+			{Key: "new", Value: "value", Category: "terraform", Workspace: ws},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			`workspace "foo-staging" setting auto_apply is true, expected false`,
+			`workspace "foo-staging" setting terraform_version is 1.1.0, expected 1.2.0`,
+			`workspace "foo-staging" variable "foo" differs`,
+			`workspace "foo-staging" variable "secret" is sensitive and cannot be compared`,
+			`workspace "foo-staging" variable "new" does not exist`,
+		}, diffs)
+	})
+
+	t.Run("return a difference for workspaces that do not exist", func(t *testing.T) {
+		diffs, err := LiveDifferences(ctx, client, []*Workspace{{Name: "foo-production", Workspace: "production"}}, &WorkspaceResourceOptions{}, Variables{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{`workspace "foo-production" does not exist`}, diffs)
+	})
+}
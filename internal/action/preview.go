@@ -0,0 +1,137 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+)
+
+// previewNode is a line of the preview tree with its nested lines
+type previewNode struct {
+	label    string
+	children []*previewNode
+}
+
+// add appends a nested line to the node and returns it
+func (n *previewNode) add(format string, a ...interface{}) *previewNode {
+	child := &previewNode{label: fmt.Sprintf(format, a...)}
+	n.children = append(n.children, child)
+
+	return child
+}
+
+// render writes the nested lines of the node, each indented below its parent
+func (n *previewNode) render(sb *strings.Builder, prefix string) {
+	for i, child := range n.children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		sb.WriteString(prefix + branch + child.label + "\n")
+		child.render(sb, prefix+indent)
+	}
+}
+
+// PreviewTree describes the resolved workspaces with their settings, variables and team access as a human readable tree.
+// Sensitive values are taken from the resolved inputs, so they are already redacted.
+func PreviewTree(resolved *ResolvedInputs, options *WorkspaceResourceOptions) string {
+	root := &previewNode{}
+
+	for _, ws := range resolved.Workspaces {
+		wsNode := root.add("%s (workspace %q in organization %q)", ws.Name, ws.Workspace, ws.Organization)
+
+		settings := previewSettings(options.ForWorkspace(ws.Workspace))
+		if tags := options.Tags[ws.Workspace]; len(tags) > 0 {
+			settings = append(settings, fmt.Sprintf("tags: %v", tags))
+		}
+
+		if len(settings) > 0 {
+			settingsNode := wsNode.add("settings")
+
+			for _, s := range settings {
+				settingsNode.add("%s", s)
+			}
+		}
+
+		varsNode := &previewNode{label: "variables"}
+
+		for _, v := range resolved.Variables {
+			if v.Workspace != ws.Workspace {
+				continue
+			}
+
+			attrs := []string{v.Category}
+			if v.Sensitive {
+				attrs = append(attrs, "sensitive")
+			}
+
+			if v.HCL {
+				attrs = append(attrs, "hcl")
+			}
+
+			varsNode.add("%s = %s (%s)", v.Key, v.Value, strings.Join(attrs, ", "))
+		}
+
+		if len(varsNode.children) > 0 {
+			wsNode.children = append(wsNode.children, varsNode)
+		}
+
+		accessNode := &previewNode{label: "team access"}
+
+		for _, ta := range resolved.TeamAccess {
+			if ta.Workspace != ws.Workspace {
+				continue
+			}
+
+			access := ta.Access
+			if access == "" {
+				access = "custom"
+			}
+
+			accessNode.add("%s: %s", ta.TeamName, access)
+		}
+
+		if len(accessNode.children) > 0 {
+			wsNode.children = append(wsNode.children, accessNode)
+		}
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d workspaces on %s\n", len(resolved.Workspaces), resolved.Host))
+	root.render(&sb, "")
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// previewSettings lists the settings of a workspace that are set
+func previewSettings(config *WorkspaceResourceOptions) []string {
+	settings := []string{}
+
+	addBool := func(name string, value *bool) {
+		if value != nil {
+			settings = append(settings, fmt.Sprintf("%s: %t", name, *value))
+		}
+	}
+
+	addString := func(name string, value string) {
+		if value != "" {
+			settings = append(settings, fmt.Sprintf("%s: %s", name, value))
+		}
+	}
+
+	addBool("assessments_enabled", config.AssessmentsEnabled)
+	addBool("auto_apply", config.AutoApply)
+	addString("description", config.Description)
+	addString("execution_mode", config.ExecutionMode)
+	addBool("file_triggers_enabled", config.FileTriggersEnabled)
+	addBool("global_remote_state", config.GlobalRemoteState)
+	addBool("queue_all_runs", config.QueueAllRuns)
+	addBool("speculative_enabled", config.SpeculativeEnabled)
+	addBool("structured_run_output_enabled", config.StructuredRunOutputEnabled)
+	addString("terraform_version", config.TerraformVersion)
+	addString("vcs_repo", config.VCSRepo)
+	addString("working_directory", config.WorkingDirectory)
+
+	return settings
+}
@@ -0,0 +1,50 @@
+package action
+
+import (
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewTree(t *testing.T) {
+	t.Run("describe the workspaces with their settings, variables and team access", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+
+		options := &WorkspaceResourceOptions{
+			AutoApply:     tfe.Bool(false),
+			ExecutionMode: "remote",
+			Tags:          map[string]Tags{"staging": {"all", "staging"}},
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"production": {AutoApply: tfe.Bool(true)},
+			},
+		}
+
+		variables := Variables{
+			{Key: "foo", Value: "bar", Category: "terraform", Workspace: workspaces[0]},
+			{Key: "secret", Value: "hunter2", Category: "env", Sensitive: true, Workspace: workspaces[0]},
+		}
+
+		teamAccess := TeamAccess{
+			{TeamName: "admins", Access: "admin", Workspace: workspaces[1]},
+		}
+
+		resolved := NewResolvedInputs(&Inputs{Host: "app.terraform.io", Organization: "org"}, workspaces, variables, teamAccess, options, nil, nil)
+
+		assert.Equal(t, `2 workspaces on app.terraform.io
+├── foo-staging (workspace "staging" in organization "org")
+│   ├── settings
+│   │   ├── auto_apply: false
+│   │   ├── execution_mode: remote
+│   │   └── tags: [all staging]
+│   └── variables
+│       ├── foo = bar (terraform)
+│       └── secret = [REDACTED] (env, sensitive)
+└── foo-production (workspace "production" in organization "org")
+    ├── settings
+    │   ├── auto_apply: true
+    │   └── execution_mode: remote
+    └── team access
+        └── admins: admin`, PreviewTree(resolved, options))
+	})
+}
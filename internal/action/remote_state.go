@@ -0,0 +1,112 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+	"gopkg.in/yaml.v2"
+)
+
+// ParseRemoteStates decodes the YAML encoded remote state blocks from the passed file and inline input, expanding "${name}" in their backend configuration.
+// Inline blocks take precedence over blocks of the same name in the file.
+func ParseRemoteStates(inline string, filePath string, name string) (map[string]tfconfig.RemoteState, error) {
+	remoteStates := map[string]tfconfig.RemoteState{}
+
+	if filePath != "" {
+		b, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote states file: %w", err)
+		}
+
+		if err = yaml.Unmarshal(b, &remoteStates); err != nil {
+			return nil, fmt.Errorf("failed to decode remote states file %s: %w", filePath, err)
+		}
+	}
+
+	var inlineStates map[string]tfconfig.RemoteState
+
+	if err := yaml.Unmarshal([]byte(inline), &inlineStates); err != nil {
+		return nil, err
+	}
+
+	for k, rs := range inlineStates {
+		remoteStates[k] = rs
+	}
+
+	for k, rs := range remoteStates {
+		remoteStates[k] = rs.ExpandName(name)
+	}
+
+	names := make([]string, 0, len(remoteStates))
+	for k := range remoteStates {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for _, k := range names {
+		if ws := remoteStates[k].Config.Workspaces; ws != nil {
+			if err := ws.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid remote state %q: %w", k, err)
+			}
+		}
+	}
+
+	return remoteStates, nil
+}
+
+// MissingRemoteStates returns a warning for each remote state of the remote backend that reads a workspace that does not exist.
+// Remote states of other backends, on another host or selecting no single workspace cannot be checked and are skipped.
+func MissingRemoteStates(ctx context.Context, client *tfe.Client, remoteStates map[string]tfconfig.RemoteState, host string) ([]string, error) {
+	names := make([]string, 0, len(remoteStates))
+	for k := range remoteStates {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	missing := []string{}
+
+	for _, k := range names {
+		rs := remoteStates[k]
+
+		if rs.Backend != "remote" || rs.Config.Organization == "" || rs.Config.Workspaces == nil {
+			continue
+		}
+
+		hostname := rs.Config.Hostname
+		if hostname == "" {
+			hostname = "app.terraform.io"
+		}
+
+		if hostname != host {
+			continue
+		}
+
+		wsName := rs.Config.Workspaces.Name
+		if rs.Config.Workspaces.Prefix != "" {
+			if rs.Workspace == "" {
+				continue
+			}
+
+			wsName = rs.Config.Workspaces.Prefix + rs.Workspace
+		}
+
+		_, err := client.Workspaces.Read(ctx, rs.Config.Organization, wsName)
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			missing = append(missing, fmt.Sprintf("remote state %q reads workspace %q in organization %q, which does not exist or cannot be read with the token", k, wsName, rs.Config.Organization))
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace %q of remote state %q: %w", wsName, k, err)
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,183 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+)
+
+func TestParseRemoteStates(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "remote_states.yml")
+
+	require.NoError(t, os.WriteFile(filePath, []byte(`
+network:
+  backend: remote
+  config:
+    hostname: app.terraform.io
+    organization: org
+    workspaces:
+      name: ${name}-network
+shared:
+  backend: s3
+  config:
+    bucket: file-bucket
+    key: terraform.tfstate
+`), 0644))
+
+	t.Run("merge the file with inline blocks and expand the name", func(t *testing.T) {
+		remoteStates, err := ParseRemoteStates(`
+shared:
+  backend: s3
+  config:
+    bucket: inline-bucket
+    key: ${name}/terraform.tfstate
+`, filePath, "foo")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]tfconfig.RemoteState{
+			"network": {
+				Backend: "remote",
+				Config: tfconfig.RemoteStateBackendConfig{
+					Hostname:     "app.terraform.io",
+					Organization: "org",
+					Workspaces:   &tfconfig.RemoteStateBackendConfigWorkspaces{Name: "foo-network"},
+				},
+			},
+			"shared": {
+				Backend: "s3",
+				Config: tfconfig.RemoteStateBackendConfig{
+					Bucket: "inline-bucket",
+					Key:    "foo/terraform.tfstate",
+				},
+			},
+		}, remoteStates)
+	})
+
+	t.Run("select workspaces by prefix", func(t *testing.T) {
+		remoteStates, err := ParseRemoteStates(`
+app:
+  backend: remote
+  workspace: production
+  config:
+    organization: org
+    workspaces:
+      prefix: ${name}-
+`, "", "foo")
+		require.NoError(t, err)
+
+		assert.Equal(t, tfconfig.RemoteState{
+			Backend:   "remote",
+			Workspace: "production",
+			Config: tfconfig.RemoteStateBackendConfig{
+				Organization: "org",
+				Workspaces:   &tfconfig.RemoteStateBackendConfigWorkspaces{Prefix: "foo-"},
+			},
+		}, remoteStates["app"])
+	})
+
+	t.Run("error when both the workspace name and prefix are set", func(t *testing.T) {
+		_, err := ParseRemoteStates(`
+app:
+  backend: remote
+  config:
+    organization: org
+    workspaces:
+      name: foo
+      prefix: foo-
+`, "", "foo")
+
+		assert.EqualError(t, err, "invalid remote state \"app\": workspaces cannot set both name and prefix")
+	})
+
+	t.Run("decode cross account S3 settings", func(t *testing.T) {
+		remoteStates, err := ParseRemoteStates(`
+shared:
+  backend: s3
+  config:
+    bucket: bucket
+    key: terraform.tfstate
+    role_arn: arn:aws:iam::123456789012:role/${name}
+    profile: shared
+    dynamodb_table: locks
+`, "", "foo")
+		require.NoError(t, err)
+
+		assert.Equal(t, tfconfig.RemoteStateBackendConfig{
+			Bucket:        "bucket",
+			Key:           "terraform.tfstate",
+			RoleArn:       "arn:aws:iam::123456789012:role/foo",
+			Profile:       "shared",
+			DynamoDBTable: "locks",
+		}, remoteStates["shared"].Config)
+	})
+
+	t.Run("return an empty map without remote states", func(t *testing.T) {
+		remoteStates, err := ParseRemoteStates("", "", "foo")
+		require.NoError(t, err)
+
+		assert.Empty(t, remoteStates)
+	})
+
+	t.Run("error when the file does not exist", func(t *testing.T) {
+		_, err := ParseRemoteStates("", path.Join(t.TempDir(), "missing.yml"), "foo")
+		assert.ErrorContains(t, err, "failed to read remote states file")
+	})
+}
+
+func TestMissingRemoteStates(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/foo-network", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo-network"}}}`))
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/app-production", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("warn about remote states of missing workspaces", func(t *testing.T) {
+		missing, err := MissingRemoteStates(ctx, client, map[string]tfconfig.RemoteState{
+			"network": {
+				Backend: "remote",
+				Config: tfconfig.RemoteStateBackendConfig{
+					Organization: "org",
+					Workspaces:   &tfconfig.RemoteStateBackendConfigWorkspaces{Name: "foo-network"},
+				},
+			},
+			"app": {
+				Backend:   "remote",
+				Workspace: "production",
+				Config: tfconfig.RemoteStateBackendConfig{
+					Organization: "org",
+					Workspaces:   &tfconfig.RemoteStateBackendConfigWorkspaces{Prefix: "app-"},
+				},
+			},
+			"other-host": {
+				Backend: "remote",
+				Config: tfconfig.RemoteStateBackendConfig{
+					Hostname:     "tfe.example.com",
+					Organization: "org",
+					Workspaces:   &tfconfig.RemoteStateBackendConfigWorkspaces{Name: "missing"},
+				},
+			},
+			"shared": {
+				Backend: "s3",
+				Config:  tfconfig.RemoteStateBackendConfig{Bucket: "bucket", Key: "terraform.tfstate"},
+			},
+		}, "app.terraform.io")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"remote state \"app\" reads workspace \"app-production\" in organization \"org\", which does not exist or cannot be read with the token",
+		}, missing)
+	})
+}
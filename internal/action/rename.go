@@ -0,0 +1,129 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+)
+
+// WorkspaceRename pairs a workspace removed from the configuration with the configured workspace replacing it
+type WorkspaceRename struct {
+	From *Workspace
+	To   *Workspace
+}
+
+// ParseWorkspaceRenames takes a map of old to new workspace names and returns the matching renames.
+// An error is returned if a new name is not a configured workspace or an old name is still configured.
+func ParseWorkspaceRenames(renames map[string]string, workspaces []*Workspace, name string) ([]WorkspaceRename, error) {
+	olds := make([]string, 0, len(renames))
+	for old := range renames {
+		olds = append(olds, old)
+	}
+
+	sort.Strings(olds)
+
+	result := []WorkspaceRename{}
+
+	for _, old := range olds {
+		if FindWorkspace(workspaces, old) != nil {
+			return nil, fmt.Errorf("renamed workspace %q is still configured in the workspaces", old)
+		}
+
+		to := FindWorkspace(workspaces, renames[old])
+		if to == nil {
+			return nil, fmt.Errorf("new name %q of renamed workspace %q not found in the configured workspaces", renames[old], old)
+		}
+
+		result = append(result, WorkspaceRename{
+			From: &Workspace{
+				Name:         fmt.Sprintf("%s-%s", name, old),
+				Workspace:    old,
+				Organization: to.Organization,
+				Standalone:   to.Standalone,
+			},
+			To: to,
+		})
+	}
+
+	return result, nil
+}
+
+// ValidateWorkspaceRenames checks that each renamed workspace exists in Terraform Cloud and that its new name is free, returning the renames still to apply.
+// Renames whose old workspace no longer exists and whose new workspace does are already applied and skipped. The IDs of the configured workspaces must already be set.
+func ValidateWorkspaceRenames(ctx context.Context, client *tfe.Client, renames []WorkspaceRename, organization string) ([]WorkspaceRename, error) {
+	pending := []WorkspaceRename{}
+
+	for _, r := range renames {
+		ws, err := client.Workspaces.Read(ctx, workspaceOrganization(r.From, organization), r.From.Name)
+		if err != nil && !errors.Is(err, tfe.ErrResourceNotFound) {
+			return nil, err
+		}
+
+		if r.To.ID != nil {
+			if err == nil {
+				return nil, fmt.Errorf("cannot rename workspace %q, workspace %q already exists", r.From.Name, r.To.Name)
+			}
+
+			githubactions.Infof("Workspace %q is already renamed to %q, skipping rename\n", r.From.Name, r.To.Name)
+
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot rename workspace %q, workspace not found", r.From.Name)
+		}
+
+		r.From.ID = &ws.ID
+
+		pending = append(pending, r)
+	}
+
+	return pending, nil
+}
+
+// MovedBlocks returns the moved blocks relocating the renamed workspaces and their dependent resources to their new addresses.
+// Team access is keyed by team IDs only known during the plan, so it is recreated instead of moved.
+func MovedBlocks(renames []WorkspaceRename, config *TargetOptions) []tfconfig.Moved {
+	moved := []tfconfig.Moved{}
+
+	add := func(from string, to string) {
+		if from != to {
+			moved = append(moved, tfconfig.Moved{From: from, To: to})
+		}
+	}
+
+	for _, r := range renames {
+		add(r.From.Address(), r.To.Address())
+
+		for _, v := range config.Variables {
+			if v.Workspace.Workspace == r.To.Workspace {
+				add(fmt.Sprintf("tfe_variable.%s-%s", r.From.Workspace, v.Key), fmt.Sprintf("tfe_variable.%s-%s", r.To.Workspace, v.Key))
+			}
+		}
+
+		for _, rt := range config.RunTriggers {
+			if rt.Workspace.Workspace == r.To.Workspace {
+				from := targetAddress("tfe_run_trigger.trigger", fmt.Sprintf("%s-%s", r.From.Workspace, rt.sourceKey()))
+				to := targetAddress("tfe_run_trigger.trigger", rt.Key())
+
+				// the source ID is only known after apply, so the instance cannot be addressed
+				if from != "tfe_run_trigger.trigger" {
+					add(from, to)
+				}
+			}
+		}
+
+		for _, n := range config.Notifications {
+			if n.Workspace.Workspace == r.To.Workspace {
+				add(fmt.Sprintf("tfe_notification_configuration.%s", r.From.Workspace), fmt.Sprintf("tfe_notification_configuration.%s", r.To.Workspace))
+			}
+		}
+	}
+
+	return moved
+}
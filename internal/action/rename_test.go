@@ -0,0 +1,129 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
+)
+
+func TestParseWorkspaceRenames(t *testing.T) {
+	t.Run("pair the old workspace with the configured workspace", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+
+		renames, err := ParseWorkspaceRenames(map[string]string{"stage": "staging"}, workspaces, "foo")
+		require.NoError(t, err)
+
+		assert.Equal(t, []WorkspaceRename{
+			{From: &Workspace{Name: "foo-stage", Workspace: "stage"}, To: workspaces[0]},
+		}, renames)
+	})
+
+	t.Run("error when the new name is not configured", func(t *testing.T) {
+		_, err := ParseWorkspaceRenames(map[string]string{"stage": "qa"}, newTestMultiWorkspaceList(), "foo")
+		assert.EqualError(t, err, "new name \"qa\" of renamed workspace \"stage\" not found in the configured workspaces")
+	})
+
+	t.Run("error when the old name is still configured", func(t *testing.T) {
+		_, err := ParseWorkspaceRenames(map[string]string{"production": "staging"}, newTestMultiWorkspaceList(), "foo")
+		assert.EqualError(t, err, "renamed workspace \"production\" is still configured in the workspaces")
+	})
+}
+
+func TestValidateWorkspaceRenames(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/foo-stage", testServerResHandler(t, 200, `{"data": {"id": "ws-old123", "type": "workspaces", "attributes": {"name": "foo-stage"}}}`))
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/foo-qa", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("set the ID of the old workspace", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-stage", Workspace: "stage"}, To: &Workspace{Name: "foo-staging", Workspace: "staging"}},
+		}
+
+		pending, err := ValidateWorkspaceRenames(ctx, client, renames, "org")
+		require.NoError(t, err)
+
+		assert.Equal(t, renames, pending)
+		assert.Equal(t, tfe.String("ws-old123"), renames[0].From.ID)
+	})
+
+	t.Run("error when the old workspace does not exist", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-qa", Workspace: "qa"}, To: &Workspace{Name: "foo-staging", Workspace: "staging"}},
+		}
+
+		_, err := ValidateWorkspaceRenames(ctx, client, renames, "org")
+		assert.EqualError(t, err, "cannot rename workspace \"foo-qa\", workspace not found")
+	})
+
+	t.Run("skip renames that are already applied", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-qa", Workspace: "qa"}, To: &Workspace{Name: "foo-staging", Workspace: "staging", ID: tfe.String("ws-abc123")}},
+		}
+
+		pending, err := ValidateWorkspaceRenames(ctx, client, renames, "org")
+		require.NoError(t, err)
+
+		assert.Empty(t, pending)
+	})
+
+	t.Run("error when the new name is taken", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-stage", Workspace: "stage"}, To: &Workspace{Name: "foo-staging", Workspace: "staging", ID: tfe.String("ws-abc123")}},
+		}
+
+		_, err := ValidateWorkspaceRenames(ctx, client, renames, "org")
+		assert.EqualError(t, err, "cannot rename workspace \"foo-stage\", workspace \"foo-staging\" already exists")
+	})
+}
+
+func TestMovedBlocks(t *testing.T) {
+	workspaces := newTestMultiWorkspaceList()
+
+	config := &TargetOptions{
+		Variables: Variables{
+			{Key: "foo", Workspace: workspaces[0]},
+			{Key: "foo", Workspace: workspaces[1]},
+		},
+		RunTriggers: RunTriggers{
+			{SourceID: "ws-ghi789", Workspace: workspaces[0]},
+			{SourceID: "${tfe_workspace.workspace[\"production\"].id}", Workspace: workspaces[0]},
+		},
+		Notifications: []*Notification{
+			{Input: &NotificationInput{Name: "foo"}, Workspace: workspaces[0]},
+		},
+	}
+
+	t.Run("move the workspace and its dependent resources", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-stage", Workspace: "stage"}, To: workspaces[0]},
+		}
+
+		assert.Equal(t, []tfconfig.Moved{
+			{From: "tfe_workspace.workspace[\"stage\"]", To: "tfe_workspace.workspace[\"staging\"]"},
+			{From: "tfe_variable.stage-foo", To: "tfe_variable.staging-foo"},
+			{From: "tfe_run_trigger.trigger[\"stage-ws-ghi789\"]", To: "tfe_run_trigger.trigger[\"staging-ws-ghi789\"]"},
+			{From: "tfe_notification_configuration.stage", To: "tfe_notification_configuration.staging"},
+		}, MovedBlocks(renames, config))
+	})
+
+	t.Run("skip the workspace of a standalone rename", func(t *testing.T) {
+		renames := []WorkspaceRename{
+			{From: &Workspace{Name: "foo-stage", Workspace: "stage", Standalone: true}, To: &Workspace{Name: "foo-qa", Workspace: "qa", Standalone: true}},
+		}
+
+		assert.Equal(t, []tfconfig.Moved{}, MovedBlocks(renames, config))
+	})
+}
@@ -0,0 +1,101 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+const (
+	resultSuccess     = "success"
+	resultPlanBlocked = "plan_blocked"
+	resultError       = "error"
+)
+
+// ErrStateLocked is returned when the state is locked by another operation
+var ErrStateLocked = errors.New("state is locked by another operation")
+
+// PlanBlockedError is returned when the plan succeeded, but a safety check of the action prevents applying it
+type PlanBlockedError struct {
+	Reason string
+}
+
+func (e *PlanBlockedError) Error() string {
+	return e.Reason
+}
+
+// planBlocked returns a PlanBlockedError with the formatted reason
+func planBlocked(format string, a ...interface{}) error {
+	return &PlanBlockedError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// RunResult is the machine readable outcome of a run, as set in the "result_json" output
+type RunResult struct {
+	Status     string `json:"status"`
+	ErrorType  string `json:"error_type,omitempty"`
+	Error      string `json:"error,omitempty"`
+	HasChanges bool   `json:"has_changes"`
+}
+
+// NewRunResult returns the outcome of a run that returned the passed error.
+// A run that skipped the apply of its changes, or failed a safety check after planning, is blocked rather than failed.
+func NewRunResult(err error, hasChanges bool, applySkipped bool) RunResult {
+	result := RunResult{Status: resultSuccess, HasChanges: hasChanges}
+
+	var blocked *PlanBlockedError
+
+	switch {
+	case err == nil && applySkipped:
+		result.Status = resultPlanBlocked
+	case err == nil:
+	case errors.As(err, &blocked):
+		result.Status = resultPlanBlocked
+		result.ErrorType = ErrorType(err)
+		result.Error = err.Error()
+	default:
+		result.Status = resultError
+		result.ErrorType = ErrorType(err)
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// ErrorType classifies the passed error, so callers can decide whether to retry a failed run
+func ErrorType(err error) string {
+	var (
+		blocked   *PlanBlockedError
+		importErr *ImportError
+	)
+
+	switch {
+	case errors.As(err, &blocked):
+		return "plan_blocked"
+	case errors.Is(err, ErrStateLocked):
+		return "state_locked"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ErrNoWorkspaces):
+		return "configuration"
+	case errors.As(err, &importErr):
+		return "import"
+	case isTransientInitError(err):
+		return "transient"
+	default:
+		return "unknown"
+	}
+}
+
+// setResultOutput sets the "result_json" output to the passed result
+func setResultOutput(result RunResult) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		githubactions.Warningf("Failed to convert the run result to JSON: %s", err)
+		return
+	}
+
+	githubactions.SetOutput("result_json", string(b))
+}
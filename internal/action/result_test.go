@@ -0,0 +1,55 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunResult(t *testing.T) {
+	t.Run("succeed without an error", func(t *testing.T) {
+		assert.Equal(t, RunResult{Status: "success", HasChanges: true}, NewRunResult(nil, true, false))
+	})
+
+	t.Run("block a run that skipped the apply", func(t *testing.T) {
+		assert.Equal(t, RunResult{Status: "plan_blocked", HasChanges: true}, NewRunResult(nil, true, true))
+	})
+
+	t.Run("block a run that failed a safety check", func(t *testing.T) {
+		err := planBlocked("error: the plan creates %d workspaces", 3)
+
+		assert.Equal(t, RunResult{
+			Status:     "plan_blocked",
+			ErrorType:  "plan_blocked",
+			Error:      "error: the plan creates 3 workspaces",
+			HasChanges: true,
+		}, NewRunResult(err, true, false))
+	})
+
+	t.Run("fail with the classified error", func(t *testing.T) {
+		err := fmt.Errorf("failed to init: %w", fmt.Errorf("%w: lock info", ErrStateLocked))
+
+		assert.Equal(t, RunResult{
+			Status:    "error",
+			ErrorType: "state_locked",
+			Error:     "failed to init: state is locked by another operation: lock info",
+		}, NewRunResult(err, false, false))
+	})
+}
+
+func TestErrorType(t *testing.T) {
+	tests := map[string]error{
+		"import":        fmt.Errorf("failed to import resources: %w", &ImportError{Phase: "workspaces", Errors: []error{errors.New("boom")}}),
+		"transient":     errors.New("failed to init: Failed to query available provider packages: connection reset by peer"),
+		"canceled":      fmt.Errorf("failed to plan: %w", context.Canceled),
+		"configuration": fmt.Errorf("failed to parse workspaces: %w", ErrNoWorkspaces),
+		"unknown":       errors.New("invalid input"),
+	}
+
+	for want, err := range tests {
+		assert.Equal(t, want, ErrorType(err), err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// githubEvent holds the fields of the GitHub Actions event payload used to describe a run
+type githubEvent struct {
+	Action     string `json:"action"`
+	HeadCommit *struct {
+		Message string `json:"message"`
+	} `json:"head_commit"`
+	PullRequest *struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	} `json:"pull_request"`
+}
+
+// DefaultRunMessage returns the subject of the commit that triggered the workflow, or the pull request title for pull request events.
+// An empty string is returned if the event payload is not available.
+func DefaultRunMessage() string {
+	eventPath := os.Getenv("GITHUB_EVENT_PATH")
+	if eventPath == "" {
+		return ""
+	}
+
+	b, err := os.ReadFile(eventPath)
+	if err != nil {
+		githubactions.Debugf("Failed to read the GitHub event payload: %s\n", err)
+		return ""
+	}
+
+	var event githubEvent
+
+	if err := json.Unmarshal(b, &event); err != nil {
+		githubactions.Debugf("Failed to decode the GitHub event payload: %s\n", err)
+		return ""
+	}
+
+	if event.HeadCommit != nil {
+		return strings.TrimSpace(strings.SplitN(event.HeadCommit.Message, "\n", 2)[0])
+	}
+
+	if event.PullRequest != nil {
+		return event.PullRequest.Title
+	}
+
+	return ""
+}
+
+// runComment is the JSON:API request body of a run comment, which the go-tfe client does not support
+type runComment struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Body string `json:"body"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// AddRunMessage adds the message as a comment to the latest run of the passed workspace, if the run was created after the passed time.
+// Runs started by the Terraform CLI always carry a fixed message, so a comment is the only way to describe them.
+func AddRunMessage(ctx context.Context, client *tfe.Client, httpClient *http.Client, address string, token string, organization string, workspace string, message string, since time.Time) error {
+	ws, err := client.Workspaces.Read(ctx, organization, workspace)
+	if err != nil {
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			githubactions.Infof("Workspace %q not found, skipping run message\n", workspace)
+			return nil
+		}
+
+		return err
+	}
+
+	runs, err := client.Runs.List(ctx, ws.ID, tfe.RunListOptions{
+		ListOptions: tfe.ListOptions{
+			PageSize: 1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(runs.Items) == 0 || runs.Items[0].CreatedAt.Before(since) {
+		githubactions.Infof("No run was created in workspace %q, skipping run message\n", workspace)
+		return nil
+	}
+
+	comment := runComment{}
+	comment.Data.Type = "comments"
+	comment.Data.Attributes.Body = message
+
+	b, err := json.Marshal(comment)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/v2/runs/%s/comments", address, runs.Items[0].ID), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to comment on run %q: %s", runs.Items[0].ID, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRunMessage(t *testing.T) {
+	writeEvent := func(t *testing.T, event string) {
+		eventPath := path.Join(t.TempDir(), "event.json")
+		require.NoError(t, os.WriteFile(eventPath, []byte(event), 0644))
+
+		t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	}
+
+	t.Run("return the subject of the head commit", func(t *testing.T) {
+		writeEvent(t, `{"head_commit": {"message": "Add staging workspace\n\nWith a longer body"}}`)
+
+		assert.Equal(t, "Add staging workspace", DefaultRunMessage())
+	})
+
+	t.Run("return the pull request title", func(t *testing.T) {
+		writeEvent(t, `{"pull_request": {"title": "Add production workspace"}}`)
+
+		assert.Equal(t, "Add production workspace", DefaultRunMessage())
+	})
+
+	t.Run("return an empty message without an event payload", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_PATH", "")
+
+		assert.Equal(t, "", DefaultRunMessage())
+	})
+}
+
+func TestAddRunMessage(t *testing.T) {
+	ctx := context.Background()
+	since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newServer := func(t *testing.T, createdAt string, comments *[]string) *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/organizations/org/workspaces/state", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "state"}}}`))
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/runs", testServerResHandler(t, 200, `{"data": [{"id": "run-abc123", "type": "runs", "attributes": {"created-at": "`+createdAt+`"}}]}`))
+		mux.HandleFunc("/api/v2/runs/run-abc123/comments", func(w http.ResponseWriter, r *http.Request) {
+			var comment runComment
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+			assert.Equal(t, "Bearer 12345", r.Header.Get("Authorization"))
+
+			*comments = append(*comments, comment.Data.Attributes.Body)
+
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		return httptest.NewServer(mux)
+	}
+
+	t.Run("comment on a run created after the passed time", func(t *testing.T) {
+		comments := []string{}
+
+		server := newServer(t, "2022-01-01T00:05:00Z", &comments)
+		defer server.Close()
+
+		err := AddRunMessage(ctx, newTestTFClient(t, server.URL), server.Client(), server.URL, "12345", "org", "state", "Add staging workspace", since)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Add staging workspace"}, comments)
+	})
+
+	t.Run("skip runs created before the passed time", func(t *testing.T) {
+		comments := []string{}
+
+		server := newServer(t, "2021-12-31T23:55:00Z", &comments)
+		defer server.Close()
+
+		err := AddRunMessage(ctx, newTestTFClient(t, server.URL), server.Client(), server.URL, "12345", "org", "state", "Add staging workspace", since)
+		require.NoError(t, err)
+
+		assert.Empty(t, comments)
+	})
+}
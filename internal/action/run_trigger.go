@@ -20,6 +20,8 @@ type RunTrigger struct {
 	SourceID     string
 	Workspace    *Workspace
 	WorkspaceRef map[string]tfeprovider.DataWorkspace
+	// Source is the source workspace if it is managed by the action, referenced so it is created before the trigger
+	Source *Workspace
 }
 
 type RunTriggers []RunTrigger
@@ -38,7 +40,8 @@ func (rt *RunTriggerInput) ToRunTrigger(target *Workspace, workspaces []*Workspa
 	} else if rt.SourceName != "" {
 		for _, ws := range workspaces {
 			if ws.Name == rt.SourceName {
-				trigger.SourceID = fmt.Sprintf("${tfe_workspace.workspace[%q].id}", ws.Workspace)
+				trigger.SourceID = ws.IDRef()
+				trigger.Source = ws
 			}
 		}
 
@@ -46,7 +49,7 @@ func (rt *RunTriggerInput) ToRunTrigger(target *Workspace, workspaces []*Workspa
 			trigger.WorkspaceRef = map[string]tfeprovider.DataWorkspace{
 				rt.SourceName: {
 					Name:         rt.SourceName,
-					Organization: organization,
+					Organization: workspaceOrganization(target, organization),
 				},
 			}
 
@@ -93,10 +96,51 @@ func MergeRunTriggers(inputs RunTriggerInputs, workspaceInputs map[string]RunTri
 	return triggers, nil
 }
 
+// Key returns the for_each key of the run trigger, made of the workspace and the source workspace ID.
+// The ID of a source workspace created in the same run is unknown during the plan, so its name is used instead.
+func (t RunTrigger) Key() string {
+	return fmt.Sprintf("%s-%s", t.Workspace.Workspace, t.sourceKey())
+}
+
+// sourceKey returns the part of the for_each key identifying the source workspace
+func (t RunTrigger) sourceKey() string {
+	if t.Source != nil {
+		if t.Source.ID == nil {
+			return t.Source.Workspace
+		}
+
+		return *t.Source.ID
+	}
+
+	return t.SourceID
+}
+
+// RunTriggerMovedBlocks returns the moved blocks relocating the triggers keyed by the name of their source workspace, when it was created by an earlier run, to the key of its ID.
+// Triggers of renamed workspaces are skipped, as they are already moved to the same address.
+func RunTriggerMovedBlocks(triggers RunTriggers, renames []WorkspaceRename) []tfconfig.Moved {
+	moved := []tfconfig.Moved{}
+
+	renamed := map[string]bool{}
+	for _, r := range renames {
+		renamed[r.To.Workspace] = true
+	}
+
+	for _, t := range triggers {
+		if t.Source != nil && t.Source.ID != nil && !renamed[t.Workspace.Workspace] {
+			moved = append(moved, tfconfig.Moved{
+				From: fmt.Sprintf("tfe_run_trigger.trigger[%q]", fmt.Sprintf("%s-%s", t.Workspace.Workspace, t.Source.Workspace)),
+				To:   fmt.Sprintf("tfe_run_trigger.trigger[%q]", t.Key()),
+			})
+		}
+	}
+
+	return moved
+}
+
 // ToResource returns a tfeprovider.RunTrigger object from the calling RunTrigger object
 func (t RunTrigger) ToResource() *tfeprovider.RunTrigger {
 	return &tfeprovider.RunTrigger{
-		WorkspaceID:  fmt.Sprintf("${tfe_workspace.workspace[%q].id}", t.Workspace.Workspace),
+		WorkspaceID:  t.Workspace.IDRef(),
 		SourceableID: t.SourceID,
 	}
 }
@@ -118,7 +162,7 @@ func AppendRunTriggers(module *tfconfig.Module, triggers RunTriggers) {
 			}
 		}
 
-		triggerForEach[fmt.Sprintf("%s-%s", t.Workspace.Workspace, t.SourceID)] = *t.ToResource()
+		triggerForEach[t.Key()] = *t.ToResource()
 	}
 
 	if len(wsDataForEach) > 0 {
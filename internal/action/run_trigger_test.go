@@ -6,7 +6,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	tfe "github.com/hashicorp/go-tfe"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
 )
 
@@ -53,6 +56,7 @@ func TestMergeRunTriggers(t *testing.T) {
 				SourceID:     "${tfe_workspace.workspace[\"default\"].id}",
 				Workspace:    workspaces[0],
 				WorkspaceRef: (map[string]tfeprovider.DataWorkspace)(nil),
+				Source:       workspaces[0],
 			}}, triggers)
 	})
 
@@ -237,6 +241,41 @@ func TestAppendRunTriggers(t *testing.T) {
 	})
 }
 
+func TestRunTriggerUpstreamWorkspace(t *testing.T) {
+	upstream := &Workspace{Name: "foo-network", Workspace: "network"}
+	downstream := &Workspace{Name: "foo-app", Workspace: "app", ID: tfe.String("ws-abc123")}
+	workspaces := []*Workspace{upstream, downstream}
+
+	triggers, err := MergeRunTriggers(RunTriggerInputs{}, map[string]RunTriggerInputs{
+		"app": {{SourceName: "foo-network"}},
+	}, workspaces, "org")
+	require.NoError(t, err)
+
+	t.Run("reference the upstream workspace created in the same run", func(t *testing.T) {
+		module := NewModule()
+
+		AppendRunTriggers(module, triggers)
+
+		// the key is known during the plan, and the reference orders the trigger after the upstream workspace
+		assert.Equal(t, map[string]tfeprovider.RunTrigger{
+			"app-network": {
+				SourceableID: "${tfe_workspace.workspace[\"network\"].id}",
+				WorkspaceID:  "${tfe_workspace.workspace[\"app\"].id}",
+			},
+		}, module.Resources["tfe_run_trigger"]["trigger"].(tfeprovider.RunTrigger).ForEach)
+		assert.Empty(t, RunTriggerMovedBlocks(triggers, nil))
+	})
+
+	t.Run("move the trigger once the upstream workspace exists", func(t *testing.T) {
+		upstream.ID = tfe.String("ws-def456")
+
+		assert.Equal(t, "app-ws-def456", triggers[0].Key())
+		assert.Equal(t, []tfconfig.Moved{
+			{From: "tfe_run_trigger.trigger[\"app-network\"]", To: "tfe_run_trigger.trigger[\"app-ws-def456\"]"},
+		}, RunTriggerMovedBlocks(triggers, nil))
+	})
+}
+
 func TestToRunTriggers(t *testing.T) {
 	t.Run("get a list of RunTriggers", func(t *testing.T) {
 		ctx := context.Background()
@@ -0,0 +1,167 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// SARIFReport is a SARIF 2.1.0 log of the destructive changes in a plan
+type SARIFReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name  string      `json:"name"`
+	Rules []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+	DefaultLevel     SARIFLevel   `json:"defaultConfiguration"`
+}
+
+type SARIFLevel struct {
+	Level string `json:"level"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifRules are the rules reported for deleted resources, by resource type. Other resource types use the "resource-deletion" rule.
+var sarifRules = []SARIFRule{
+	{ID: "workspace-deletion", ShortDescription: SARIFMessage{Text: "Terraform Cloud workspace is deleted, including all of its state versions"}, DefaultLevel: SARIFLevel{Level: "error"}},
+	{ID: "variable-deletion", ShortDescription: SARIFMessage{Text: "Terraform Cloud workspace variable is deleted"}, DefaultLevel: SARIFLevel{Level: "warning"}},
+	{ID: "resource-deletion", ShortDescription: SARIFMessage{Text: "Terraform Cloud resource is deleted"}, DefaultLevel: SARIFLevel{Level: "note"}},
+}
+
+// sarifRule returns the rule matching a deleted resource of the passed type
+func sarifRule(resourceType string) SARIFRule {
+	switch resourceType {
+	case "tfe_workspace":
+		return sarifRules[0]
+	case "tfe_variable":
+		return sarifRules[1]
+	default:
+		return sarifRules[2]
+	}
+}
+
+// sarifArtifactURI returns the repository path of the running workflow file, read from "GITHUB_WORKFLOW_REF", or an empty string if unknown
+func sarifArtifactURI() string {
+	ref := os.Getenv("GITHUB_WORKFLOW_REF")
+
+	i := strings.Index(ref, ".github/")
+	if i < 0 {
+		return ""
+	}
+
+	return strings.SplitN(ref[i:], "@", 2)[0]
+}
+
+// NewSARIFReport creates a SARIF report with a result for each resource deleted or replaced in the passed plan
+func NewSARIFReport(plan *tfjson.Plan) *SARIFReport {
+	results := []SARIFResult{}
+	uri := sarifArtifactURI()
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || !(rc.Change.Actions.Delete() || rc.Change.Actions.Replace()) {
+			continue
+		}
+
+		rule := sarifRule(rc.Type)
+
+		verb := "deleted"
+		if rc.Change.Actions.Replace() {
+			verb = "replaced"
+		}
+
+		location := SARIFLocation{
+			LogicalLocations: []SARIFLogicalLocation{
+				{FullyQualifiedName: rc.Address, Kind: "resource"},
+			},
+		}
+
+		if uri != "" {
+			location.PhysicalLocation = &SARIFPhysicalLocation{
+				ArtifactLocation: SARIFArtifactLocation{URI: uri},
+			}
+		}
+
+		results = append(results, SARIFResult{
+			RuleID:    rule.ID,
+			Level:     rule.DefaultLevel.Level,
+			Message:   SARIFMessage{Text: fmt.Sprintf("%s will be %s", rc.Address, verb)},
+			Locations: []SARIFLocation{location},
+		})
+	}
+
+	return &SARIFReport{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []SARIFRun{
+			{
+				Tool: SARIFTool{
+					Driver: SARIFDriver{
+						Name:  "terraform-cloud-workspace-action",
+						Rules: sarifRules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// WriteSARIFReport writes a SARIF report of the destructive changes in the passed plan to the passed file path
+func WriteSARIFReport(plan *tfjson.Plan, filePath string) error {
+	b, err := json.MarshalIndent(NewSARIFReport(plan), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, b, 0644)
+}
@@ -0,0 +1,75 @@
+package action
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSARIFReport(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "tfe_workspace.workspace[\"staging\"]", Type: "tfe_workspace", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+			{Address: "tfe_variable.staging-foo", Type: "tfe_variable", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+			{Address: "tfe_variable.staging-bar", Type: "tfe_variable", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+			{Address: "tfe_team_access.teams[\"staging-readers\"]", Type: "tfe_team_access", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+		},
+	}
+
+	t.Run("report deleted and replaced resources", func(t *testing.T) {
+		t.Setenv("GITHUB_WORKFLOW_REF", "org/repo/.github/workflows/workspace.yml@refs/heads/main")
+
+		report := NewSARIFReport(plan)
+
+		require.Len(t, report.Runs, 1)
+		results := report.Runs[0].Results
+
+		require.Len(t, results, 3)
+
+		assert.Equal(t, "workspace-deletion", results[0].RuleID)
+		assert.Equal(t, "error", results[0].Level)
+		assert.Equal(t, "tfe_workspace.workspace[\"staging\"] will be deleted", results[0].Message.Text)
+		assert.Equal(t, ".github/workflows/workspace.yml", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+
+		assert.Equal(t, "variable-deletion", results[1].RuleID)
+		assert.Equal(t, "warning", results[1].Level)
+		assert.Equal(t, "tfe_variable.staging-foo will be replaced", results[1].Message.Text)
+
+		assert.Equal(t, "resource-deletion", results[2].RuleID)
+		assert.Equal(t, "note", results[2].Level)
+	})
+
+	t.Run("omit the physical location outside of a workflow", func(t *testing.T) {
+		t.Setenv("GITHUB_WORKFLOW_REF", "")
+
+		report := NewSARIFReport(plan)
+
+		assert.Nil(t, report.Runs[0].Results[0].Locations[0].PhysicalLocation)
+	})
+
+	t.Run("report no results without destructive changes", func(t *testing.T) {
+		report := NewSARIFReport(&tfjson.Plan{})
+
+		assert.Empty(t, report.Runs[0].Results)
+	})
+}
+
+func TestWriteSARIFReport(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "plan.sarif")
+
+	require.NoError(t, WriteSARIFReport(&tfjson.Plan{}, filePath))
+
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &report))
+
+	assert.Equal(t, "2.1.0", report["version"])
+	assert.Equal(t, []interface{}{}, report["runs"].([]interface{})[0].(map[string]interface{})["results"])
+}
@@ -0,0 +1,91 @@
+package action
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TagPolicy is a central tagging standard, adding tags to the workspaces whose names match a pattern and requiring tags of certain classes
+type TagPolicy struct {
+	Workspaces      []TagPolicyRule `yaml:"workspaces,omitempty"`
+	RequiredClasses []string        `yaml:"required_classes,omitempty"`
+}
+
+// TagPolicyRule adds its tags to the workspaces whose names match its glob pattern
+type TagPolicyRule struct {
+	Pattern string `yaml:"pattern"`
+	Tags    Tags   `yaml:"tags"`
+}
+
+// ReadTagPolicy reads and validates the tag policy file at the passed path
+func ReadTagPolicy(filePath string) (*TagPolicy, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag policy file: %w", err)
+	}
+
+	var policy TagPolicy
+	if err = yaml.UnmarshalStrict(b, &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode tag policy file: %w", err)
+	}
+
+	for _, rule := range policy.Workspaces {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tag policy pattern %q: %w", rule.Pattern, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+// ApplyTagPolicy adds the tags of the matching policy rules to the passed tags of each workspace, skipping tags the workspace already has.
+// An error lists the workspaces missing a tag of a required class, the class of a tag being the part before its first ":".
+func ApplyTagPolicy(tags map[string]Tags, policy *TagPolicy, workspaces []*Workspace) (map[string]Tags, error) {
+	merged := map[string]Tags{}
+	for wsName, ts := range tags {
+		merged[wsName] = append(Tags{}, ts...)
+	}
+
+	missing := []string{}
+
+	for _, ws := range workspaces {
+		for _, rule := range policy.Workspaces {
+			if ok, _ := path.Match(rule.Pattern, ws.Name); !ok {
+				continue
+			}
+
+			for _, tag := range rule.Tags {
+				if !hasAnyTag(merged[ws.Workspace], Tags{tag}) {
+					merged[ws.Workspace] = append(merged[ws.Workspace], tag)
+				}
+			}
+		}
+
+		for _, class := range policy.RequiredClasses {
+			if !hasTagClass(merged[ws.Workspace], class) {
+				missing = append(missing, fmt.Sprintf("workspace %q has no %q tag", ws.Name, class))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("tag policy not met: %s", strings.Join(missing, "; "))
+	}
+
+	return merged, nil
+}
+
+// hasTagClass returns true if any of the passed tags is of the passed class, such as "env:production" of class "env"
+func hasTagClass(tags Tags, class string) bool {
+	for _, tag := range tags {
+		if strings.HasPrefix(string(tag), class+":") {
+			return true
+		}
+	}
+
+	return false
+}
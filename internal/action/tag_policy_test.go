@@ -0,0 +1,90 @@
+package action
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTagPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("read a policy", func(t *testing.T) {
+		filePath := path.Join(dir, "policy.yml")
+		require.NoError(t, os.WriteFile(filePath, []byte(`workspaces:
+  - pattern: "*-production"
+    tags:
+      - env:production
+required_classes:
+  - env
+`), 0644))
+
+		policy, err := ReadTagPolicy(filePath)
+		require.NoError(t, err)
+
+		assert.Equal(t, &TagPolicy{
+			Workspaces:      []TagPolicyRule{{Pattern: "*-production", Tags: Tags{"env:production"}}},
+			RequiredClasses: []string{"env"},
+		}, policy)
+	})
+
+	t.Run("error on an invalid pattern", func(t *testing.T) {
+		filePath := path.Join(dir, "invalid.yml")
+		require.NoError(t, os.WriteFile(filePath, []byte(`workspaces:
+  - pattern: "[production"
+    tags:
+      - env:production
+`), 0644))
+
+		_, err := ReadTagPolicy(filePath)
+		assert.EqualError(t, err, `invalid tag policy pattern "[production": syntax error in pattern`)
+	})
+
+	t.Run("error on an unknown field", func(t *testing.T) {
+		filePath := path.Join(dir, "unknown.yml")
+		require.NoError(t, os.WriteFile(filePath, []byte("required: [env]\n"), 0644))
+
+		_, err := ReadTagPolicy(filePath)
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyTagPolicy(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: "payments-staging", Workspace: "staging"},
+		{Name: "payments-production", Workspace: "production"},
+	}
+
+	tags := map[string]Tags{
+		"staging":    {"team:payments", "env:staging"},
+		"production": {"team:payments"},
+	}
+
+	t.Run("add the tags of the matching rules", func(t *testing.T) {
+		merged, err := ApplyTagPolicy(tags, &TagPolicy{
+			Workspaces: []TagPolicyRule{
+				{Pattern: "*-production", Tags: Tags{"env:production"}},
+				{Pattern: "payments-*", Tags: Tags{"team:payments", "pci"}},
+			},
+			RequiredClasses: []string{"env", "team"},
+		}, workspaces)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]Tags{
+			"staging":    {"team:payments", "env:staging", "pci"},
+			"production": {"team:payments", "env:production", "pci"},
+		}, merged)
+
+		assert.Equal(t, Tags{"team:payments"}, tags["production"])
+	})
+
+	t.Run("error on workspaces missing a required class", func(t *testing.T) {
+		_, err := ApplyTagPolicy(tags, &TagPolicy{
+			RequiredClasses: []string{"env", "cost-center"},
+		}, workspaces)
+		assert.EqualError(t, err, `tag policy not met: workspace "payments-staging" has no "cost-center" tag; workspace "payments-production" has no "env" tag; workspace "payments-production" has no "cost-center" tag`)
+	})
+}
@@ -0,0 +1,118 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// TargetOptions holds the resources generated for the configured workspaces, used to scope a plan to specific workspaces
+type TargetOptions struct {
+	Variables     Variables
+	TeamAccess    TeamAccess
+	RunTriggers   RunTriggers
+	Notifications []*Notification
+
+	// Organization is the default organization of workspaces without one
+	Organization string
+
+	// TeamIDs maps team names to team IDs by organization, as team access instances are keyed by team ID.
+	// The whole team access resource is targeted for teams without a known ID.
+	TeamIDs map[string]map[string]string
+}
+
+// targetAddress returns the address of a for_each resource instance, or the address of the whole resource if the instance key is only known after apply
+func targetAddress(resource string, key string) string {
+	if strings.Contains(key, "${") {
+		return resource
+	}
+
+	return fmt.Sprintf("%s[%q]", resource, key)
+}
+
+// appendUnique appends the passed address to the list if it is not already present
+func appendUnique(addresses []string, address string) []string {
+	for _, a := range addresses {
+		if a == address {
+			return addresses
+		}
+	}
+
+	return append(addresses, address)
+}
+
+// WorkspaceTargets returns the resource addresses of the target workspaces and their dependent resources.
+// An error is returned if a target does not match a configured workspace.
+func WorkspaceTargets(targets []string, workspaces []*Workspace, config *TargetOptions) ([]string, error) {
+	addresses := []string{}
+
+	for _, target := range targets {
+		ws := FindWorkspace(workspaces, target)
+		if ws == nil {
+			return nil, fmt.Errorf("target workspace %q not found in the configured workspaces", target)
+		}
+
+		addresses = appendUnique(addresses, ws.Address())
+
+		for _, v := range config.Variables {
+			if v.Workspace.Workspace == ws.Workspace {
+				addresses = appendUnique(addresses, fmt.Sprintf("tfe_variable.%s-%s", ws.Workspace, v.Key))
+			}
+		}
+
+		for _, ta := range config.TeamAccess {
+			if ta.Workspace.Workspace != ws.Workspace {
+				continue
+			}
+
+			id, ok := config.TeamIDs[workspaceOrganization(ws, config.Organization)][ta.TeamName]
+			if !ok {
+				// team access keys include the team ID, which is otherwise only read from a data source during the plan
+				addresses = appendUnique(addresses, "tfe_team_access.teams")
+				continue
+			}
+
+			addresses = appendUnique(addresses, fmt.Sprintf("tfe_team_access.teams[%q]", fmt.Sprintf("%s-%s", ws.Workspace, id)))
+		}
+
+		for _, rt := range config.RunTriggers {
+			if rt.Workspace.Workspace == ws.Workspace {
+				addresses = appendUnique(addresses, targetAddress("tfe_run_trigger.trigger", rt.Key()))
+			}
+		}
+
+		for _, n := range config.Notifications {
+			if n.Workspace.Workspace == ws.Workspace {
+				addresses = appendUnique(addresses, fmt.Sprintf("tfe_notification_configuration.%s", ws.Workspace))
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// FetchTeamIDs returns the IDs of the teams of each organization with team access by team name, used to target the team access instances of a workspace
+func FetchTeamIDs(ctx context.Context, client *tfe.Client, teamAccess TeamAccess, organization string) (map[string]map[string]string, error) {
+	ids := map[string]map[string]string{}
+
+	for _, ta := range teamAccess {
+		org := workspaceOrganization(ta.Workspace, organization)
+		if _, ok := ids[org]; ok {
+			continue
+		}
+
+		teams, err := FetchRelatedTeams(ctx, client, ta.Workspace, org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list teams of organization %q: %w", org, err)
+		}
+
+		ids[org] = map[string]string{}
+		for _, t := range teams {
+			ids[org][t.Name] = t.ID
+		}
+	}
+
+	return ids, nil
+}
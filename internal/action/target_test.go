@@ -0,0 +1,97 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceTargets(t *testing.T) {
+	workspaces := newTestMultiWorkspaceList()
+
+	config := &TargetOptions{
+		Variables: Variables{
+			{Key: "foo", Workspace: workspaces[0]},
+			{Key: "foo", Workspace: workspaces[1]},
+		},
+		TeamAccess: TeamAccess{
+			{TeamName: "Readers", Access: "read", Workspace: workspaces[0]},
+		},
+		RunTriggers: RunTriggers{
+			{SourceID: "ws-ghi789", Workspace: workspaces[0]},
+			{SourceID: "${tfe_workspace.workspace[\"staging\"].id}", Workspace: workspaces[1]},
+		},
+		Notifications: []*Notification{
+			{Input: &NotificationInput{Name: "foo"}, Workspace: workspaces[0]},
+		},
+	}
+
+	t.Run("target a workspace and its dependent resources", func(t *testing.T) {
+		targets, err := WorkspaceTargets([]string{"staging"}, workspaces, config)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"tfe_workspace.workspace[\"staging\"]",
+			"tfe_variable.staging-foo",
+			"tfe_team_access.teams",
+			"tfe_run_trigger.trigger[\"staging-ws-ghi789\"]",
+			"tfe_notification_configuration.staging",
+		}, targets)
+	})
+
+	t.Run("target the team access instances of a workspace when the team IDs are known", func(t *testing.T) {
+		targets, err := WorkspaceTargets([]string{"staging"}, workspaces, &TargetOptions{
+			TeamAccess:   config.TeamAccess,
+			Organization: "org",
+			TeamIDs:      map[string]map[string]string{"org": {"Readers": "team-1"}},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"tfe_workspace.workspace[\"staging\"]",
+			"tfe_team_access.teams[\"staging-team-1\"]",
+		}, targets)
+	})
+
+	t.Run("target a whole resource when the instance key is only known after apply", func(t *testing.T) {
+		targets, err := WorkspaceTargets([]string{"production"}, workspaces, config)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"tfe_workspace.workspace[\"production\"]",
+			"tfe_variable.production-foo",
+			"tfe_run_trigger.trigger",
+		}, targets)
+	})
+
+	t.Run("error when a target does not match a configured workspace", func(t *testing.T) {
+		_, err := WorkspaceTargets([]string{"playground"}, workspaces, config)
+		assert.EqualError(t, err, "target workspace \"playground\" not found in the configured workspaces")
+	})
+}
+
+func TestFetchTeamIDs(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/org/teams", testServerResHandler(t, 200, `{"data": [
+		{"id": "team-1", "type": "teams", "attributes": {"name": "Readers"}},
+		{"id": "team-2", "type": "teams", "attributes": {"name": "Engineers"}}
+	]}`))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	workspaces := newTestMultiWorkspaceList()
+
+	ids, err := FetchTeamIDs(ctx, newTestTFClient(t, server.URL), TeamAccess{
+		{TeamName: "Readers", Access: "read", Workspace: workspaces[0]},
+	}, "org")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]map[string]string{"org": {"Readers": "team-1", "Engineers": "team-2"}}, ids)
+}
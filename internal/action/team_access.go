@@ -2,8 +2,11 @@ package action
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	tfe "github.com/hashicorp/go-tfe"
+	"github.com/sethvargo/go-githubactions"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
 )
 
@@ -12,9 +15,26 @@ type TeamAccessInput []TeamAccessInputItem
 
 // TeamAccessInputItem represents a single team access setting
 type TeamAccessInputItem struct {
-	Access      string                      `yaml:"access,omitempty"`
-	Permissions *TeamAccessPermissionsInput `yaml:"permissions,omitempty"`
-	TeamName    string                      `yaml:"name"`
+	Access        string                      `yaml:"access,omitempty"`
+	Permissions   *TeamAccessPermissionsInput `yaml:"permissions,omitempty"`
+	TeamName      string                      `yaml:"name"`
+	Workspaces    []string                    `yaml:"workspaces,omitempty"`
+	WorkspaceTags Tags                        `yaml:"workspace_tags,omitempty"`
+}
+
+// appliesTo returns true if the team access setting applies to the passed workspace, which is every workspace unless a workspaces filter is set
+func (item TeamAccessInputItem) appliesTo(ws *Workspace) bool {
+	if len(item.Workspaces) == 0 {
+		return true
+	}
+
+	for _, name := range item.Workspaces {
+		if name == ws.Workspace {
+			return true
+		}
+	}
+
+	return false
 }
 
 type TeamAccess []TeamAccessItem
@@ -27,27 +47,103 @@ type TeamAccessItem struct {
 	Workspace *Workspace
 }
 
-// NewTeamAccess takes a team inputs and workspaces and returns a TeamAccessItem per input, per workspace
+// NewTeamAccess takes a team inputs and workspaces and returns a TeamAccessItem per input, per workspace.
+// Inputs with a workspaces filter only apply to the listed workspaces, overriding an unfiltered input for the same team.
 func NewTeamAccess(inputs TeamAccessInput, workspaces []*Workspace) TeamAccess {
-	access := make(TeamAccess, len(inputs)*len(workspaces))
-
-	i := 0
+	access := TeamAccess{}
 
 	for _, team := range inputs {
 		for _, ws := range workspaces {
-			access[i] = TeamAccessItem{
+			if !team.appliesTo(ws) || (len(team.Workspaces) == 0 && hasTeamAccessOverride(inputs, team.TeamName, ws)) {
+				continue
+			}
+
+			access = append(access, TeamAccessItem{
 				Access:      team.Access,
 				Permissions: team.Permissions,
 				TeamName:    team.TeamName,
 				Workspace:   ws,
-			}
-			i = i + 1
+			})
 		}
 	}
 
 	return access
 }
 
+// hasTeamAccessOverride returns true if an input with a workspaces filter sets the access of the passed team on the passed workspace
+func hasTeamAccessOverride(inputs TeamAccessInput, teamName string, ws *Workspace) bool {
+	for _, team := range inputs {
+		if team.TeamName == teamName && len(team.Workspaces) > 0 && team.appliesTo(ws) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateTeamAccessWorkspaces returns an error if a team access workspaces filter lists a workspace that is not configured
+func ValidateTeamAccessWorkspaces(inputs TeamAccessInput, workspaces []*Workspace) error {
+	for _, team := range inputs {
+		for _, name := range team.Workspaces {
+			if FindWorkspace(workspaces, name) == nil {
+				return fmt.Errorf("team access for %q lists workspace %q, which is not found in the configured workspaces", team.TeamName, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveTeamAccessTags converts the workspace tags filter of each team access input into a workspaces filter, listing the workspaces tagged with any of the filter tags.
+// When both filters are set, only the listed workspaces that carry a filter tag are kept.
+func ResolveTeamAccessTags(inputs TeamAccessInput, tags map[string]Tags, workspaces []*Workspace) (TeamAccessInput, error) {
+	resolved := make(TeamAccessInput, 0, len(inputs))
+
+	for _, team := range inputs {
+		if len(team.WorkspaceTags) == 0 {
+			resolved = append(resolved, team)
+			continue
+		}
+
+		names := []string{}
+
+		for _, ws := range workspaces {
+			if team.appliesTo(ws) && hasAnyTag(tags[ws.Workspace], team.WorkspaceTags) {
+				names = append(names, ws.Workspace)
+			}
+		}
+
+		if len(names) == 0 {
+			filter := make([]string, len(team.WorkspaceTags))
+			for i, tag := range team.WorkspaceTags {
+				filter[i] = string(tag)
+			}
+
+			return nil, fmt.Errorf("team access for %q filters workspace tags %s, which match none of the configured workspaces", team.TeamName, strings.Join(filter, ", "))
+		}
+
+		team.Workspaces = names
+		team.WorkspaceTags = nil
+
+		resolved = append(resolved, team)
+	}
+
+	return resolved, nil
+}
+
+// hasAnyTag returns true if any of the wanted tags is in the passed tags
+func hasAnyTag(tags Tags, wanted Tags) bool {
+	for _, w := range wanted {
+		for _, tag := range tags {
+			if tag == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // ToResource converts the TeamAccessItem to a Terraform resource
 func (ta TeamAccessItem) ToResource() *tfeprovider.TeamAccess {
 	resource := &tfeprovider.TeamAccess{
@@ -69,12 +165,12 @@ func (ta TeamAccessItem) ToResource() *tfeprovider.TeamAccess {
 }
 
 type TeamAccessPermissionsInput struct {
-	Runs             string `yaml:"runs"`
-	Variables        string `yaml:"variables"`
-	StateVersions    string `yaml:"state_versions"`
-	SentinelMocks    string `yaml:"sentinel_mocks"`
-	WorkspaceLocking bool   `yaml:"workspace_locking"`
-	RunTasks         bool   `yaml:"run_tasks"`
+	Runs             string `json:"runs" yaml:"runs"`
+	Variables        string `json:"variables" yaml:"variables"`
+	StateVersions    string `json:"state_versions" yaml:"state_versions"`
+	SentinelMocks    string `json:"sentinel_mocks" yaml:"sentinel_mocks"`
+	WorkspaceLocking bool   `json:"workspace_locking" yaml:"workspace_locking"`
+	RunTasks         bool   `json:"run_tasks" yaml:"run_tasks"`
 }
 
 // findTeamByID takes a list of teams and returns a matching team to the passed ID
@@ -143,3 +239,64 @@ func FetchRelatedTeamAccess(ctx context.Context, client *tfe.Client, workspace *
 
 	return teamAccess.Items, nil
 }
+
+// OrphanedTeamAccess returns the team access of the workspace granted to teams without access in the desired configuration.
+// Access of teams that are not found in the passed teams is kept, as it cannot be matched to the configuration.
+func OrphanedTeamAccess(access []*tfe.TeamAccess, teams []*tfe.Team, desired TeamAccess, workspace *Workspace) []*tfe.TeamAccess {
+	orphaned := []*tfe.TeamAccess{}
+
+	for _, a := range access {
+		if a.Team == nil {
+			continue
+		}
+
+		t := findTeamByID(teams, a.Team.ID)
+		if t == nil {
+			continue
+		}
+
+		found := false
+
+		for _, d := range desired {
+			if d.Workspace.Workspace == workspace.Workspace && d.TeamName == t.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			orphaned = append(orphaned, a)
+		}
+	}
+
+	return orphaned
+}
+
+// PruneTeamAccess removes the team access of the passed workspaces that is not in the desired configuration, skipping workspaces that do not exist
+func PruneTeamAccess(ctx context.Context, client *tfe.Client, workspaces []*Workspace, desired TeamAccess, organization string) error {
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			continue
+		}
+
+		teams, err := FetchRelatedTeams(ctx, client, ws, workspaceOrganization(ws, organization))
+		if err != nil {
+			return fmt.Errorf("failed to list teams: %w", err)
+		}
+
+		access, err := FetchRelatedTeamAccess(ctx, client, ws)
+		if err != nil {
+			return fmt.Errorf("failed to list team access of workspace %q: %w", ws.Name, err)
+		}
+
+		for _, a := range OrphanedTeamAccess(access, teams, desired, ws) {
+			if err = client.TeamAccess.Remove(ctx, a.ID); err != nil {
+				return fmt.Errorf("failed to remove team access %q from workspace %q: %w", a.ID, ws.Name, err)
+			}
+
+			githubactions.Infof("Removed access of team %q from workspace %q\n", findTeamByID(teams, a.Team.ID).Name, ws.Name)
+		}
+	}
+
+	return nil
+}
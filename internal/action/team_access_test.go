@@ -1,9 +1,14 @@
 package action
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	tfe "github.com/hashicorp/go-tfe"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type NewTeamAccessTestCase struct {
@@ -72,6 +77,37 @@ func TestNewTeamAccess(t *testing.T) {
 				TeamAccessItem{Access: "write", TeamName: "Writers", Workspace: &Workspace{Name: "production"}},
 			},
 		},
+		{
+			Description: "filtered access only applies to the listed workspaces",
+			Workspaces: []*Workspace{
+				{Name: "foo-staging", Workspace: "staging"},
+				{Name: "foo-production", Workspace: "production"},
+			},
+			Input: TeamAccessInput{
+				TeamAccessInputItem{Access: "write", TeamName: "Writers", Workspaces: []string{"staging"}},
+			},
+			Expect: TeamAccess{
+				TeamAccessItem{Access: "write", TeamName: "Writers", Workspace: &Workspace{Name: "foo-staging", Workspace: "staging"}},
+			},
+		},
+		{
+			Description: "filtered access overrides the default access of the same team",
+			Workspaces: []*Workspace{
+				{Name: "foo-staging", Workspace: "staging"},
+				{Name: "foo-production", Workspace: "production"},
+			},
+			Input: TeamAccessInput{
+				TeamAccessInputItem{Access: "read", TeamName: "Engineers"},
+				TeamAccessInputItem{Access: "read", TeamName: "Readers"},
+				TeamAccessInputItem{Access: "admin", TeamName: "Engineers", Workspaces: []string{"production"}},
+			},
+			Expect: TeamAccess{
+				TeamAccessItem{Access: "read", TeamName: "Engineers", Workspace: &Workspace{Name: "foo-staging", Workspace: "staging"}},
+				TeamAccessItem{Access: "read", TeamName: "Readers", Workspace: &Workspace{Name: "foo-staging", Workspace: "staging"}},
+				TeamAccessItem{Access: "read", TeamName: "Readers", Workspace: &Workspace{Name: "foo-production", Workspace: "production"}},
+				TeamAccessItem{Access: "admin", TeamName: "Engineers", Workspace: &Workspace{Name: "foo-production", Workspace: "production"}},
+			},
+		},
 	} {
 		t.Run(testCase.Description, func(t *testing.T) {
 			access := NewTeamAccess(testCase.Input, testCase.Workspaces)
@@ -79,3 +115,124 @@ func TestNewTeamAccess(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTeamAccessWorkspaces(t *testing.T) {
+	t.Run("accept configured workspaces", func(t *testing.T) {
+		err := ValidateTeamAccessWorkspaces(TeamAccessInput{
+			{Access: "admin", TeamName: "Engineers", Workspaces: []string{"production"}},
+		}, newTestMultiWorkspaceList())
+		assert.NoError(t, err)
+	})
+
+	t.Run("error on an unknown workspace", func(t *testing.T) {
+		err := ValidateTeamAccessWorkspaces(TeamAccessInput{
+			{Access: "admin", TeamName: "Engineers", Workspaces: []string{"prod"}},
+		}, newTestMultiWorkspaceList())
+		assert.EqualError(t, err, "team access for \"Engineers\" lists workspace \"prod\", which is not found in the configured workspaces")
+	})
+}
+
+func TestResolveTeamAccessTags(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: "foo-staging", Workspace: "staging"},
+		{Name: "foo-production", Workspace: "production"},
+		{Name: "foo-sandbox", Workspace: "sandbox"},
+	}
+
+	tags := map[string]Tags{
+		"staging":    {"shared", "payments"},
+		"production": {"shared", "payments", "prod"},
+		"sandbox":    {"shared"},
+	}
+
+	t.Run("filter the workspaces by tag", func(t *testing.T) {
+		resolved, err := ResolveTeamAccessTags(TeamAccessInput{
+			{Access: "read", TeamName: "Readers"},
+			{Access: "write", TeamName: "Payments", WorkspaceTags: Tags{"payments"}},
+		}, tags, workspaces)
+		require.NoError(t, err)
+
+		assert.Equal(t, TeamAccessInput{
+			{Access: "read", TeamName: "Readers"},
+			{Access: "write", TeamName: "Payments", Workspaces: []string{"staging", "production"}},
+		}, resolved)
+	})
+
+	t.Run("intersect with the workspaces filter", func(t *testing.T) {
+		resolved, err := ResolveTeamAccessTags(TeamAccessInput{
+			{Access: "admin", TeamName: "Payments", Workspaces: []string{"production", "sandbox"}, WorkspaceTags: Tags{"payments"}},
+		}, tags, workspaces)
+		require.NoError(t, err)
+
+		assert.Equal(t, TeamAccessInput{
+			{Access: "admin", TeamName: "Payments", Workspaces: []string{"production"}},
+		}, resolved)
+	})
+
+	t.Run("error when no workspace matches", func(t *testing.T) {
+		_, err := ResolveTeamAccessTags(TeamAccessInput{
+			{Access: "write", TeamName: "Payments", WorkspaceTags: Tags{"billing", "ledger"}},
+		}, tags, workspaces)
+		assert.EqualError(t, err, `team access for "Payments" filters workspace tags billing, ledger, which match none of the configured workspaces`)
+	})
+}
+
+func TestOrphanedTeamAccess(t *testing.T) {
+	ws := &Workspace{Name: "foo-staging", Workspace: "staging"}
+	other := &Workspace{Name: "foo-production", Workspace: "production"}
+
+	teams := []*tfe.Team{
+		{ID: "team-1", Name: "Readers"},
+		{ID: "team-2", Name: "Engineers"},
+	}
+
+	access := []*tfe.TeamAccess{
+		{ID: "tws-1", Team: &tfe.Team{ID: "team-1"}},
+		{ID: "tws-2", Team: &tfe.Team{ID: "team-2"}},
+		{ID: "tws-3", Team: &tfe.Team{ID: "team-unknown"}},
+	}
+
+	desired := TeamAccess{
+		{TeamName: "Readers", Access: "read", Workspace: ws},
+		{TeamName: "Engineers", Access: "admin", Workspace: other},
+	}
+
+	orphaned := OrphanedTeamAccess(access, teams, desired, ws)
+
+	assert.Equal(t, []*tfe.TeamAccess{access[1]}, orphaned)
+}
+
+func TestPruneTeamAccess(t *testing.T) {
+	ctx := context.Background()
+
+	removed := []string{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/org/teams", testServerResHandler(t, 200, `{"data": [
+		{"id": "team-1", "type": "teams", "attributes": {"name": "Readers"}},
+		{"id": "team-2", "type": "teams", "attributes": {"name": "Engineers"}}
+	]}`))
+	mux.HandleFunc("/api/v2/team-workspaces", testServerResHandler(t, 200, `{"data": [
+		{"id": "tws-1", "type": "team-workspaces", "attributes": {"access": "read"}, "relationships": {"team": {"data": {"id": "team-1", "type": "teams"}}}},
+		{"id": "tws-2", "type": "team-workspaces", "attributes": {"access": "admin"}, "relationships": {"team": {"data": {"id": "team-2", "type": "teams"}}}}
+	]}`))
+	mux.HandleFunc("/api/v2/team-workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+
+		removed = append(removed, r.URL.Path)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ws := &Workspace{Name: "foo-staging", Workspace: "staging", ID: strPtr("ws-abc123")}
+
+	err := PruneTeamAccess(ctx, newTestTFClient(t, server.URL), []*Workspace{ws, {Name: "foo-production", Workspace: "production"}}, TeamAccess{
+		{TeamName: "Readers", Access: "read", Workspace: ws},
+	}, "org")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/api/v2/team-workspaces/tws-2"}, removed)
+}
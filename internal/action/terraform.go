@@ -2,10 +2,18 @@ package action
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	install "github.com/hashicorp/hc-install"
@@ -13,8 +21,74 @@ import (
 	"github.com/hashicorp/hc-install/releases"
 	"github.com/hashicorp/hc-install/src"
 	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/sethvargo/go-githubactions"
+	yaml "gopkg.in/yaml.v2"
 )
 
+// defaultInitRetries is the number of times "terraform init" is retried after a transient network error if init_retries is not set
+const defaultInitRetries = 2
+
+// initRetryInterval is the time waited before the first retry of "terraform init", doubling with each further retry
+var initRetryInterval = 5 * time.Second
+
+// transientInitErrors are substrings of "terraform init" output caused by network failures, such as a provider registry outage
+var transientInitErrors = []string{
+	"connection reset by peer",
+	"connection refused",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"Client.Timeout exceeded",
+	"no such host",
+	"unexpected EOF",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// isTransientInitError returns true if the passed "terraform init" error was caused by a network failure
+func isTransientInitError(err error) bool {
+	for _, s := range transientInitErrors {
+		if strings.Contains(err.Error(), s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stateLockError is the "terraform init" output when the state is locked by another operation during state migration, followed by the lock info
+const stateLockError = "Error acquiring the state lock"
+
+// terraformIniter runs "terraform init"
+type terraformIniter interface {
+	Init(ctx context.Context, opts ...tfexec.InitOption) error
+}
+
+// initWithRetry runs "terraform init", retrying it up to the passed number of retries with exponential backoff after transient network errors, such as a backend outage during state migration.
+// Other errors, such as configuration errors, are returned immediately, as are state lock conflicts, which include the lock info.
+func initWithRetry(ctx context.Context, tf terraformIniter, retries int, opts ...tfexec.InitOption) error {
+	for attempt := 0; ; attempt++ {
+		err := tf.Init(ctx, opts...)
+		if err != nil && strings.Contains(err.Error(), stateLockError) {
+			return fmt.Errorf("%w: %s", ErrStateLocked, err)
+		}
+
+		if err == nil || attempt >= retries || !isTransientInitError(err) {
+			return err
+		}
+
+		interval := initRetryInterval * time.Duration(1<<attempt)
+
+		githubactions.Infof("Terraform init failed with a transient error, retrying in %s (attempt %d of %d): %s\n", interval, attempt+1, retries, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 func NewTerraformExec(ctx context.Context, workDir string, tfVersion string) (*tfexec.Terraform, error) {
 	v, err := version.NewVersion(tfVersion)
 	if err != nil {
@@ -51,3 +125,185 @@ func writeTerraformrcFile(host string, token string) error {
 
 	return nil
 }
+
+// CredentialsEnv returns the current environment with the passed token set for the tfe provider ("TFE_TOKEN") and for Terraform's own requests to the host ("TF_TOKEN_<host>").
+// Variables managed by tfexec are left out, as they cannot be overridden.
+func CredentialsEnv(host string, token string) map[string]string {
+	env := processEnv()
+
+	// host names are encoded with periods as underscores and hyphens as double underscores
+	hostKey := strings.ReplaceAll(strings.ReplaceAll(host, "-", "__"), ".", "_")
+
+	env["TFE_TOKEN"] = token
+	env[fmt.Sprintf("TF_TOKEN_%s", hostKey)] = token
+
+	return env
+}
+
+// processEnv returns the current environment, without the variables managed by tfexec
+func processEnv() map[string]string {
+	env := map[string]string{}
+
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	for _, k := range tfexec.ProhibitedEnv(env) {
+		delete(env, k)
+	}
+
+	return env
+}
+
+// credentialsEnvPattern matches the environment variables holding the Terraform Cloud credentials
+var credentialsEnvPattern = regexp.MustCompile(`^(TFE_TOKEN|TF_TOKEN_.+|TF_CLI_CONFIG_FILE)$`)
+
+// logEnvSetters are the log variables managed by tfexec, which are only set through their setters
+var logEnvSetters = map[string]func(*tfexec.Terraform, string) error{
+	"TF_LOG":          (*tfexec.Terraform).SetLog,
+	"TF_LOG_CORE":     (*tfexec.Terraform).SetLogCore,
+	"TF_LOG_PATH":     (*tfexec.Terraform).SetLogPath,
+	"TF_LOG_PROVIDER": (*tfexec.Terraform).SetLogProvider,
+}
+
+// ParseTerraformEnv decodes the YAML encoded map of extra environment variables of the Terraform process.
+// Variables managed by tfexec are rejected, except for the log variables, and so are the credential variables unless allowCredentials is true.
+func ParseTerraformEnv(input string, allowCredentials bool) (map[string]string, error) {
+	env := map[string]string{}
+	if err := yaml.Unmarshal([]byte(input), &env); err != nil {
+		return nil, fmt.Errorf("failed to decode environment variables: %w", err)
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, ok := logEnvSetters[k]; ok {
+			continue
+		}
+
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return nil, fmt.Errorf("invalid environment variable name %q", k)
+		}
+
+		if len(tfexec.ProhibitedEnv(map[string]string{k: ""})) > 0 {
+			return nil, fmt.Errorf("environment variable %s is managed by the action and cannot be set", k)
+		}
+
+		if credentialsEnvPattern.MatchString(k) && !allowCredentials {
+			return nil, fmt.Errorf("environment variable %s overrides the Terraform credentials, allow_credentials_env must be true to set it", k)
+		}
+	}
+
+	return env, nil
+}
+
+// SetTerraformEnv sets the environment of the Terraform process to the base environment merged with the extra variables, setting the log variables through tfexec
+func SetTerraformEnv(tf *tfexec.Terraform, base map[string]string, extra map[string]string) error {
+	env := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		env[k] = v
+	}
+
+	for k, v := range extra {
+		if set, ok := logEnvSetters[k]; ok {
+			if err := set(tf, v); err != nil {
+				return fmt.Errorf("failed to set %s: %w", k, err)
+			}
+
+			continue
+		}
+
+		env[k] = v
+	}
+
+	return tf.SetEnv(env)
+}
+
+// SetPluginCacheDir creates the plugin cache directory and sets its absolute path as TF_PLUGIN_CACHE_DIR in the passed environment, as Terraform runs in the working directory
+func SetPluginCacheDir(env map[string]string, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve plugin cache directory: %w", err)
+	}
+
+	if v, ok := env["TF_PLUGIN_CACHE_DIR"]; ok {
+		return fmt.Errorf("env sets TF_PLUGIN_CACHE_DIR to %q, which conflicts with plugin_cache_dir", v)
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin cache directory: %w", err)
+	}
+
+	env["TF_PLUGIN_CACHE_DIR"] = dir
+
+	return nil
+}
+
+// backendState is the backend configuration recorded in the working directory by "terraform init"
+type backendState struct {
+	Backend *struct {
+		Type   string                 `json:"type"`
+		Config map[string]interface{} `json:"config"`
+	} `json:"backend"`
+}
+
+// BackendUnchanged returns true if the working directory is already initialized with the passed backend configuration
+func BackendUnchanged(workDir string, backend map[string]interface{}) (bool, error) {
+	b, err := ioutil.ReadFile(path.Join(workDir, ".terraform", "terraform.tfstate"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	var state backendState
+
+	if err := json.Unmarshal(b, &state); err != nil {
+		return false, fmt.Errorf("failed to decode the initialized backend configuration: %w", err)
+	}
+
+	if state.Backend == nil {
+		return len(backend) == 0, nil
+	}
+
+	if len(backend) != 1 {
+		return false, nil
+	}
+
+	config, ok := backend[state.Backend.Type].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	// the recorded configuration includes every attribute of the backend schema, with unset attributes as null
+	for k, v := range state.Backend.Config {
+		if v == nil {
+			if _, ok := config[k]; ok {
+				return false, nil
+			}
+
+			continue
+		}
+
+		if fmt.Sprint(config[k]) != fmt.Sprint(v) {
+			return false, nil
+		}
+	}
+
+	for k := range config {
+		if _, ok := state.Backend.Config[k]; !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
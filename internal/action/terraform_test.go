@@ -0,0 +1,222 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestBackendState writes the passed content as the initialized backend state of a new working directory
+func writeTestBackendState(t *testing.T, content string) string {
+	workDir := t.TempDir()
+
+	require.NoError(t, os.Mkdir(path.Join(workDir, ".terraform"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(workDir, ".terraform", "terraform.tfstate"), []byte(content), 0644))
+
+	return workDir
+}
+
+func TestBackendUnchanged(t *testing.T) {
+	s3State := `{
+  "version": 3,
+  "backend": {
+    "type": "s3",
+    "config": {
+      "bucket": "foo",
+      "key": "bar",
+      "region": "us-east-1",
+      "role_arn": null
+    },
+    "hash": 12345
+  }
+}`
+
+	t.Run("return false if the working directory is not initialized", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(t.TempDir(), nil)
+		require.NoError(t, err)
+
+		assert.False(t, unchanged)
+	})
+
+	t.Run("return true for an identical backend", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(writeTestBackendState(t, s3State), map[string]interface{}{
+			"s3": map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1"},
+		})
+		require.NoError(t, err)
+
+		assert.True(t, unchanged)
+	})
+
+	t.Run("return false for a changed attribute", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(writeTestBackendState(t, s3State), map[string]interface{}{
+			"s3": map[string]interface{}{"bucket": "foo", "key": "baz", "region": "us-east-1"},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, unchanged)
+	})
+
+	t.Run("return false for a changed backend type", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(writeTestBackendState(t, s3State), map[string]interface{}{
+			"local": map[string]interface{}{"path": "foo/terraform.tfstate"},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, unchanged)
+	})
+
+	t.Run("return false when migrating to the default local backend", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(writeTestBackendState(t, s3State), nil)
+		require.NoError(t, err)
+
+		assert.False(t, unchanged)
+	})
+
+	t.Run("return true when the default local backend is unchanged", func(t *testing.T) {
+		unchanged, err := BackendUnchanged(writeTestBackendState(t, `{"version": 3}`), nil)
+		require.NoError(t, err)
+
+		assert.True(t, unchanged)
+	})
+}
+
+// testIniter returns the queued errors from successive Init calls
+type testIniter struct {
+	errs  []error
+	calls int
+	opts  []tfexec.InitOption
+}
+
+func (ti *testIniter) Init(ctx context.Context, opts ...tfexec.InitOption) error {
+	ti.calls++
+	ti.opts = opts
+
+	if len(ti.errs) == 0 {
+		return nil
+	}
+
+	err := ti.errs[0]
+	ti.errs = ti.errs[1:]
+
+	return err
+}
+
+func TestInitWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	initRetryInterval = time.Millisecond
+
+	t.Run("retry after a transient error", func(t *testing.T) {
+		ti := &testIniter{errs: []error{errors.New("Error: Failed to query available provider packages: read tcp: i/o timeout")}}
+
+		assert.NoError(t, initWithRetry(ctx, ti, defaultInitRetries))
+		assert.Equal(t, 2, ti.calls)
+	})
+
+	t.Run("pass the init options to every attempt", func(t *testing.T) {
+		ti := &testIniter{errs: []error{errors.New("503 Service Unavailable")}}
+
+		assert.NoError(t, initWithRetry(ctx, ti, defaultInitRetries, tfexec.Reconfigure(true)))
+		assert.Equal(t, 2, ti.calls)
+		assert.Equal(t, []tfexec.InitOption{tfexec.Reconfigure(true)}, ti.opts)
+	})
+
+	t.Run("fail immediately on a configuration error", func(t *testing.T) {
+		ti := &testIniter{errs: []error{errors.New("Error: Unsupported block type")}}
+
+		assert.EqualError(t, initWithRetry(ctx, ti, defaultInitRetries), "Error: Unsupported block type")
+		assert.Equal(t, 1, ti.calls)
+	})
+
+	t.Run("fail immediately on a state lock conflict", func(t *testing.T) {
+		ti := &testIniter{errs: []error{errors.New("Error: Error acquiring the state lock\n\nLock Info:\n  ID: abc123\n  Who: runner@host\n\n503 Service Unavailable")}}
+
+		err := initWithRetry(ctx, ti, defaultInitRetries)
+		assert.ErrorContains(t, err, "state is locked by another operation")
+		assert.ErrorContains(t, err, "Who: runner@host")
+		assert.Equal(t, 1, ti.calls)
+	})
+
+	t.Run("fail after exhausting the retries", func(t *testing.T) {
+		transient := errors.New("503 Service Unavailable")
+		ti := &testIniter{errs: []error{transient, transient, transient, transient}}
+
+		assert.Equal(t, transient, initWithRetry(ctx, ti, defaultInitRetries))
+		assert.Equal(t, defaultInitRetries+1, ti.calls)
+	})
+}
+
+func TestCredentialsEnv(t *testing.T) {
+	t.Setenv("TF_LOG", "DEBUG")
+	t.Setenv("TEST_CREDENTIALS_ENV", "foo")
+
+	env := CredentialsEnv("tfe.my-company.com", "12345")
+
+	assert.Equal(t, "12345", env["TFE_TOKEN"])
+	assert.Equal(t, "12345", env["TF_TOKEN_tfe_my__company_com"])
+	assert.Equal(t, "foo", env["TEST_CREDENTIALS_ENV"])
+	assert.NotContains(t, env, "TF_LOG")
+}
+
+func TestParseTerraformEnv(t *testing.T) {
+	t.Run("parse variables", func(t *testing.T) {
+		env, err := ParseTerraformEnv("TF_LOG: DEBUG\nHTTPS_PROXY: http://proxy:3128", false)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"TF_LOG": "DEBUG", "HTTPS_PROXY": "http://proxy:3128"}, env)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		env, err := ParseTerraformEnv("", false)
+		require.NoError(t, err)
+
+		assert.Empty(t, env)
+	})
+
+	t.Run("error on a variable managed by tfexec", func(t *testing.T) {
+		_, err := ParseTerraformEnv("TF_IN_AUTOMATION: '1'", false)
+		assert.Error(t, err)
+	})
+
+	t.Run("error on an invalid name", func(t *testing.T) {
+		_, err := ParseTerraformEnv("'FOO=BAR': baz", false)
+		assert.Error(t, err)
+	})
+
+	t.Run("error on credentials unless allowed", func(t *testing.T) {
+		input := "TF_TOKEN_app_terraform_io: abc"
+
+		_, err := ParseTerraformEnv(input, false)
+		assert.ErrorContains(t, err, "allow_credentials_env")
+
+		env, err := ParseTerraformEnv(input, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, "abc", env["TF_TOKEN_app_terraform_io"])
+	})
+}
+
+func TestSetPluginCacheDir(t *testing.T) {
+	t.Run("create the directory", func(t *testing.T) {
+		dir := path.Join(t.TempDir(), "cache", "plugins")
+		env := map[string]string{}
+
+		require.NoError(t, SetPluginCacheDir(env, dir))
+
+		assert.Equal(t, dir, env["TF_PLUGIN_CACHE_DIR"])
+		assert.DirExists(t, dir)
+	})
+
+	t.Run("error when env sets the directory", func(t *testing.T) {
+		env := map[string]string{"TF_PLUGIN_CACHE_DIR": "/tmp/foo"}
+
+		assert.Error(t, SetPluginCacheDir(env, t.TempDir()))
+	})
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// closestVersionCount is the number of available Terraform versions suggested when a requested version is not available
+const closestVersionCount = 3
+
+// terraformVersionList is the subset of the admin Terraform version list API response
+type terraformVersionList struct {
+	Data []struct {
+		Attributes struct {
+			Version string `json:"version"`
+			Enabled bool   `json:"enabled"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			NextPage int `json:"next-page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// FetchTerraformVersions returns the enabled Terraform versions of the Terraform Enterprise instance.
+// The admin API is only available to site admin tokens in Terraform Enterprise, so accessible is false when the API answers with any client error.
+func FetchTerraformVersions(ctx context.Context, httpClient *http.Client, address string, token string) (versions []string, accessible bool, err error) {
+	for page := 1; page != 0; {
+		var list terraformVersionList
+
+		status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("admin/terraform-versions?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", page, maxPageSize), &list)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list Terraform versions: %w", err)
+		}
+
+		if status >= 400 && status < 500 {
+			return nil, false, nil
+		}
+
+		if status != http.StatusOK {
+			return nil, false, fmt.Errorf("failed to list Terraform versions: %d %s", status, http.StatusText(status))
+		}
+
+		for _, v := range list.Data {
+			if v.Attributes.Enabled {
+				versions = append(versions, v.Attributes.Version)
+			}
+		}
+
+		page = list.Meta.Pagination.NextPage
+	}
+
+	return versions, true, nil
+}
+
+// ValidateTerraformVersions returns an error for the first requested version that is not available, listing the closest available versions.
+// Version constraints and other values that are not exact versions are not checked.
+func ValidateTerraformVersions(requested []string, available []string) error {
+	availableVersions := []*version.Version{}
+
+	for _, a := range available {
+		if v, err := version.NewVersion(a); err == nil {
+			availableVersions = append(availableVersions, v)
+		}
+	}
+
+	sort.Sort(version.Collection(availableVersions))
+
+	for _, r := range requested {
+		v, err := version.NewVersion(r)
+		if err != nil || v.String() != strings.TrimPrefix(r, "v") {
+			continue
+		}
+
+		found := false
+
+		for _, a := range availableVersions {
+			if a.Equal(v) {
+				found = true
+				break
+			}
+		}
+
+		if !found && len(availableVersions) == 0 {
+			return fmt.Errorf("terraform version %s is not available, no Terraform versions are enabled", r)
+		}
+
+		if !found {
+			return fmt.Errorf("terraform version %s is not available, the closest available versions are %s", r, strings.Join(closestVersions(v, availableVersions, closestVersionCount), ", "))
+		}
+	}
+
+	return nil
+}
+
+// closestVersions returns up to count of the sorted available versions nearest to the passed version, in ascending order
+func closestVersions(v *version.Version, available []*version.Version, count int) []string {
+	i := sort.Search(len(available), func(i int) bool {
+		return available[i].GreaterThan(v)
+	})
+
+	// take the versions around the insertion point, preferring newer versions
+	start := i - count/2
+	if start > len(available)-count {
+		start = len(available) - count
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	end := start + count
+	if end > len(available) {
+		end = len(available)
+	}
+
+	names := []string{}
+	for _, a := range available[start:end] {
+		names = append(names, a.Original())
+	}
+
+	return names
+}
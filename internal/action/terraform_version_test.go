@@ -0,0 +1,103 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchTerraformVersions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("list the enabled versions", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		defer server.Close()
+
+		mux.HandleFunc("/api/v2/admin/terraform-versions", testServerResHandler(t, 200, `{"data": [
+			{"id": "tool-1", "type": "terraform-versions", "attributes": {"version": "1.2.9", "enabled": true}},
+			{"id": "tool-2", "type": "terraform-versions", "attributes": {"version": "1.3.0", "enabled": false}},
+			{"id": "tool-3", "type": "terraform-versions", "attributes": {"version": "1.3.1", "enabled": true}}
+		]}`))
+
+		versions, accessible, err := FetchTerraformVersions(ctx, http.DefaultClient, server.URL, "12345")
+		require.NoError(t, err)
+
+		assert.True(t, accessible)
+		assert.Equal(t, []string{"1.2.9", "1.3.1"}, versions)
+	})
+
+	t.Run("skip when the admin API is not accessible", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		defer server.Close()
+
+		mux.HandleFunc("/api/v2/admin/terraform-versions", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+		versions, accessible, err := FetchTerraformVersions(ctx, http.DefaultClient, server.URL, "12345")
+		require.NoError(t, err)
+
+		assert.False(t, accessible)
+		assert.Empty(t, versions)
+	})
+
+	t.Run("skip when the token is forbidden from the admin API", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		defer server.Close()
+
+		mux.HandleFunc("/api/v2/admin/terraform-versions", testServerResHandler(t, 403, `{"errors": [{"status": "403", "title": "forbidden"}]}`))
+
+		versions, accessible, err := FetchTerraformVersions(ctx, http.DefaultClient, server.URL, "12345")
+		require.NoError(t, err)
+
+		assert.False(t, accessible)
+		assert.Empty(t, versions)
+	})
+
+	t.Run("fail on server errors", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+
+		defer server.Close()
+
+		mux.HandleFunc("/api/v2/admin/terraform-versions", testServerResHandler(t, 500, `{"errors": [{"status": "500", "title": "internal error"}]}`))
+
+		_, _, err := FetchTerraformVersions(ctx, http.DefaultClient, server.URL, "12345")
+		assert.EqualError(t, err, "failed to list Terraform versions: 500 Internal Server Error")
+	})
+}
+
+func TestValidateTerraformVersions(t *testing.T) {
+	available := []string{"1.3.1", "1.1.9", "1.2.9", "1.3.0", "1.4.0", "1.0.11"}
+
+	t.Run("accept available versions", func(t *testing.T) {
+		assert.NoError(t, ValidateTerraformVersions([]string{"1.3.0", "1.0.11"}, available))
+	})
+
+	t.Run("skip version constraints", func(t *testing.T) {
+		assert.NoError(t, ValidateTerraformVersions([]string{"~> 1.3", "latest"}, available))
+	})
+
+	t.Run("list the closest versions", func(t *testing.T) {
+		err := ValidateTerraformVersions([]string{"1.3.0", "1.2.5"}, available)
+		assert.EqualError(t, err, "terraform version 1.2.5 is not available, the closest available versions are 1.1.9, 1.2.9, 1.3.0")
+	})
+
+	t.Run("list the newest versions for a newer version", func(t *testing.T) {
+		err := ValidateTerraformVersions([]string{"1.9.0"}, available)
+		assert.EqualError(t, err, "terraform version 1.9.0 is not available, the closest available versions are 1.3.0, 1.3.1, 1.4.0")
+	})
+
+	t.Run("error when no versions are enabled", func(t *testing.T) {
+		err := ValidateTerraformVersions([]string{"1.3.0"}, nil)
+		assert.EqualError(t, err, "terraform version 1.3.0 is not available, no Terraform versions are enabled")
+	})
+}
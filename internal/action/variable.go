@@ -3,22 +3,86 @@ package action
 import (
 	"context"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 
 	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/sethvargo/go-githubactions"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
 )
 
+// ParseSensitiveKeyPatterns compiles the passed regular expressions of sensitive variable keys
+func ParseSensitiveKeyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensitive key pattern %q: %w", p, err)
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
 type VariablesInput []VariablesInputItem
 
+// variablesGroup is the object form of VariablesInput, with a category applied to its variables that do not set one
+type variablesGroup struct {
+	Category  string               `yaml:"category"`
+	Variables []VariablesInputItem `yaml:"variables"`
+}
+
+// UnmarshalYAML decodes either a list of variables or a group of variables with a default category
+func (vi *VariablesInput) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if _, ok := raw.(map[interface{}]interface{}); !ok {
+		var items []VariablesInputItem
+		if err := unmarshal(&items); err != nil {
+			return err
+		}
+
+		*vi = items
+
+		return nil
+	}
+
+	var group variablesGroup
+	if err := unmarshal(&group); err != nil {
+		return err
+	}
+
+	for i := range group.Variables {
+		if group.Variables[i].Category == "" {
+			group.Variables[i].Category = group.Category
+		}
+	}
+
+	*vi = group.Variables
+
+	return nil
+}
+
 type WorkspaceVariablesInput map[string]VariablesInput
 
 type VariablesInputItem struct {
 	Key         string `yaml:"key"`
 	Value       string `yaml:"value"`
+	ValueFrom   string `yaml:"value_from,omitempty"`
 	Description string `yaml:"description,omitempty"`
 	Category    string `yaml:"category,omitempty"`
 	Sensitive   bool   `yaml:"sensitive,omitempty"`
+	HCL         bool   `yaml:"hcl,omitempty"`
+	Raw         bool   `yaml:"raw,omitempty"`
 }
 
 type Variables []Variable
@@ -29,19 +93,83 @@ type Variable struct {
 	Description string
 	Category    string
 	Sensitive   bool
+	HCL         bool
+	Raw         bool
 	Workspace   *Workspace
 }
 
-// NewVariable creates a new Variable struct
-func NewVariable(vi VariablesInputItem, w *Workspace) *Variable {
-	return &Variable{
+// NewVariable creates a new Variable struct, resolving the value from the environment if "value_from" is set.
+// Variables with a key matching any of the passed sensitive key patterns are marked sensitive even if not set as sensitive.
+func NewVariable(vi VariablesInputItem, w *Workspace, sensitiveKeyPatterns []*regexp.Regexp) (*Variable, error) {
+	v := &Variable{
 		Key:         vi.Key,
 		Value:       vi.Value,
 		Description: vi.Description,
 		Category:    vi.Category,
 		Sensitive:   vi.Sensitive,
+		HCL:         vi.HCL,
+		Raw:         vi.Raw,
 		Workspace:   w,
 	}
+
+	if vi.ValueFrom != "" {
+		if vi.Value != "" {
+			return nil, fmt.Errorf("variable %q cannot set both value and value_from", vi.Key)
+		}
+
+		value, ok := os.LookupEnv(vi.ValueFrom)
+		if !ok {
+			return nil, fmt.Errorf("variable %q references environment variable %q, which is not set", vi.Key, vi.ValueFrom)
+		}
+
+		v.Value = value
+		v.Sensitive = true
+	}
+
+	if !v.Sensitive {
+		for _, re := range sensitiveKeyPatterns {
+			if re.MatchString(v.Key) {
+				githubactions.Infof("Marking variable %q sensitive, its key matches sensitive key pattern %q\n", v.Key, re.String())
+
+				v.Sensitive = true
+
+				break
+			}
+		}
+	}
+
+	if v.HCL {
+		if err := ValidateHCL(v.Key, v.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	if vi.Raw {
+		// a heredoc's closing marker must be followed by a newline, which YAML block scalars strip
+		expr := v.Value
+		if strings.Contains(expr, "\n") && !strings.HasSuffix(expr, "\n") {
+			expr += "\n"
+		}
+
+		if err := ValidateHCL(v.Key, expr); err != nil {
+			return nil, err
+		}
+
+		// values of the JSON configuration are string templates, wrapping the expression interpolates its result
+		v.Value = fmt.Sprintf("${%s}", expr)
+	}
+
+	return v, nil
+}
+
+// ValidateHCL checks that the value of an HCL variable is a syntactically valid HCL expression
+func ValidateHCL(key string, value string) error {
+	_, diags := hclsyntax.ParseExpression([]byte(value), key, hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("variable %q has an invalid HCL value: %s", key, diags.Error())
+	}
+
+	return nil
 }
 
 // MaskSensitive masks all sensitive variable values in the GitHub Actions log output
@@ -67,7 +195,8 @@ func (v Variable) ToResource() *tfeprovider.Variable {
 		Description: v.Description,
 		Category:    v.Category,
 		Sensitive:   v.Sensitive,
-		WorkspaceID: fmt.Sprintf("${tfe_workspace.workspace[%q].id}", v.Workspace.Workspace),
+		HCL:         v.HCL,
+		WorkspaceID: v.Workspace.IDRef(),
 	}
 }
 
@@ -79,20 +208,39 @@ func ToVariable(v *tfe.Variable, workspace *Workspace) *Variable {
 		Description: v.Description,
 		Category:    string(v.Category),
 		Sensitive:   v.Sensitive,
+		HCL:         v.HCL,
 		Workspace:   workspace,
 	}
 }
 
-// FetchRelatedVariables returns tfe.Variables related to the passed workspace
+// FetchRelatedVariables returns tfe.Variables related to the passed workspace, reading every page of the list
 func FetchRelatedVariables(ctx context.Context, client *tfe.Client, workspace *Workspace) ([]*tfe.Variable, error) {
-	vars, err := client.Variables.List(ctx, *workspace.ID, tfe.VariableListOptions{
-		ListOptions: tfe.ListOptions{
-			PageSize: maxPageSize,
-		},
-	})
-	if err != nil {
-		return nil, err
+	items := []*tfe.Variable{}
+
+	for page := 1; page != 0; {
+		var vars *tfe.VariableList
+
+		err := fetchPage(ctx, page, func() (err error) {
+			vars, err = client.Variables.List(ctx, *workspace.ID, tfe.VariableListOptions{
+				ListOptions: tfe.ListOptions{
+					PageNumber: page,
+					PageSize:   maxPageSize,
+				},
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, vars.Items...)
+
+		page = 0
+		if vars.Pagination != nil {
+			page = vars.Pagination.NextPage
+		}
 	}
 
-	return vars.Items, nil
+	return items, nil
 }
@@ -0,0 +1,120 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// variableSetList is the subset of the variable set list API response, which the go-tfe client does not support
+type variableSetList struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Name string `json:"name"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			NextPage int `json:"next-page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// variableSetVariableList is the subset of the variable set variables API response
+type variableSetVariableList struct {
+	Data []struct {
+		Attributes struct {
+			Key         string `json:"key"`
+			Value       string `json:"value"`
+			Description string `json:"description"`
+			Category    string `json:"category"`
+			Sensitive   bool   `json:"sensitive"`
+			HCL         bool   `json:"hcl"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			NextPage int `json:"next-page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// FetchVariableSetID returns the ID of the variable set of the passed organization with the passed name
+func FetchVariableSetID(ctx context.Context, httpClient *http.Client, address string, token string, organization string, name string) (string, error) {
+	for page := 1; page != 0; {
+		var sets variableSetList
+
+		status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("organizations/%s/varsets?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", url.PathEscape(organization), page, maxPageSize), &sets)
+		if err != nil {
+			return "", fmt.Errorf("failed to list the variable sets of organization %q: %w", organization, err)
+		}
+
+		if status != http.StatusOK {
+			return "", fmt.Errorf("failed to list the variable sets of organization %q: %d %s", organization, status, http.StatusText(status))
+		}
+
+		for _, s := range sets.Data {
+			if s.Attributes.Name == name {
+				return s.ID, nil
+			}
+		}
+
+		page = sets.Meta.Pagination.NextPage
+	}
+
+	return "", fmt.Errorf("variable set %q not found in organization %q", name, organization)
+}
+
+// VariableSetVariables returns the variables of the passed variable sets as variable inputs, to create them as workspace variables.
+// Sensitive values cannot be read back from the API, so sensitive variables are skipped and returned as warnings.
+func VariableSetVariables(ctx context.Context, httpClient *http.Client, address string, token string, organization string, names []string) (VariablesInput, []string, error) {
+	variables := VariablesInput{}
+	warnings := []string{}
+
+	for _, name := range names {
+		id, err := FetchVariableSetID(ctx, httpClient, address, token, organization, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for page := 1; page != 0; {
+			var vars variableSetVariableList
+
+			status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("varsets/%s/relationships/vars?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", url.PathEscape(id), page, maxPageSize), &vars)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list the variables of variable set %q: %w", name, err)
+			}
+
+			if status != http.StatusOK {
+				return nil, nil, fmt.Errorf("failed to list the variables of variable set %q: %d %s", name, status, http.StatusText(status))
+			}
+
+			for _, v := range vars.Data {
+				if v.Attributes.Sensitive {
+					warnings = append(warnings, fmt.Sprintf("variable %q of variable set %q is sensitive and cannot be read, skipping it", v.Attributes.Key, name))
+					continue
+				}
+
+				variables = append(variables, VariablesInputItem{
+					Key:         v.Attributes.Key,
+					Value:       escapeTemplate(v.Attributes.Value),
+					Description: v.Attributes.Description,
+					Category:    v.Attributes.Category,
+					HCL:         v.Attributes.HCL,
+				})
+			}
+
+			page = vars.Meta.Pagination.NextPage
+		}
+	}
+
+	return variables, warnings, nil
+}
+
+// escapeTemplate escapes the template sequences of a literal value, as variable values are rendered as Terraform string templates
+func escapeTemplate(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+}
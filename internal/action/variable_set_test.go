@@ -0,0 +1,43 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableSetVariables(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/organizations/org/varsets", testServerResHandler(t, 200, `{"data": [{"id": "varset-abc123", "type": "varsets", "attributes": {"name": "shared"}}], "meta": {"pagination": {"next-page": null}}}`))
+	mux.HandleFunc("/api/v2/varsets/varset-abc123/relationships/vars", testServerResHandler(t, 200, `{"data": [
+		{"id": "var-1", "type": "vars", "attributes": {"key": "region", "value": "us-east-1", "category": "terraform", "sensitive": false, "hcl": false}},
+		{"id": "var-2", "type": "vars", "attributes": {"key": "TEMPLATE", "value": "${foo}", "category": "env", "sensitive": false, "hcl": false}},
+		{"id": "var-3", "type": "vars", "attributes": {"key": "secret", "value": null, "category": "terraform", "sensitive": true, "hcl": false}}
+	]}`))
+
+	t.Run("copy non-sensitive variables", func(t *testing.T) {
+		vars, warnings, err := VariableSetVariables(ctx, http.DefaultClient, server.URL, "12345", "org", []string{"shared"})
+		require.NoError(t, err)
+
+		assert.Equal(t, VariablesInput{
+			{Key: "region", Value: "us-east-1", Category: "terraform"},
+			{Key: "TEMPLATE", Value: "$${foo}", Category: "env"},
+		}, vars)
+		assert.Equal(t, []string{"variable \"secret\" of variable set \"shared\" is sensitive and cannot be read, skipping it"}, warnings)
+	})
+
+	t.Run("error when the variable set does not exist", func(t *testing.T) {
+		_, _, err := VariableSetVariables(ctx, http.DefaultClient, server.URL, "12345", "org", []string{"missing"})
+		assert.ErrorContains(t, err, "not found")
+	})
+}
@@ -0,0 +1,258 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewVariable(t *testing.T) {
+	t.Run("create a variable from a literal value", func(t *testing.T) {
+		ws := newTestWorkspace()
+
+		v, err := NewVariable(VariablesInputItem{Key: "foo", Value: "bar", Category: "env"}, ws, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, &Variable{Key: "foo", Value: "bar", Category: "env", Workspace: ws}, v)
+	})
+
+	t.Run("resolve value_from from the environment and mark the variable sensitive", func(t *testing.T) {
+		t.Setenv("TEST_VARIABLE_SECRET", "secret")
+
+		ws := newTestWorkspace()
+
+		v, err := NewVariable(VariablesInputItem{Key: "foo", ValueFrom: "TEST_VARIABLE_SECRET", Category: "env"}, ws, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, &Variable{Key: "foo", Value: "secret", Category: "env", Sensitive: true, Workspace: ws}, v)
+	})
+
+	t.Run("error when the value_from environment variable is unset", func(t *testing.T) {
+		_, err := NewVariable(VariablesInputItem{Key: "foo", ValueFrom: "TEST_VARIABLE_UNSET"}, newTestWorkspace(), nil)
+		assert.EqualError(t, err, "variable \"foo\" references environment variable \"TEST_VARIABLE_UNSET\", which is not set")
+	})
+
+	t.Run("error when both value and value_from are set", func(t *testing.T) {
+		t.Setenv("TEST_VARIABLE_SECRET", "secret")
+
+		_, err := NewVariable(VariablesInputItem{Key: "foo", Value: "bar", ValueFrom: "TEST_VARIABLE_SECRET"}, newTestWorkspace(), nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("create an HCL variable with a valid value", func(t *testing.T) {
+		ws := newTestWorkspace()
+
+		v, err := NewVariable(VariablesInputItem{Key: "foo", Value: `{ bar = ["baz", "qux"] }`, Category: "terraform", HCL: true}, ws, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, &Variable{Key: "foo", Value: `{ bar = ["baz", "qux"] }`, Category: "terraform", HCL: true, Workspace: ws}, v)
+	})
+
+	t.Run("interpolate a raw variable value", func(t *testing.T) {
+		ws := newTestWorkspace()
+
+		v, err := NewVariable(VariablesInputItem{Key: "vpc_id", Value: "data.terraform_remote_state.network.outputs.vpc_id", Category: "terraform", Raw: true}, ws, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, &Variable{Key: "vpc_id", Value: "${data.terraform_remote_state.network.outputs.vpc_id}", Category: "terraform", Raw: true, Workspace: ws}, v)
+	})
+
+	t.Run("end a multiline raw variable value on its own line", func(t *testing.T) {
+		v, err := NewVariable(VariablesInputItem{Key: "policy", Value: "<<EOT\n{\"a\": \"b\"}\nEOT", Category: "terraform", Raw: true}, newTestWorkspace(), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, "{\"a\": \"b\"}\n", renderedTemplateValue(t, v.ToResource().Value))
+	})
+
+	t.Run("error when a raw variable value is not an expression", func(t *testing.T) {
+		_, err := NewVariable(VariablesInputItem{Key: "vpc_id", Value: "data.", Raw: true}, newTestWorkspace(), nil)
+		assert.ErrorContains(t, err, "variable \"vpc_id\" has an invalid HCL value")
+	})
+
+	t.Run("error when an HCL variable value cannot be parsed", func(t *testing.T) {
+		_, err := NewVariable(VariablesInputItem{Key: "foo", Value: `["bar", "baz"`, Category: "terraform", HCL: true}, newTestWorkspace(), nil)
+		assert.ErrorContains(t, err, "variable \"foo\" has an invalid HCL value: foo:1")
+	})
+
+	t.Run("mark variables with a key matching a sensitive key pattern sensitive", func(t *testing.T) {
+		patterns, err := ParseSensitiveKeyPatterns([]string{"(?i)token"})
+		assert.NoError(t, err)
+
+		v, err := NewVariable(VariablesInputItem{Key: "GITHUB_TOKEN", Value: "bar", Category: "env"}, newTestWorkspace(), patterns)
+		assert.NoError(t, err)
+		assert.True(t, v.Sensitive)
+
+		v, err = NewVariable(VariablesInputItem{Key: "region", Value: "us-east-1", Category: "env"}, newTestWorkspace(), patterns)
+		assert.NoError(t, err)
+		assert.False(t, v.Sensitive)
+	})
+}
+
+// renderedTemplateValue returns the value Terraform renders from a string of the JSON configuration, which is a string template
+func renderedTemplateValue(t *testing.T, value string) string {
+	b, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	var decoded string
+	require.NoError(t, json.Unmarshal(b, &decoded))
+
+	expr, diags := hclsyntax.ParseTemplate([]byte(decoded), "value", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	v, diags := expr.Value(nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	return v.AsString()
+}
+
+func TestVariableToResource(t *testing.T) {
+	t.Run("keep multiline values with quotes", func(t *testing.T) {
+		value := "-----BEGIN CERTIFICATE-----\nMIIB\\n\"quoted\"\n-----END CERTIFICATE-----\n"
+
+		v, err := NewVariable(VariablesInputItem{Key: "certificate", Value: value, Category: "env"}, newTestWorkspace(), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, value, renderedTemplateValue(t, v.ToResource().Value))
+	})
+
+	t.Run("keep escaped template sequences literal", func(t *testing.T) {
+		v, err := NewVariable(VariablesInputItem{Key: "script", Value: "#!/bin/sh\necho \"$${HOME}\"", Category: "env"}, newTestWorkspace(), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, "#!/bin/sh\necho \"${HOME}\"", renderedTemplateValue(t, v.ToResource().Value))
+	})
+
+	t.Run("keep multiline HCL values", func(t *testing.T) {
+		value := "{\n  greeting = \"hello\\nworld\"\n  tags     = [\"a\", \"b\"]\n}"
+
+		v, err := NewVariable(VariablesInputItem{Key: "config", Value: value, Category: "terraform", HCL: true}, newTestWorkspace(), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, value, renderedTemplateValue(t, v.ToResource().Value))
+	})
+}
+
+func TestParseSensitiveKeyPatterns(t *testing.T) {
+	t.Run("error on an invalid pattern", func(t *testing.T) {
+		_, err := ParseSensitiveKeyPatterns([]string{"("})
+		assert.ErrorContains(t, err, "invalid sensitive key pattern \"(\"")
+	})
+}
+
+func TestWorkspaceVariablesInputUnmarshal(t *testing.T) {
+	t.Run("decode both the list and the group form", func(t *testing.T) {
+		var wsVars WorkspaceVariablesInput
+
+		err := yaml.UnmarshalStrict([]byte(`
+staging:
+  - key: environment
+    value: staging
+    category: terraform
+production:
+  category: env
+  variables:
+    - key: REGION
+      value: us-east-1
+    - key: environment
+      value: production
+      category: terraform
+`), &wsVars)
+		require.NoError(t, err)
+
+		assert.Equal(t, WorkspaceVariablesInput{
+			"staging": {
+				{Key: "environment", Value: "staging", Category: "terraform"},
+			},
+			"production": {
+				{Key: "REGION", Value: "us-east-1", Category: "env"},
+				{Key: "environment", Value: "production", Category: "terraform"},
+			},
+		}, wsVars)
+	})
+
+	t.Run("error on unknown fields of a group", func(t *testing.T) {
+		var wsVars WorkspaceVariablesInput
+
+		err := yaml.UnmarshalStrict([]byte(`
+production:
+  categories: env
+  variables: []
+`), &wsVars)
+		assert.Error(t, err)
+	})
+}
+
+func TestFetchRelatedVariables(t *testing.T) {
+	ctx := context.Background()
+
+	retryInterval := pageRetryInterval
+	pageRetryInterval = time.Millisecond
+
+	t.Cleanup(func() {
+		pageRetryInterval = retryInterval
+	})
+
+	varsPage := func(key string, next int) string {
+		nextPage := "null"
+		if next != 0 {
+			nextPage = strconv.Itoa(next)
+		}
+
+		return fmt.Sprintf(`{"data": [{"id": "var-%s", "type": "vars", "attributes": {"key": %q, "value": "bar", "category": "terraform"}}], "meta": {"pagination": {"current-page": 1, "next-page": %s, "total-pages": 2, "total-count": 2}}}`, key, key, nextPage)
+	}
+
+	t.Run("continue from a page that failed after a rate limit", func(t *testing.T) {
+		requests := map[string]int{}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/vars", func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page[number]")
+			requests[page]++
+
+			switch {
+			case page == "1":
+				testServerResHandler(t, 200, varsPage("foo", 2))(w, r)
+			case requests[page] == 1:
+				testServerResHandler(t, 429, `{"errors": [{"status": "429", "title": "too many requests"}]}`)(w, r)
+			case requests[page] == 2:
+				testServerResHandler(t, 500, `{"errors": [{"status": "500", "title": "internal server error"}]}`)(w, r)
+			default:
+				testServerResHandler(t, 200, varsPage("baz", 0))(w, r)
+			}
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		vars, err := FetchRelatedVariables(ctx, newTestTFClient(t, server.URL), newTestWorkspace())
+		require.NoError(t, err)
+
+		require.Len(t, vars, 2)
+		assert.Equal(t, "foo", vars[0].Key)
+		assert.Equal(t, "baz", vars[1].Key)
+
+		assert.Equal(t, map[string]int{"1": 1, "2": 3}, requests)
+	})
+
+	t.Run("error when a page keeps failing", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/workspaces/ws-abc123/vars", testServerResHandler(t, 500, `{"errors": [{"status": "500", "title": "internal server error"}]}`))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		_, err := FetchRelatedVariables(ctx, newTestTFClient(t, server.URL), newTestWorkspace())
+		assert.ErrorContains(t, err, "failed to fetch page 1 after 3 attempts")
+	})
+}
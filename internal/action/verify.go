@@ -0,0 +1,54 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// VerifyWorkspaces checks that every passed workspace exists with the execution mode resolved from its settings and describes each problem found.
+// The execution mode is not checked if the expected mode is empty.
+func VerifyWorkspaces(ctx context.Context, client *tfe.Client, workspaces []*Workspace, organization string, options *WorkspaceResourceOptions) ([]string, error) {
+	problems := []string{}
+
+	for _, ws := range workspaces {
+		executionMode := expectedExecutionMode(options.ForWorkspace(ws.Workspace))
+
+		live, err := client.Workspaces.Read(ctx, workspaceOrganization(ws, organization), ws.Name)
+		if err != nil {
+			if errors.Is(err, tfe.ErrResourceNotFound) {
+				problems = append(problems, fmt.Sprintf("workspace %q does not exist", ws.Name))
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+		}
+
+		if executionMode != "" && live.ExecutionMode != executionMode {
+			problems = append(problems, fmt.Sprintf("workspace %q has execution mode %q, expected %q", ws.Name, live.ExecutionMode, executionMode))
+		}
+	}
+
+	return problems, nil
+}
+
+// expectedExecutionMode returns the execution mode of a workspace with the passed options, which is "agent" if an agent pool is set
+func expectedExecutionMode(options *WorkspaceResourceOptions) string {
+	if options.AgentPoolID != "" || options.AgentPoolName != "" {
+		return "agent"
+	}
+
+	return options.ExecutionMode
+}
+
+// VerificationSummary formats the result of a workspace verification as markdown for the job summary
+func VerificationSummary(workspaces []*Workspace, problems []string) string {
+	if len(problems) == 0 {
+		return fmt.Sprintf("### Workspace verification\n\nVerified %d workspaces", len(workspaces))
+	}
+
+	return fmt.Sprintf("### Workspace verification\n\n- %s", strings.Join(problems, "\n- "))
+}
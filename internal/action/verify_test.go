@@ -0,0 +1,65 @@
+package action
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWorkspaces(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/foo-staging", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo-staging", "execution-mode": "remote"}}}`))
+	mux.HandleFunc("/api/v2/organizations/org/workspaces/foo-production", testServerResHandler(t, 404, `{"errors": [{"status": "404", "title": "not found"}]}`))
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("return no problems when the workspaces exist with the expected execution mode", func(t *testing.T) {
+		problems, err := VerifyWorkspaces(ctx, client, []*Workspace{{Name: "foo-staging", Workspace: "staging"}}, "org", &WorkspaceResourceOptions{ExecutionMode: "remote"})
+		require.NoError(t, err)
+
+		assert.Empty(t, problems)
+	})
+
+	t.Run("return missing workspaces and unexpected execution modes", func(t *testing.T) {
+		problems, err := VerifyWorkspaces(ctx, client, []*Workspace{
+			{Name: "foo-staging", Workspace: "staging"},
+			{Name: "foo-production", Workspace: "production"},
+		}, "org", &WorkspaceResourceOptions{AgentPoolName: "pool"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			`workspace "foo-staging" has execution mode "remote", expected "agent"`,
+			`workspace "foo-production" does not exist`,
+		}, problems)
+	})
+
+	t.Run("expect the execution mode of each workspace", func(t *testing.T) {
+		problems, err := VerifyWorkspaces(ctx, client, []*Workspace{{Name: "foo-staging", Workspace: "staging"}}, "org", &WorkspaceResourceOptions{
+			ExecutionMode: "local",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {ExecutionMode: tfe.String("remote")},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, problems)
+	})
+}
+
+func TestVerificationSummary(t *testing.T) {
+	workspaces := []*Workspace{{Name: "foo-staging"}, {Name: "foo-production"}}
+
+	assert.Equal(t, "### Workspace verification\n\nVerified 2 workspaces", VerificationSummary(workspaces, nil))
+	assert.Equal(t, "### Workspace verification\n\n- workspace \"foo-production\" does not exist", VerificationSummary(workspaces, []string{`workspace "foo-production" does not exist`}))
+}
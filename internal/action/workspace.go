@@ -6,11 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/sethvargo/go-githubactions"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfconfig"
 	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
 )
@@ -19,6 +26,26 @@ type Workspace struct {
 	Name      string
 	Workspace string
 	ID        *string
+
+	// Organization overrides the organization the workspace is managed in
+	Organization string
+
+	// Standalone renders the workspace as a single resource instead of an instance of the for_each workspace resource
+	Standalone bool
+}
+
+// Address returns the Terraform resource address of the workspace
+func (ws Workspace) Address() string {
+	if ws.Standalone {
+		return "tfe_workspace.workspace"
+	}
+
+	return fmt.Sprintf("tfe_workspace.workspace[%q]", ws.Workspace)
+}
+
+// IDRef returns an interpolated reference to the ID of the workspace resource
+func (ws Workspace) IDRef() string {
+	return fmt.Sprintf("${%s.id}", ws.Address())
 }
 
 // getVCSClientByName looks for a VCS client of the passed type against the VCS clients in the Terraform Cloud organization
@@ -55,77 +82,319 @@ func GetVCSTokenIDByClientType(ctx context.Context, tfc *tfe.Client, organizatio
 	return vcsClient.OAuthTokens[0].ID, nil
 }
 
+// GetAgentPoolIDByName returns the ID of the agent pool matching the passed name in the Terraform Cloud organization
+func GetAgentPoolIDByName(ctx context.Context, tfc *tfe.Client, organization string, name string) (string, error) {
+	list, err := tfc.AgentPools.List(ctx, organization, tfe.AgentPoolListOptions{
+		ListOptions: tfe.ListOptions{
+			PageSize: maxPageSize,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range list.Items {
+		if p.Name == name {
+			return p.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no agent pool found with name %q", name)
+}
+
 type WorkspaceResourceOptions struct {
-	AgentPoolID            string
-	AutoApply              *bool
-	Description            string
-	ExecutionMode          string
-	FileTriggersEnabled    *bool
-	GlobalRemoteState      *bool
-	Organization           string
-	QueueAllRuns           *bool
-	RemoteStateConsumerIDs string
-	SpeculativeEnabled     *bool
-	SSHKeyID               string
-	Tags                   map[string]Tags
-	TerraformVersion       string
-	VCSIngressSubmodules   bool
-	VCSRepo                string
-	VCSTokenID             string
-	VCSType                string
-	WorkingDirectory       string
-}
-
-// NewWorkspaceResource adds defaults and conditional fields to a WorkspaceWorkspaceResource struct
-func NewWorkspaceResource(ctx context.Context, client *tfe.Client, workspaces []*Workspace, config *WorkspaceResourceOptions) (*tfeprovider.Workspace, error) {
-	wsForEach := map[string]*tfeprovider.Workspace{}
+	AgentPoolID                string
+	AgentPoolName              string
+	AssessmentsEnabled         *bool
+	AutoApply                  *bool
+	AutoDestroyAt              string
+	Description                string
+	ExecutionMode              string
+	FileTriggersEnabled        *bool
+	GlobalRemoteState          *bool
+	Organization               string
+	ProviderOrganization       string
+	QueueAllRuns               *bool
+	RemoteStateConsumerIDs     string
+	SpeculativeEnabled         *bool
+	StructuredRunOutputEnabled *bool
+	SSHKeyID                   string
+	Tags                       map[string]Tags
+	TerraformVersion           string
+	TriggerPatterns            []string
+	TriggerPrefixes            []string
+	TriggerTagsRegex           string
+	VCSIngressSubmodules       bool
+	VCSRepo                    string
+	VCSTokenID                 string
+	VCSType                    string
+	WorkingDirectory           string
+	WorkspaceSettings          map[string]WorkspaceSettings
+}
+
+// workspaceOrganization returns the organization of the passed workspace, falling back to the passed default organization
+func workspaceOrganization(ws *Workspace, organization string) string {
+	if ws.Organization != "" {
+		return ws.Organization
+	}
+
+	return organization
+}
+
+// distinctOrganizations returns the sorted, distinct organizations of the passed workspaces
+func distinctOrganizations(workspaces []*Workspace, organization string) []string {
+	seen := map[string]bool{}
+	orgs := []string{}
 
 	for _, ws := range workspaces {
-		wsForEach[ws.Workspace] = &tfeprovider.Workspace{
-			Name: ws.Name,
+		org := workspaceOrganization(ws, organization)
+
+		if !seen[org] {
+			seen[org] = true
+			orgs = append(orgs, org)
+		}
+	}
+
+	sort.Strings(orgs)
+
+	return orgs
+}
+
+// validateWorkingDirectory returns an error if the working directory is not relative to the root of the repository
+func validateWorkingDirectory(dir string) error {
+	if strings.HasPrefix(dir, "/") {
+		return fmt.Errorf("working directory %q must be relative to the root of the repository and cannot start with \"/\"", dir)
+	}
+
+	return nil
+}
+
+// validateTriggers returns an error if the trigger patterns or prefixes cannot be applied, which Terraform Cloud otherwise rejects with an unclear error during the apply
+func validateTriggers(config *WorkspaceResourceOptions) error {
+	if config.TriggerTagsRegex != "" {
+		return validateTagTriggers(config)
+	}
+
+	if len(config.TriggerPatterns) == 0 && len(config.TriggerPrefixes) == 0 {
+		return nil
+	}
+
+	if len(config.TriggerPatterns) > 0 && len(config.TriggerPrefixes) > 0 {
+		return fmt.Errorf("trigger patterns and trigger prefixes cannot both be set")
+	}
+
+	if config.FileTriggersEnabled != nil && !*config.FileTriggersEnabled {
+		return fmt.Errorf("trigger patterns and trigger prefixes require file triggers, they cannot be set when file_triggers_enabled is false")
+	}
+
+	for _, pattern := range config.TriggerPatterns {
+		// the whole pattern is checked for syntax errors, regardless of the matched path
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid trigger pattern %q: %w", pattern, err)
 		}
 	}
 
+	return nil
+}
+
+// validateTagTriggers returns an error if a tag trigger regular expression is invalid or combined with settings it is incompatible with
+func validateTagTriggers(config *WorkspaceResourceOptions) error {
+	if _, err := regexp.Compile(config.TriggerTagsRegex); err != nil {
+		return fmt.Errorf("invalid trigger tags regular expression: %w", err)
+	}
+
+	if len(config.TriggerPatterns) > 0 || len(config.TriggerPrefixes) > 0 {
+		return fmt.Errorf("trigger tags cannot be set with trigger patterns or trigger prefixes")
+	}
+
+	if config.FileTriggersEnabled != nil && *config.FileTriggersEnabled {
+		return fmt.Errorf("trigger tags replace file triggers, they cannot be set when file_triggers_enabled is true")
+	}
+
+	if config.VCSType == "" && config.VCSTokenID == "" {
+		return fmt.Errorf("trigger tags require a VCS integration, vcs_type or vcs_token_id must be set")
+	}
+
+	return nil
+}
+
+// NewWorkspaceResource adds defaults and conditional fields to a WorkspaceWorkspaceResource struct.
+// When the workspaces span multiple organizations, organization specific attributes are set per workspace in the for_each map.
+func NewWorkspaceResource(ctx context.Context, client *tfe.Client, workspaces []*Workspace, config *WorkspaceResourceOptions) (*tfeprovider.Workspace, error) {
+	if err := validateWorkingDirectory(config.WorkingDirectory); err != nil {
+		return nil, err
+	}
+
+	if err := validateWorkspaceSettings(workspaces, config.WorkspaceSettings); err != nil {
+		return nil, err
+	}
+
+	// a standalone workspace is rendered without for_each, so its own settings are applied directly
+	if len(workspaces) == 1 && workspaces[0].Standalone {
+		config = config.ForWorkspace(workspaces[0].Workspace)
+	}
+
 	ws := &tfeprovider.Workspace{
-		ForEach:      wsForEach,
-		Name:         "${each.value.name}",
 		Organization: config.Organization,
 	}
 
+	orgs := distinctOrganizations(workspaces, config.Organization)
+	multiOrg := len(orgs) > 1
+
+	// VCS tokens and SSH keys belong to a single organization, so an explicit ID cannot be used for workspaces in several
+	if multiOrg && config.VCSTokenID != "" {
+		return nil, fmt.Errorf("vcs_token_id cannot be used with workspaces in multiple organizations, use vcs_type to look up the VCS token of each organization")
+	}
+
+	if multiOrg && config.SSHKeyID != "" {
+		return nil, fmt.Errorf("ssh_key_id cannot be used with workspaces in multiple organizations")
+	}
+
+	if len(orgs) == 1 {
+		ws.Organization = orgs[0]
+	}
+
+	// the default organization of the provider applies to workspaces without one
+	if ws.Organization == config.ProviderOrganization {
+		ws.Organization = ""
+	}
+
 	if config.AutoApply != nil {
 		ws.AutoApply = config.AutoApply
 	}
 
 	var vcs *tfeprovider.VCSRepo
 
+	// local execution runs Terraform outside of Terraform Cloud, so the execution mode of each workspace takes precedence over a VCS integration, which would never queue runs
+	localWorkspaces := map[string]bool{}
+
 	if config.VCSType != "" || config.VCSTokenID != "" {
+		for _, w := range workspaces {
+			if c := config.ForWorkspace(w.Workspace); c.ExecutionMode == "local" && c.AgentPoolID == "" && c.AgentPoolName == "" {
+				githubactions.Warningf("Execution mode of workspace %q is local, skipping its VCS integration\n", w.Workspace)
+				localWorkspaces[w.Workspace] = true
+			}
+		}
+	}
+
+	if (config.VCSType != "" || config.VCSTokenID != "") && (len(workspaces) == 0 || len(localWorkspaces) < len(workspaces)) {
 		if config.VCSRepo == "" {
 			return nil, fmt.Errorf("VCS repository must be passed if VCS type or a VCS token ID is passed")
 		}
 
-		vcsTokenID := config.VCSTokenID
-		if vcsTokenID == "" {
-			t, err := GetVCSTokenIDByClientType(ctx, client, config.Organization, config.VCSType)
+		vcs = &tfeprovider.VCSRepo{
+			Identifier:        config.VCSRepo,
+			IngressSubmodules: config.VCSIngressSubmodules,
+			TagsRegex:         config.TriggerTagsRegex,
+		}
+	}
+
+	if config.AgentPoolID != "" && config.AgentPoolName != "" {
+		return nil, fmt.Errorf("agent pool ID and agent pool name cannot both be set")
+	}
+
+	if err := validateTriggers(config); err != nil {
+		return nil, err
+	}
+
+	// the VCS token and agent pool of each organization are looked up once, however many of its workspaces use them
+	vcsTokenIDs := map[string]string{}
+	agentPoolIDs := map[string]string{}
+
+	for _, org := range orgs {
+		if vcs != nil {
+			vcsTokenIDs[org] = config.VCSTokenID
+
+			if config.VCSTokenID == "" {
+				t, err := GetVCSTokenIDByClientType(ctx, client, org, config.VCSType)
+				if err != nil {
+					return nil, err
+				}
+
+				vcsTokenIDs[org] = t
+			}
+		}
+
+		agentPoolIDs[org] = config.AgentPoolID
+
+		if config.AgentPoolName != "" {
+			id, err := GetAgentPoolIDByName(ctx, client, org, config.AgentPoolName)
 			if err != nil {
 				return nil, err
 			}
 
-			vcsTokenID = t
-		} else {
-			vcsTokenID = config.VCSTokenID
+			agentPoolIDs[org] = id
 		}
+	}
 
-		vcs = &tfeprovider.VCSRepo{
-			OauthTokenID:      vcsTokenID,
-			Identifier:        config.VCSRepo,
-			IngressSubmodules: config.VCSIngressSubmodules,
+	if len(workspaces) == 1 && workspaces[0].Standalone {
+		ws.Name = workspaces[0].Name
+	} else {
+		wsForEach := map[string]*tfeprovider.Workspace{}
+
+		for _, w := range workspaces {
+			entry := &tfeprovider.Workspace{
+				Name: w.Name,
+			}
+
+			org := workspaceOrganization(w, config.Organization)
+
+			if multiOrg {
+				entry.Organization = org
+				entry.AgentPoolID = agentPoolIDs[org]
+			}
+
+			if vcs != nil && !localWorkspaces[w.Workspace] && (multiOrg || len(localWorkspaces) > 0) {
+				entry.VCSRepo = &tfeprovider.VCSRepo{
+					OauthTokenID:      vcsTokenIDs[org],
+					Identifier:        vcs.Identifier,
+					IngressSubmodules: vcs.IngressSubmodules,
+					TagsRegex:         vcs.TagsRegex,
+				}
+			}
+
+			wsForEach[w.Workspace] = entry
+		}
+
+		ws.ForEach = wsForEach
+		ws.Name = "${each.value.name}"
+	}
+
+	if multiOrg {
+		ws.Organization = "${each.value.organization}"
+
+		if vcs != nil {
+			vcs.OauthTokenID = "${each.value.vcs_repo.oauth_token_id}"
 		}
+
+		if config.AgentPoolID != "" || config.AgentPoolName != "" {
+			ws.AgentPoolID = "${each.value.agent_pool_id}"
+		}
+	} else if len(orgs) == 1 {
+		if vcs != nil {
+			vcs.OauthTokenID = vcsTokenIDs[orgs[0]]
+		}
+
+		ws.AgentPoolID = agentPoolIDs[orgs[0]]
 	}
 
-	ws.VCSRepo = vcs
+	if vcs != nil && len(localWorkspaces) > 0 {
+		// only workspaces without local execution have a vcs_repo entry, so the block is rendered dynamically from it
+		vcs.OauthTokenID = "${vcs_repo.value.oauth_token_id}"
 
-	if config.AgentPoolID != "" {
-		ws.AgentPoolID = config.AgentPoolID
+		ws.DynamicVCSRepo = &tfeprovider.DynamicVCSRepo{
+			VCSRepo: []tfeprovider.DynamicVCSRepoEntry{
+				{
+					ForEach: "${lookup(each.value, \"vcs_repo\", null) == null ? [] : [each.value.vcs_repo]}",
+					Content: vcs,
+				},
+			},
+		}
+	} else {
+		ws.VCSRepo = vcs
+	}
+
+	if config.AgentPoolID != "" || config.AgentPoolName != "" {
 		ws.ExecutionMode = "agent"
 	} else if config.ExecutionMode != "" {
 		ws.ExecutionMode = config.ExecutionMode
@@ -139,16 +408,46 @@ func NewWorkspaceResource(ctx context.Context, client *tfe.Client, workspaces []
 		}
 	}
 
+	ws.AssessmentsEnabled = config.AssessmentsEnabled
+	ws.AutoDestroyAt = config.AutoDestroyAt
 	ws.Description = config.Description
 	ws.TerraformVersion = config.TerraformVersion
+
+	// the provider queues the first run of a new workspace unless disabled, which fails as no configuration has been uploaded yet.
+	// Existing workspaces keep their live value through their workspace settings when the input is not set, as the provider updates it in place.
 	ws.QueueAllRuns = config.QueueAllRuns
-	ws.SpeculativeEnabled = config.SpeculativeEnabled
+	if ws.QueueAllRuns == nil {
+		ws.QueueAllRuns = tfe.Bool(false)
+	}
+
+	// assigned only if set, as a nil pointer in the interface would be rendered as null
+	if config.SpeculativeEnabled != nil {
+		ws.SpeculativeEnabled = config.SpeculativeEnabled
+	}
+
+	ws.StructuredRunOutputEnabled = config.StructuredRunOutputEnabled
 	ws.FileTriggersEnabled = config.FileTriggersEnabled
+
+	// tag triggers only queue runs when file triggers are disabled
+	if config.TriggerTagsRegex != "" && ws.FileTriggersEnabled == nil {
+		ws.FileTriggersEnabled = tfe.Bool(false)
+	}
+
+	ws.TriggerPatterns = config.TriggerPatterns
+	ws.TriggerPrefixes = config.TriggerPrefixes
 	ws.SSHKeyID = config.SSHKeyID
 	ws.WorkingDirectory = config.WorkingDirectory
 
-	if err := SetTags(ws, config.Tags); err != nil {
-		return nil, err
+	if len(workspaces) == 1 && workspaces[0].Standalone {
+		if tags, ok := config.Tags[workspaces[0].Workspace]; ok && len(tags) > 0 {
+			ws.TagNames = tags
+		}
+	} else {
+		if err := SetTags(ws, config.Tags); err != nil {
+			return nil, err
+		}
+
+		setWorkspaceSettings(ws, workspaces, config)
 	}
 
 	return ws, nil
@@ -185,6 +484,29 @@ func SetTags(module *tfeprovider.Workspace, tags map[string]Tags) error {
 	return nil
 }
 
+// invalidTagChars matches characters not allowed in Terraform Cloud workspace tags
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9:_-]+`)
+
+// SanitizeTag lowercases the passed tag and replaces characters not allowed in workspace tags with hyphens
+func SanitizeTag(tag string) Tag {
+	return Tag(strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(tag), "-"), "-"))
+}
+
+// GitMetadataTags returns tags for the repository and commit SHA of the GitHub Actions run, skipping values missing from the environment
+func GitMetadataTags() Tags {
+	tags := Tags{}
+
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		tags = append(tags, SanitizeTag(fmt.Sprintf("repo:%s", repo)))
+	}
+
+	if sha := os.Getenv("GITHUB_SHA"); sha != "" {
+		tags = append(tags, SanitizeTag(fmt.Sprintf("sha:%s", sha)))
+	}
+
+	return tags
+}
+
 // MergeWorkspaceTags returns a map of tags by workspace
 func MergeWorkspaceTags(tags Tags, wsTags map[string]Tags, workspaces []*Workspace) (map[string]Tags, error) {
 	tagsByWorkspace := map[string]Tags{}
@@ -215,16 +537,24 @@ func AppendTeamAccess(module *tfconfig.Module, teamAccess TeamAccess, organizati
 	resourceForEach := map[string]tfeprovider.TeamAccess{}
 
 	for _, access := range teamAccess {
-		dataForEach[access.TeamName] = TeamDataResource{
+		org := workspaceOrganization(access.Workspace, organization)
+
+		// teams are looked up per organization, prefix teams of other organizations to keep their keys distinct
+		teamKey := access.TeamName
+		if org != organization {
+			teamKey = fmt.Sprintf("%s/%s", org, access.TeamName)
+		}
+
+		dataForEach[teamKey] = TeamDataResource{
 			Name:         access.TeamName,
-			Organization: organization,
+			Organization: org,
 		}
 
-		teamIDRef := fmt.Sprintf("${data.tfe_team.teams[\"%s\"].id}", access.TeamName)
+		teamIDRef := fmt.Sprintf("${data.tfe_team.teams[\"%s\"].id}", teamKey)
 
 		resourceForEach[fmt.Sprintf("%s-%s", access.Workspace.Workspace, teamIDRef)] = tfeprovider.TeamAccess{
 			TeamID:      teamIDRef,
-			WorkspaceID: fmt.Sprintf("${tfe_workspace.workspace[%q].id}", access.Workspace.Workspace),
+			WorkspaceID: access.Workspace.IDRef(),
 			Access:      access.Access,
 			Permissions: access.ToResource().Permissions,
 		}
@@ -257,6 +587,40 @@ func AppendTeamAccess(module *tfconfig.Module, teamAccess TeamAccess, organizati
 	})
 }
 
+// AppendWorkspaceRuns adds a run to each workspace, queued once when the run resource is created, after the workspace variables are set
+func AppendWorkspaceRuns(module *tfconfig.Module, workspaces []*Workspace, variables Variables, options *tfeprovider.WorkspaceRunOptions) {
+	if options == nil || len(workspaces) == 0 {
+		return
+	}
+
+	// variables sharing a workspace and key map to the same resource, which is listed once
+	seen := map[string]bool{}
+	dependsOn := []string{}
+
+	for _, v := range variables {
+		addr := fmt.Sprintf("tfe_variable.%s-%s", v.Workspace.Workspace, v.Key)
+
+		if !seen[addr] {
+			seen[addr] = true
+			dependsOn = append(dependsOn, addr)
+		}
+	}
+
+	sort.Strings(dependsOn)
+
+	runForEach := map[string]tfeprovider.WorkspaceRun{}
+	for _, ws := range workspaces {
+		runForEach[ws.Workspace] = tfeprovider.WorkspaceRun{WorkspaceID: ws.IDRef()}
+	}
+
+	module.AppendResource("tfe_workspace_run", "run", tfeprovider.WorkspaceRun{
+		ForEach:     runForEach,
+		WorkspaceID: "${each.value.workspace_id}",
+		Apply:       options,
+		DependsOn:   dependsOn,
+	})
+}
+
 type NewWorkspaceConfigOptions struct {
 	Backend                  map[string]interface{}
 	WorkspaceVariables       map[string]tfconfig.Variable
@@ -267,6 +631,8 @@ type NewWorkspaceConfigOptions struct {
 	Notifications            []*Notification
 	WorkspaceResourceOptions *WorkspaceResourceOptions
 	Providers                []Provider
+	Moved                    []tfconfig.Moved
+	WorkspaceRun             *tfeprovider.WorkspaceRunOptions
 }
 
 func NewModule() *tfconfig.Module {
@@ -307,10 +673,14 @@ func NewWorkspaceConfig(ctx context.Context, client *tfe.Client, workspaces []*W
 
 	AppendRunTriggers(module, config.RunTriggers)
 
-	AppendTeamAccess(module, config.TeamAccess, wsResource.Organization)
+	AppendTeamAccess(module, config.TeamAccess, config.WorkspaceResourceOptions.Organization)
+
+	AppendWorkspaceRuns(module, workspaces, config.Variables, config.WorkspaceRun)
 
 	AddProviders(module, config.Providers)
 
+	module.Moved = config.Moved
+
 	return module, nil
 }
 
@@ -328,19 +698,86 @@ func WriteModuleFile(module *tfconfig.Module, filePath string) error {
 	return nil
 }
 
-// TerraformInit updates the current configuration using the passed module and runs "terraform init"
-func TerraformInit(ctx context.Context, tf *tfexec.Terraform, module *tfconfig.Module, filePath string) error {
+// TerraformInit updates the current configuration using the passed module and runs "terraform init", retrying it up to the passed number of retries after transient network errors.
+// If the working directory is already initialized with the same backend, init runs with -reconfigure, skipping an unneeded state migration.
+func TerraformInit(ctx context.Context, tf *tfexec.Terraform, module *tfconfig.Module, filePath string, initRetries int) error {
 	if err := WriteModuleFile(module, filePath); err != nil {
 		return err
 	}
 
-	if err := tf.Init(ctx); err != nil {
+	unchanged, err := BackendUnchanged(tf.WorkingDir(), module.Terraform.Backend)
+	if err != nil {
+		return err
+	}
+
+	opts := []tfexec.InitOption{}
+
+	if unchanged {
+		githubactions.Infof("Backend configuration is unchanged, skipping state migration\n")
+		opts = append(opts, tfexec.Reconfigure(true))
+	}
+
+	if err := initWithRetry(ctx, tf, initRetries, opts...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// minProviderOrganizationVersion is the first tfe provider version accepting a default organization
+var minProviderOrganizationVersion = version.Must(version.NewVersion("0.42.0"))
+
+// minProviderWorkspaceRunVersion is the first tfe provider version with the tfe_workspace_run resource
+var minProviderWorkspaceRunVersion = version.Must(version.NewVersion("0.47.0"))
+
+// minProviderAutoDestroyAtVersion is the first tfe provider version with the auto_destroy_at attribute
+var minProviderAutoDestroyAtVersion = version.Must(version.NewVersion("0.52.0"))
+
+// minProviderTriggerPatternsVersion is the first tfe provider version with the trigger_patterns attribute
+var minProviderTriggerPatternsVersion = version.Must(version.NewVersion("0.36.0"))
+
+// minProviderTagsRegexVersion is the first tfe provider version with the tags_regex attribute of the VCS repository
+var minProviderTagsRegexVersion = version.Must(version.NewVersion("0.43.0"))
+
+// ValidateProviderOrganization returns an error if the passed tfe provider version does not accept a default organization. Version constraints are not checked.
+func ValidateProviderOrganization(providerVersion string) error {
+	return requireProviderVersion("tfe_provider_organization", providerVersion, minProviderOrganizationVersion)
+}
+
+// ValidateProviderWorkspaceRun returns an error if the passed tfe provider version does not have the tfe_workspace_run resource. Version constraints are not checked.
+func ValidateProviderWorkspaceRun(providerVersion string) error {
+	return requireProviderVersion("workspace_run", providerVersion, minProviderWorkspaceRunVersion)
+}
+
+// ValidateProviderAutoDestroyAt returns an error if the passed tfe provider version does not have the auto_destroy_at attribute. Version constraints are not checked.
+func ValidateProviderAutoDestroyAt(providerVersion string) error {
+	return requireProviderVersion("auto_destroy_at", providerVersion, minProviderAutoDestroyAtVersion)
+}
+
+// ValidateProviderTriggerPatterns returns an error if the passed tfe provider version does not have the trigger_patterns attribute. Version constraints are not checked.
+func ValidateProviderTriggerPatterns(providerVersion string) error {
+	return requireProviderVersion("trigger_patterns", providerVersion, minProviderTriggerPatternsVersion)
+}
+
+// ValidateProviderTagsRegex returns an error if the passed tfe provider version does not have the tags_regex attribute. Version constraints are not checked.
+func ValidateProviderTagsRegex(providerVersion string) error {
+	return requireProviderVersion("trigger_tags_regex", providerVersion, minProviderTagsRegexVersion)
+}
+
+// requireProviderVersion returns an error if the passed tfe provider version is older than the minimum version of the passed input
+func requireProviderVersion(input string, providerVersion string, min *version.Version) error {
+	v, err := version.NewVersion(providerVersion)
+	if err != nil {
+		return nil
+	}
+
+	if v.LessThan(min) {
+		return fmt.Errorf("%s requires tfe_provider_version %s or later, got %s", input, min, providerVersion)
+	}
+
+	return nil
+}
+
 func AddProviders(module *tfconfig.Module, providers []Provider) {
 	if len(providers) == 0 {
 		return
@@ -376,6 +813,81 @@ func WillDestroy(plan *tfjson.Plan, targetType string) bool {
 	return false
 }
 
+// CreatedWorkspaces returns the sorted names of the workspaces the plan creates. Replaced workspaces are not counted.
+func CreatedWorkspaces(plan *tfjson.Plan) []string {
+	names := []string{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Type != "tfe_workspace" || rc.Change == nil || !rc.Change.Actions.Create() {
+			continue
+		}
+
+		name := rc.Address
+		if after, ok := rc.Change.After.(map[string]interface{}); ok {
+			if n, ok := after["name"].(string); ok {
+				name = n
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// DestroyedWorkspaces returns the sorted names of the workspaces the plan deletes, including workspaces that are replaced
+func DestroyedWorkspaces(plan *tfjson.Plan) []string {
+	names := []string{}
+
+	for _, rc := range plan.ResourceChanges {
+		if rc.Type != "tfe_workspace" || rc.Change == nil || !(rc.Change.Actions.Delete() || rc.Change.Actions.Replace()) {
+			continue
+		}
+
+		name := rc.Address
+		if before, ok := rc.Change.Before.(map[string]interface{}); ok {
+			if n, ok := before["name"].(string); ok {
+				name = n
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// BlockedWorkspaceDeletions returns the destroyed workspaces whose names do not match any of the allowed names, which may be glob patterns such as "pr-*"
+func BlockedWorkspaceDeletions(destroyed []string, allowed []string) ([]string, error) {
+	blocked := []string{}
+
+	for _, name := range destroyed {
+		isAllowed := false
+
+		for _, pattern := range allowed {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+			}
+
+			if ok {
+				isAllowed = true
+				break
+			}
+		}
+
+		if !isAllowed {
+			blocked = append(blocked, name)
+		}
+	}
+
+	return blocked, nil
+}
+
 // FindWorkspace returns a workspace that matches the passed Terraform workspace identifier (not the workspace name)
 func FindWorkspace(workspaces []*Workspace, target string) *Workspace {
 	for _, v := range workspaces {
@@ -387,8 +899,119 @@ func FindWorkspace(workspaces []*Workspace, target string) *Workspace {
 	return nil
 }
 
+// ParseAutoDestroyAt resolves the passed RFC3339 timestamp or duration relative to now to an RFC3339 timestamp, which must be in the future
+func ParseAutoDestroyAt(input string, now time.Time) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	at, err := time.Parse(time.RFC3339, input)
+	if err != nil {
+		d, durErr := time.ParseDuration(input)
+		if durErr != nil {
+			return "", fmt.Errorf("auto destroy time %q must be an RFC3339 timestamp or a duration", input)
+		}
+
+		at = now.Add(d)
+	}
+
+	if !at.After(now) {
+		return "", fmt.Errorf("auto destroy time %q must be in the future", input)
+	}
+
+	return at.UTC().Format(time.RFC3339), nil
+}
+
+// MatchWorkspaces returns the workspaces matching the passed name, which may be a glob pattern such as "prod-*"
+func MatchWorkspaces(workspaces []*Workspace, pattern string) ([]*Workspace, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+	}
+
+	matches := []*Workspace{}
+
+	for _, ws := range workspaces {
+		// errors are only returned for malformed patterns, which were checked above
+		if ok, _ := path.Match(pattern, ws.Workspace); ok {
+			matches = append(matches, ws)
+		}
+	}
+
+	return matches, nil
+}
+
+// isWorkspacePattern returns true if the passed workspace name contains glob characters
+func isWorkspacePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// SortWorkspacePatterns sorts the passed workspace names, with glob patterns before exact names so that exact names take precedence
+func SortWorkspacePatterns(names []string) []string {
+	sorted := append([]string{}, names...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		pi, pj := isWorkspacePattern(sorted[i]), isWorkspacePattern(sorted[j])
+		if pi != pj {
+			return pi
+		}
+
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted
+}
+
+// SetWorkspaceOrganizations sets the organization of the workspaces listed in the passed map of workspace names to organizations
+func SetWorkspaceOrganizations(workspaces []*Workspace, organizations map[string]string) error {
+	for wsName, org := range organizations {
+		ws := FindWorkspace(workspaces, wsName)
+		if ws == nil {
+			return fmt.Errorf("organization specified for unknown workspace %q", wsName)
+		}
+
+		ws.Organization = org
+	}
+
+	return nil
+}
+
+// SetStandaloneWorkspace marks the workspace as standalone when exactly one workspace is managed, so it is rendered without for_each
+func SetStandaloneWorkspace(workspaces []*Workspace) {
+	if len(workspaces) == 1 {
+		workspaces[0].Standalone = true
+	}
+}
+
+// StandaloneMovedBlocks returns the moved block relocating a single existing workspace from its for_each instance address to the standalone resource address, or back, so toggling standalone_workspace does not recreate it.
+// A renamed workspace is already moved by its rename.
+func StandaloneMovedBlocks(workspaces []*Workspace, renames []WorkspaceRename) []tfconfig.Moved {
+	moved := []tfconfig.Moved{}
+
+	if len(workspaces) != 1 || workspaces[0].ID == nil {
+		return moved
+	}
+
+	for _, r := range renames {
+		if r.To == workspaces[0] {
+			return moved
+		}
+	}
+
+	ws := *workspaces[0]
+	ws.Standalone = !ws.Standalone
+
+	return append(moved, tfconfig.Moved{From: ws.Address(), To: workspaces[0].Address()})
+}
+
+// ErrNoWorkspaces is returned when neither workspace names nor a workspace name are passed, so no workspace can be resolved
+var ErrNoWorkspaces = errors.New("no workspaces resolved, set the workspace name or workspaces")
+
 // ParseWorkspaces a list of workspace names and the generic workspace name and returns a list of Workspace objects. "default" is used if no workspace names are passed.
 func ParseWorkspaces(workspaceNames []string, name string) ([]*Workspace, error) {
+	if len(workspaceNames) == 0 && name == "" {
+		return nil, ErrNoWorkspaces
+	}
+
 	var workspaces []*Workspace
 
 	if len(workspaceNames) == 0 {
@@ -408,10 +1031,90 @@ func ParseWorkspaces(workspaceNames []string, name string) ([]*Workspace, error)
 	return workspaces, nil
 }
 
+// FetchWorkspaceIDsByTags returns the sorted, distinct IDs of the workspaces in the organization carrying any of the passed tags
+func FetchWorkspaceIDsByTags(ctx context.Context, client *tfe.Client, organization string, tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	ids := []string{}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+
+		for page := 1; page != 0; {
+			var wsList *tfe.WorkspaceList
+
+			err := fetchPage(ctx, page, func() (err error) {
+				wsList, err = client.Workspaces.List(ctx, organization, tfe.WorkspaceListOptions{
+					ListOptions: tfe.ListOptions{
+						PageNumber: page,
+						PageSize:   maxPageSize,
+					},
+					Tags: &tag,
+				})
+
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list workspaces tagged %q: %w", tag, err)
+			}
+
+			for _, ws := range wsList.Items {
+				if !seen[ws.ID] {
+					seen[ws.ID] = true
+					ids = append(ids, ws.ID)
+				}
+			}
+
+			page = 0
+			if wsList.Pagination != nil {
+				page = wsList.Pagination.NextPage
+			}
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// FetchWorkspaceIDsBySearch returns the IDs of the workspaces in the organization with a name containing the passed search string
+func FetchWorkspaceIDsBySearch(ctx context.Context, client *tfe.Client, organization string, search string) ([]string, error) {
+	ids := []string{}
+
+	for page := 1; page != 0; {
+		var wsList *tfe.WorkspaceList
+
+		err := fetchPage(ctx, page, func() (err error) {
+			wsList, err = client.Workspaces.List(ctx, organization, tfe.WorkspaceListOptions{
+				ListOptions: tfe.ListOptions{
+					PageNumber: page,
+					PageSize:   maxPageSize,
+				},
+				Search: &search,
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspaces matching %q: %w", search, err)
+		}
+
+		for _, ws := range wsList.Items {
+			ids = append(ids, ws.ID)
+		}
+
+		page = 0
+		if wsList.Pagination != nil {
+			page = wsList.Pagination.NextPage
+		}
+	}
+
+	return ids, nil
+}
+
 // SetWorkspaceIDs takes a list of workspace objects and sets the ID if the resources is found in the Terraform Cloud organization
 func SetWorkspaceIDs(ctx context.Context, client *tfe.Client, workspaces []*Workspace, organization string) error {
 	for _, workspace := range workspaces {
-		ws, err := client.Workspaces.Read(ctx, organization, workspace.Name)
+		ws, err := client.Workspaces.Read(ctx, workspaceOrganization(workspace, organization), workspace.Name)
 		if err != nil {
 			if !errors.Is(err, tfe.ErrResourceNotFound) {
 				return err
@@ -0,0 +1,396 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/takescoop/terraform-cloud-workspace-action/internal/tfeprovider"
+)
+
+// WorkspaceSettings are the settings of a single workspace, overriding the settings passed for all workspaces
+type WorkspaceSettings struct {
+	AssessmentsEnabled         *bool   `yaml:"assessments_enabled,omitempty" json:"assessments_enabled,omitempty"`
+	AutoApply                  *bool   `yaml:"auto_apply,omitempty" json:"auto_apply,omitempty"`
+	Description                *string `yaml:"description,omitempty" json:"description,omitempty"`
+	ExecutionMode              *string `yaml:"execution_mode,omitempty" json:"execution_mode,omitempty"`
+	FileTriggersEnabled        *bool   `yaml:"file_triggers_enabled,omitempty" json:"file_triggers_enabled,omitempty"`
+	GlobalRemoteState          *bool   `yaml:"global_remote_state,omitempty" json:"global_remote_state,omitempty"`
+	QueueAllRuns               *bool   `yaml:"queue_all_runs,omitempty" json:"queue_all_runs,omitempty"`
+	SpeculativeEnabled         *bool   `yaml:"speculative_enabled,omitempty" json:"speculative_enabled,omitempty"`
+	StructuredRunOutputEnabled *bool   `yaml:"structured_run_output_enabled,omitempty" json:"structured_run_output_enabled,omitempty"`
+	TerraformVersion           *string `yaml:"terraform_version,omitempty" json:"terraform_version,omitempty"`
+	WorkingDirectory           *string `yaml:"working_directory,omitempty" json:"working_directory,omitempty"`
+
+	// AutoDestroyAt is resolved from the auto_destroy_at input by ResolveAutoDestroyAt and cannot be set per workspace
+	AutoDestroyAt *string `yaml:"-" json:"auto_destroy_at,omitempty"`
+}
+
+// workspaceSetting describes how a setting that can be overridden per workspace is rendered on a for_each entry
+type workspaceSetting struct {
+	attribute  string
+	overridden func(s WorkspaceSettings) bool
+	set        func(entry *tfeprovider.Workspace, config *WorkspaceResourceOptions)
+}
+
+// workspaceSettings lists the settings that can be overridden per workspace
+var workspaceSettings = []workspaceSetting{
+	{
+		attribute:  "assessments_enabled",
+		overridden: func(s WorkspaceSettings) bool { return s.AssessmentsEnabled != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			e.AssessmentsEnabled = c.AssessmentsEnabled
+		},
+	},
+	{
+		attribute:  "auto_apply",
+		overridden: func(s WorkspaceSettings) bool { return s.AutoApply != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.AutoApply = c.AutoApply },
+	},
+	{
+		attribute:  "auto_destroy_at",
+		overridden: func(s WorkspaceSettings) bool { return s.AutoDestroyAt != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.AutoDestroyAt = c.AutoDestroyAt },
+	},
+	{
+		attribute:  "description",
+		overridden: func(s WorkspaceSettings) bool { return s.Description != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.Description = c.Description },
+	},
+	{
+		attribute:  "execution_mode",
+		overridden: func(s WorkspaceSettings) bool { return s.ExecutionMode != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			e.ExecutionMode = c.ExecutionMode
+			if c.AgentPoolID != "" || c.AgentPoolName != "" {
+				e.ExecutionMode = "agent"
+			}
+		},
+	},
+	{
+		attribute:  "file_triggers_enabled",
+		overridden: func(s WorkspaceSettings) bool { return s.FileTriggersEnabled != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			e.FileTriggersEnabled = c.FileTriggersEnabled
+		},
+	},
+	{
+		attribute:  "global_remote_state",
+		overridden: func(s WorkspaceSettings) bool { return s.GlobalRemoteState != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.GlobalRemoteState = c.GlobalRemoteState },
+	},
+	{
+		attribute:  "queue_all_runs",
+		overridden: func(s WorkspaceSettings) bool { return s.QueueAllRuns != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			e.QueueAllRuns = c.QueueAllRuns
+			if e.QueueAllRuns == nil {
+				e.QueueAllRuns = tfe.Bool(false)
+			}
+		},
+	},
+	{
+		attribute:  "speculative_enabled",
+		overridden: func(s WorkspaceSettings) bool { return s.SpeculativeEnabled != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			if c.SpeculativeEnabled != nil {
+				e.SpeculativeEnabled = *c.SpeculativeEnabled
+			}
+		},
+	},
+	{
+		attribute:  "structured_run_output_enabled",
+		overridden: func(s WorkspaceSettings) bool { return s.StructuredRunOutputEnabled != nil },
+		set: func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) {
+			e.StructuredRunOutputEnabled = c.StructuredRunOutputEnabled
+		},
+	},
+	{
+		attribute:  "terraform_version",
+		overridden: func(s WorkspaceSettings) bool { return s.TerraformVersion != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.TerraformVersion = c.TerraformVersion },
+	},
+	{
+		attribute:  "working_directory",
+		overridden: func(s WorkspaceSettings) bool { return s.WorkingDirectory != nil },
+		set:        func(e *tfeprovider.Workspace, c *WorkspaceResourceOptions) { e.WorkingDirectory = c.WorkingDirectory },
+	},
+}
+
+// MergeDeprecatedWorkspaceSettings adds the Terraform versions and speculative plan settings of the deprecated workspace_terraform_versions and workspace_speculative_enabled inputs to the workspace settings.
+// Settings already set in the workspace settings take precedence.
+func MergeDeprecatedWorkspaceSettings(settings map[string]WorkspaceSettings, tfVersions map[string]string, speculative map[string]bool) map[string]WorkspaceSettings {
+	merged := map[string]WorkspaceSettings{}
+
+	for wsName, s := range settings {
+		merged[wsName] = s
+	}
+
+	for wsName, v := range tfVersions {
+		s := merged[wsName]
+		if s.TerraformVersion == nil {
+			s.TerraformVersion = tfe.String(v)
+		}
+
+		merged[wsName] = s
+	}
+
+	for wsName, v := range speculative {
+		s := merged[wsName]
+		if s.SpeculativeEnabled == nil {
+			s.SpeculativeEnabled = tfe.Bool(v)
+		}
+
+		merged[wsName] = s
+	}
+
+	return merged
+}
+
+// ForWorkspace returns a copy of the options with the settings of the passed workspace applied
+func (config *WorkspaceResourceOptions) ForWorkspace(workspace string) *WorkspaceResourceOptions {
+	c := *config
+
+	s, ok := config.WorkspaceSettings[workspace]
+	if !ok {
+		return &c
+	}
+
+	if s.AssessmentsEnabled != nil {
+		c.AssessmentsEnabled = s.AssessmentsEnabled
+	}
+
+	if s.AutoApply != nil {
+		c.AutoApply = s.AutoApply
+	}
+
+	if s.AutoDestroyAt != nil {
+		c.AutoDestroyAt = *s.AutoDestroyAt
+	}
+
+	if s.Description != nil {
+		c.Description = *s.Description
+	}
+
+	if s.ExecutionMode != nil {
+		c.ExecutionMode = *s.ExecutionMode
+	}
+
+	if s.FileTriggersEnabled != nil {
+		c.FileTriggersEnabled = s.FileTriggersEnabled
+	}
+
+	if s.GlobalRemoteState != nil {
+		c.GlobalRemoteState = s.GlobalRemoteState
+	}
+
+	if s.QueueAllRuns != nil {
+		c.QueueAllRuns = s.QueueAllRuns
+	}
+
+	if s.SpeculativeEnabled != nil {
+		c.SpeculativeEnabled = s.SpeculativeEnabled
+	}
+
+	if s.StructuredRunOutputEnabled != nil {
+		c.StructuredRunOutputEnabled = s.StructuredRunOutputEnabled
+	}
+
+	if s.TerraformVersion != nil {
+		c.TerraformVersion = *s.TerraformVersion
+	}
+
+	if s.WorkingDirectory != nil {
+		c.WorkingDirectory = *s.WorkingDirectory
+	}
+
+	return &c
+}
+
+// validateWorkspaceSettings returns an error if settings are passed for a workspace that is not configured, or are invalid
+func validateWorkspaceSettings(workspaces []*Workspace, settings map[string]WorkspaceSettings) error {
+	names := make([]string, 0, len(settings))
+	for wsName := range settings {
+		names = append(names, wsName)
+	}
+
+	sort.Strings(names)
+
+	for _, wsName := range names {
+		if FindWorkspace(workspaces, wsName) == nil {
+			return fmt.Errorf("workspace settings specified for unknown workspace %q", wsName)
+		}
+
+		if dir := settings[wsName].WorkingDirectory; dir != nil {
+			if err := validateWorkingDirectory(*dir); err != nil {
+				return fmt.Errorf("invalid settings of workspace %q: %w", wsName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setWorkspaceSettings renders the settings that are overridden for any workspace on the for_each entries of the workspace resource, looking them up from each entry
+func setWorkspaceSettings(ws *tfeprovider.Workspace, workspaces []*Workspace, config *WorkspaceResourceOptions) {
+	for _, setting := range workspaceSettings {
+		overridden := false
+
+		for _, s := range config.WorkspaceSettings {
+			if setting.overridden(s) {
+				overridden = true
+				break
+			}
+		}
+
+		if !overridden {
+			continue
+		}
+
+		for _, w := range workspaces {
+			setting.set(ws.ForEach[w.Workspace], config.ForWorkspace(w.Workspace))
+		}
+
+		ws.Lookups = append(ws.Lookups, setting.attribute)
+	}
+}
+
+// ReadLiveWorkspaces reads each existing workspace once, keyed by its workspace key, so the live values can be shared by every setting preserved from them
+func ReadLiveWorkspaces(ctx context.Context, client *tfe.Client, workspaces []*Workspace) (map[string]*tfe.Workspace, error) {
+	live := map[string]*tfe.Workspace{}
+
+	for _, ws := range workspaces {
+		if ws.ID == nil {
+			continue
+		}
+
+		w, err := client.Workspaces.ReadByID(ctx, *ws.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+		}
+
+		live[ws.Workspace] = w
+	}
+
+	return live, nil
+}
+
+// PreserveLiveQueueAllRuns sets the queue_all_runs setting of each existing workspace that does not set it to the passed live value, so that only new workspaces default to not queueing their first run
+func PreserveLiveQueueAllRuns(live map[string]*tfe.Workspace, settings map[string]WorkspaceSettings) map[string]WorkspaceSettings {
+	preserved := map[string]WorkspaceSettings{}
+
+	for wsName, s := range settings {
+		preserved[wsName] = s
+	}
+
+	for wsName, w := range live {
+		if preserved[wsName].QueueAllRuns != nil {
+			continue
+		}
+
+		s := preserved[wsName]
+		s.QueueAllRuns = tfe.Bool(w.QueueAllRuns)
+		preserved[wsName] = s
+	}
+
+	return preserved
+}
+
+// PreserveLiveDescriptions sets the description of each existing workspace with a non-empty description to the passed live description, so descriptions edited outside of the action are not reverted
+func PreserveLiveDescriptions(live map[string]*tfe.Workspace, settings map[string]WorkspaceSettings) map[string]WorkspaceSettings {
+	preserved := map[string]WorkspaceSettings{}
+
+	for wsName, s := range settings {
+		preserved[wsName] = s
+	}
+
+	for wsName, w := range live {
+		if w.Description == "" {
+			continue
+		}
+
+		s := preserved[wsName]
+		s.Description = tfe.String(w.Description)
+		preserved[wsName] = s
+	}
+
+	return preserved
+}
+
+// workspaceAutoDestroy is the subset of the workspace API response containing the auto destroy time, which the go-tfe client does not support
+type workspaceAutoDestroy struct {
+	Data struct {
+		Attributes struct {
+			AutoDestroyAt *string `json:"auto-destroy-at"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ResolveAutoDestroyAt sets the auto destroy time of each workspace to the passed input, resolved relative to now.
+// An RFC3339 timestamp is applied to every workspace. For a duration, existing workspaces that already have an auto destroy time keep their live value, so the deadline is not extended on every run.
+func ResolveAutoDestroyAt(ctx context.Context, httpClient *http.Client, address string, token string, workspaces []*Workspace, settings map[string]WorkspaceSettings, input string, now time.Time) (map[string]WorkspaceSettings, error) {
+	resolved := map[string]WorkspaceSettings{}
+
+	for wsName, s := range settings {
+		resolved[wsName] = s
+	}
+
+	if _, err := time.Parse(time.RFC3339, input); err == nil {
+		at, err := ParseAutoDestroyAt(input, now)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ws := range workspaces {
+			s := resolved[ws.Workspace]
+			s.AutoDestroyAt = &at
+			resolved[ws.Workspace] = s
+		}
+
+		return resolved, nil
+	}
+
+	var at *string
+
+	for _, ws := range workspaces {
+		s := resolved[ws.Workspace]
+
+		if ws.ID != nil {
+			var live workspaceAutoDestroy
+
+			status, err := fetchJSONAPI(ctx, httpClient, address, token, fmt.Sprintf("workspaces/%s", url.PathEscape(*ws.ID)), &live)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read workspace %q: %w", ws.Name, err)
+			}
+
+			if status != http.StatusOK {
+				return nil, fmt.Errorf("failed to read workspace %q: %d %s", ws.Name, status, http.StatusText(status))
+			}
+
+			if v := live.Data.Attributes.AutoDestroyAt; v != nil && *v != "" {
+				s.AutoDestroyAt = v
+				resolved[ws.Workspace] = s
+
+				continue
+			}
+		}
+
+		if at == nil {
+			v, err := ParseAutoDestroyAt(input, now)
+			if err != nil {
+				return nil, err
+			}
+
+			at = &v
+		}
+
+		s.AutoDestroyAt = at
+		resolved[ws.Workspace] = s
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,262 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForWorkspace(t *testing.T) {
+	config := &WorkspaceResourceOptions{
+		AutoApply:        tfe.Bool(false),
+		ExecutionMode:    "remote",
+		TerraformVersion: "1.1.0",
+		WorkspaceSettings: map[string]WorkspaceSettings{
+			"production": {
+				AutoApply:     tfe.Bool(true),
+				ExecutionMode: tfe.String("agent"),
+			},
+		},
+	}
+
+	t.Run("apply the settings of the workspace", func(t *testing.T) {
+		c := config.ForWorkspace("production")
+
+		assert.Equal(t, tfe.Bool(true), c.AutoApply)
+		assert.Equal(t, "agent", c.ExecutionMode)
+		assert.Equal(t, "1.1.0", c.TerraformVersion)
+	})
+
+	t.Run("use the global settings for workspaces without settings", func(t *testing.T) {
+		c := config.ForWorkspace("staging")
+
+		assert.Equal(t, tfe.Bool(false), c.AutoApply)
+		assert.Equal(t, "remote", c.ExecutionMode)
+	})
+
+	t.Run("do not modify the passed options", func(t *testing.T) {
+		config.ForWorkspace("production")
+
+		assert.Equal(t, tfe.Bool(false), config.AutoApply)
+		assert.Equal(t, "remote", config.ExecutionMode)
+	})
+}
+
+func TestMergeDeprecatedWorkspaceSettings(t *testing.T) {
+	settings := map[string]WorkspaceSettings{
+		"production": {TerraformVersion: tfe.String("1.3.0")},
+	}
+
+	merged := MergeDeprecatedWorkspaceSettings(settings, map[string]string{
+		"staging":    "1.2.0",
+		"production": "1.1.0",
+	}, map[string]bool{"staging": true})
+
+	assert.Equal(t, map[string]WorkspaceSettings{
+		"staging":    {TerraformVersion: tfe.String("1.2.0"), SpeculativeEnabled: tfe.Bool(true)},
+		"production": {TerraformVersion: tfe.String("1.3.0")},
+	}, merged)
+
+	assert.Len(t, settings, 1, "the passed settings are not modified")
+}
+
+func TestNewWorkspaceResourceWithSettings(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/organizations/org/oauth-clients", testServerResHandler(t, 200, basicOauthClientResponse))
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("look up overridden settings from each workspace", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			AutoApply:   tfe.Bool(false),
+			Description: "shared",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"production": {AutoApply: tfe.Bool(true)},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, tfe.Bool(true), ws.ForEach["production"].AutoApply)
+		assert.Equal(t, tfe.Bool(false), ws.ForEach["staging"].AutoApply)
+
+		b, err := json.Marshal(ws)
+		require.NoError(t, err)
+
+		attrs := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(b, &attrs))
+
+		assert.Equal(t, "${lookup(each.value, \"auto_apply\", null)}", attrs["auto_apply"])
+		assert.Equal(t, "shared", attrs["description"])
+	})
+
+	t.Run("apply the settings directly to a standalone workspace", func(t *testing.T) {
+		workspaces := []*Workspace{{Name: "foo", Workspace: "default", Standalone: true}}
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"default": {WorkingDirectory: tfe.String("terraform")},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "terraform", ws.WorkingDirectory)
+		assert.Empty(t, ws.Lookups)
+	})
+
+	t.Run("return an error for an absolute working directory", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			WorkingDirectory: "terraform",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"production": {WorkingDirectory: tfe.String("/terraform/production")},
+			},
+		})
+
+		assert.EqualError(t, err, "invalid settings of workspace \"production\": working directory \"/terraform/production\" must be relative to the root of the repository and cannot start with \"/\"")
+	})
+
+	t.Run("return an error for settings of an unknown workspace", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"development": {AutoApply: tfe.Bool(true)},
+			},
+		})
+
+		assert.EqualError(t, err, "workspace settings specified for unknown workspace \"development\"")
+	})
+}
+
+func TestReadLiveWorkspaces(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	reads := map[string]int{}
+
+	for id, attributes := range map[string]string{
+		"ws-abc123": `{"name": "foo-staging", "description": "edited in the UI", "queue-all-runs": true}`,
+		"ws-def456": `{"name": "foo-production", "description": ""}`,
+	} {
+		id, attributes := id, attributes
+
+		mux.HandleFunc("/api/v2/workspaces/"+id, func(w http.ResponseWriter, r *http.Request) {
+			reads[id]++
+
+			testServerResHandler(t, 200, `{"data": {"id": "`+id+`", "type": "workspaces", "attributes": `+attributes+`}}`)(w, r)
+		})
+	}
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("read each existing workspace once for every preserved setting", func(t *testing.T) {
+		workspaces := append(newTestMultiWorkspaceList(), &Workspace{Name: "foo-development", Workspace: "development"})
+
+		live, err := ReadLiveWorkspaces(ctx, client, workspaces)
+		require.NoError(t, err)
+
+		settings := PreserveLiveQueueAllRuns(live, map[string]WorkspaceSettings{
+			"production": {QueueAllRuns: tfe.Bool(true)},
+		})
+		settings = PreserveLiveDescriptions(live, settings)
+
+		assert.Equal(t, map[string]WorkspaceSettings{
+			"staging":    {QueueAllRuns: tfe.Bool(true), Description: tfe.String("edited in the UI")},
+			"production": {QueueAllRuns: tfe.Bool(true)},
+		}, settings)
+
+		assert.Equal(t, map[string]int{"ws-abc123": 1, "ws-def456": 1}, reads)
+	})
+}
+
+func TestPreserveLiveDescriptions(t *testing.T) {
+	live := map[string]*tfe.Workspace{
+		"staging":    {Description: "edited in the UI"},
+		"production": {Description: ""},
+	}
+
+	t.Run("keep non-empty live descriptions of existing workspaces", func(t *testing.T) {
+		settings := PreserveLiveDescriptions(live, map[string]WorkspaceSettings{
+			"staging": {AutoApply: tfe.Bool(true)},
+		})
+
+		assert.Equal(t, map[string]WorkspaceSettings{
+			"staging": {AutoApply: tfe.Bool(true), Description: tfe.String("edited in the UI")},
+		}, settings)
+	})
+}
+
+func TestPreserveLiveQueueAllRuns(t *testing.T) {
+	live := map[string]*tfe.Workspace{
+		"staging":    {QueueAllRuns: true},
+		"production": {QueueAllRuns: true},
+	}
+
+	settings := PreserveLiveQueueAllRuns(live, map[string]WorkspaceSettings{
+		"production": {QueueAllRuns: tfe.Bool(false)},
+	})
+
+	assert.Equal(t, map[string]WorkspaceSettings{
+		"staging":    {QueueAllRuns: tfe.Bool(true)},
+		"production": {QueueAllRuns: tfe.Bool(false)},
+	}, settings)
+}
+
+func TestResolveAutoDestroyAt(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/workspaces/ws-abc123", testServerResHandler(t, 200, `{"data": {"id": "ws-abc123", "type": "workspaces", "attributes": {"name": "foo-staging", "auto-destroy-at": "2022-06-02T00:00:00.000Z"}}}`))
+	mux.HandleFunc("/api/v2/workspaces/ws-def456", testServerResHandler(t, 200, `{"data": {"id": "ws-def456", "type": "workspaces", "attributes": {"name": "foo-production", "auto-destroy-at": null}}}`))
+
+	workspaces := append(newTestMultiWorkspaceList(), &Workspace{Name: "foo-development", Workspace: "development"})
+
+	t.Run("keep the live value of existing workspaces and resolve the others", func(t *testing.T) {
+		settings, err := ResolveAutoDestroyAt(ctx, http.DefaultClient, server.URL, "12345", workspaces, map[string]WorkspaceSettings{
+			"staging": {AutoApply: tfe.Bool(true)},
+		}, "72h", now)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]WorkspaceSettings{
+			"staging":     {AutoApply: tfe.Bool(true), AutoDestroyAt: tfe.String("2022-06-02T00:00:00.000Z")},
+			"production":  {AutoDestroyAt: tfe.String("2022-06-04T12:00:00Z")},
+			"development": {AutoDestroyAt: tfe.String("2022-06-04T12:00:00Z")},
+		}, settings)
+	})
+
+	t.Run("change the live value of existing workspaces to an explicit timestamp", func(t *testing.T) {
+		settings, err := ResolveAutoDestroyAt(ctx, http.DefaultClient, server.URL, "12345", workspaces, nil, "2022-06-10T00:00:00Z", now)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]WorkspaceSettings{
+			"staging":     {AutoDestroyAt: tfe.String("2022-06-10T00:00:00Z")},
+			"production":  {AutoDestroyAt: tfe.String("2022-06-10T00:00:00Z")},
+			"development": {AutoDestroyAt: tfe.String("2022-06-10T00:00:00Z")},
+		}, settings)
+	})
+
+	t.Run("error on a past timestamp", func(t *testing.T) {
+		_, err := ResolveAutoDestroyAt(ctx, http.DefaultClient, server.URL, "12345", workspaces[:1], nil, "2022-05-01T00:00:00Z", now)
+		assert.EqualError(t, err, "auto destroy time \"2022-05-01T00:00:00Z\" must be in the future")
+	})
+}
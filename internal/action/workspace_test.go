@@ -8,7 +8,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 
 	tfe "github.com/hashicorp/go-tfe"
 	"github.com/hashicorp/go-version"
@@ -161,6 +163,9 @@ func TestNewWorkspaceResource(t *testing.T) {
 	defer server.Close()
 
 	mux.HandleFunc("/api/v2/organizations/org/oauth-clients", testServerResHandler(t, 200, basicOauthClientResponse))
+	mux.HandleFunc("/api/v2/organizations/org/agent-pools", testServerResHandler(t, 200, `{"data": [{"id": "apool-abc123", "type": "agent-pools", "attributes": {"name": "my-pool"}}]}`))
+	mux.HandleFunc("/api/v2/organizations/other/oauth-clients", testServerResHandler(t, 200, strings.ReplaceAll(basicOauthClientResponse, "ot-678910", "ot-other")))
+	mux.HandleFunc("/api/v2/organizations/other/agent-pools", testServerResHandler(t, 200, `{"data": [{"id": "apool-other", "type": "agent-pools", "attributes": {"name": "my-pool"}}]}`))
 
 	client := newTestTFClient(t, server.URL)
 
@@ -189,7 +194,8 @@ func TestNewWorkspaceResource(t *testing.T) {
 		}
 	},
 	"name": "${each.value.name}",
-	"organization": "org"
+	"organization": "org",
+	"queue_all_runs": false
 }`)
 	})
 
@@ -283,6 +289,63 @@ func TestNewWorkspaceResource(t *testing.T) {
 		assert.EqualError(t, err, "VCS repository must be passed if VCS type or a VCS token ID is passed")
 	})
 
+	t.Run("skip the VCS integration with the local execution mode", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:  "org",
+			ExecutionMode: "local",
+			VCSType:       "github",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Nil(t, ws.VCSRepo)
+	})
+
+	t.Run("skip the VCS integration of workspaces with the local execution mode", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization: "org",
+			VCSTokenID:   "TOKEN",
+			VCSRepo:      "org/repo",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {ExecutionMode: tfe.String("local")},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Nil(t, ws.VCSRepo)
+		assert.Nil(t, ws.ForEach["staging"].VCSRepo)
+		assert.Equal(t, &tfeprovider.VCSRepo{OauthTokenID: "TOKEN", Identifier: "org/repo"}, ws.ForEach["production"].VCSRepo)
+		assert.Equal(t, &tfeprovider.DynamicVCSRepo{
+			VCSRepo: []tfeprovider.DynamicVCSRepoEntry{
+				{
+					ForEach: "${lookup(each.value, \"vcs_repo\", null) == null ? [] : [each.value.vcs_repo]}",
+					Content: &tfeprovider.VCSRepo{OauthTokenID: "${vcs_repo.value.oauth_token_id}", Identifier: "org/repo"},
+				},
+			},
+		}, ws.DynamicVCSRepo)
+	})
+
+	t.Run("skip the VCS integration when all workspaces use the local execution mode", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:  "org",
+			ExecutionMode: "local",
+			VCSTokenID:    "TOKEN",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {Description: tfe.String("staging")},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Nil(t, ws.VCSRepo)
+		assert.Nil(t, ws.DynamicVCSRepo)
+		assert.Nil(t, ws.ForEach["staging"].VCSRepo)
+	})
+
 	t.Run("use VCSTokenID directly when passed", func(t *testing.T) {
 		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
 			Organization: "org",
@@ -309,6 +372,96 @@ func TestNewWorkspaceResource(t *testing.T) {
 		assert.Equal(t, ws.ExecutionMode, "agent")
 	})
 
+	t.Run("resolve AgentPoolID from AgentPoolName", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:  "org",
+			AgentPoolName: "my-pool",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "apool-abc123", ws.AgentPoolID)
+		assert.Equal(t, "agent", ws.ExecutionMode)
+	})
+
+	t.Run("fail if the agent pool name is not found", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:  "org",
+			AgentPoolName: "unknown",
+		})
+		assert.EqualError(t, err, "no agent pool found with name \"unknown\"")
+	})
+
+	t.Run("fail if both AgentPoolID and AgentPoolName are passed", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:  "org",
+			AgentPoolID:   "apool-abc123",
+			AgentPoolName: "my-pool",
+		})
+		assert.EqualError(t, err, "agent pool ID and agent pool name cannot both be set")
+	})
+
+	t.Run("resolve organization specific attributes per workspace across organizations", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+		workspaces[1].Organization = "other"
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization:  "org",
+			VCSType:       "github",
+			VCSRepo:       "org/repo",
+			AgentPoolName: "my-pool",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "${each.value.organization}", ws.Organization)
+		assert.Equal(t, "${each.value.agent_pool_id}", ws.AgentPoolID)
+		assert.Equal(t, "${each.value.vcs_repo.oauth_token_id}", ws.VCSRepo.OauthTokenID)
+		assert.Equal(t, "agent", ws.ExecutionMode)
+
+		assert.Equal(t, &tfeprovider.Workspace{
+			Name:         "foo-staging",
+			Organization: "org",
+			AgentPoolID:  "apool-abc123",
+			VCSRepo:      &tfeprovider.VCSRepo{OauthTokenID: "ot-678910", Identifier: "org/repo"},
+		}, ws.ForEach["staging"])
+		assert.Equal(t, &tfeprovider.Workspace{
+			Name:         "foo-production",
+			Organization: "other",
+			AgentPoolID:  "apool-other",
+			VCSRepo:      &tfeprovider.VCSRepo{OauthTokenID: "ot-other", Identifier: "org/repo"},
+		}, ws.ForEach["production"])
+	})
+
+	t.Run("fail with an explicit VCS token ID or SSH key ID in multiple organizations", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+		workspaces[1].Organization = "other"
+
+		_, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+			VCSTokenID:   "ot-678910",
+			VCSRepo:      "org/repo",
+		})
+		assert.EqualError(t, err, "vcs_token_id cannot be used with workspaces in multiple organizations, use vcs_type to look up the VCS token of each organization")
+
+		_, err = NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+			SSHKeyID:     "sshkey-abc123",
+		})
+		assert.EqualError(t, err, "ssh_key_id cannot be used with workspaces in multiple organizations")
+	})
+
+	t.Run("use the overridden organization when all workspaces share it", func(t *testing.T) {
+		workspaces := newTestSingleWorkspaceList()
+		workspaces[0].Organization = "other"
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "other", ws.Organization)
+		assert.Equal(t, &tfeprovider.Workspace{Name: "ws"}, ws.ForEach["default"])
+	})
+
 	t.Run("add RemoteConsumerIDs and GlobalRemoteState if global_remote_state is false", func(t *testing.T) {
 		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
 			Organization:           "org",
@@ -346,6 +499,224 @@ func TestNewWorkspaceResource(t *testing.T) {
 		assert.Equal(t, ws.RemoteStateConsumerIDs, []string{})
 	})
 
+	t.Run("render a standalone workspace without for_each", func(t *testing.T) {
+		workspace := newTestWorkspace()
+		workspace.Standalone = true
+
+		ws, err := NewWorkspaceResource(ctx, client, []*Workspace{workspace}, &WorkspaceResourceOptions{
+			Organization: "org",
+			Tags: map[string]Tags{
+				"default": {"all"},
+			},
+		})
+		require.NoError(t, err)
+
+		s, err := json.MarshalIndent(ws, "", "\t")
+		require.NoError(t, err)
+
+		assert.Equal(t, `{
+	"name": "ws",
+	"organization": "org",
+	"queue_all_runs": false,
+	"tag_names": [
+		"all"
+	]
+}`, string(s))
+	})
+
+	t.Run("set trigger patterns", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:    "org",
+			TriggerPatterns: []string{"modules/**/*"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"modules/**/*"}, ws.TriggerPatterns)
+	})
+
+	t.Run("error on trigger patterns with file triggers disabled", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:        "org",
+			FileTriggersEnabled: boolPtr(false),
+			TriggerPrefixes:     []string{"modules/"},
+		})
+		assert.EqualError(t, err, "trigger patterns and trigger prefixes require file triggers, they cannot be set when file_triggers_enabled is false")
+	})
+
+	t.Run("error on both trigger patterns and prefixes", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:    "org",
+			TriggerPatterns: []string{"modules/**/*"},
+			TriggerPrefixes: []string{"modules/"},
+		})
+		assert.EqualError(t, err, "trigger patterns and trigger prefixes cannot both be set")
+	})
+
+	t.Run("accept valid trigger patterns", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:    "org",
+			TriggerPatterns: []string{"modules/**/*", "*.tf", "env/[a-z]*/main.tf"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"modules/**/*", "*.tf", "env/[a-z]*/main.tf"}, ws.TriggerPatterns)
+	})
+
+	t.Run("error on an invalid trigger pattern", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:    "org",
+			TriggerPatterns: []string{"modules/**/*", "env/[a-z/main.tf"},
+		})
+		assert.EqualError(t, err, "invalid trigger pattern \"env/[a-z/main.tf\": syntax error in pattern")
+	})
+
+	t.Run("set tag triggers on the VCS repository and disable file triggers", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:     "org",
+			TriggerTagsRegex: `^v\d+\.\d+\.\d+$`,
+			VCSRepo:          "org/repo",
+			VCSTokenID:       "TOKEN",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, `^v\d+\.\d+\.\d+$`, ws.VCSRepo.TagsRegex)
+		assert.Equal(t, boolPtr(false), ws.FileTriggersEnabled)
+	})
+
+	t.Run("error on tag triggers with trigger patterns", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:     "org",
+			TriggerTagsRegex: "^v",
+			TriggerPatterns:  []string{"modules/**/*"},
+			VCSRepo:          "org/repo",
+			VCSTokenID:       "TOKEN",
+		})
+		assert.EqualError(t, err, "trigger tags cannot be set with trigger patterns or trigger prefixes")
+	})
+
+	t.Run("error on tag triggers without a VCS integration", func(t *testing.T) {
+		_, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:     "org",
+			TriggerTagsRegex: "^v",
+		})
+		assert.EqualError(t, err, "trigger tags require a VCS integration, vcs_type or vcs_token_id must be set")
+	})
+
+	t.Run("look up per workspace Terraform versions, falling back to the global version", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:     "org",
+			TerraformVersion: "1.1.0",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {TerraformVersion: tfe.String("1.2.0")},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Contains(t, ws.Lookups, "terraform_version")
+		assert.Equal(t, "1.2.0", ws.ForEach["staging"].TerraformVersion)
+		assert.Equal(t, "1.1.0", ws.ForEach["production"].TerraformVersion)
+	})
+
+	t.Run("set the Terraform version of a standalone workspace", func(t *testing.T) {
+		workspaces := newTestSingleWorkspaceList()
+		workspaces[0].Standalone = true
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization:     "org",
+			TerraformVersion: "1.1.0",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				workspaces[0].Workspace: {TerraformVersion: tfe.String("1.2.0")},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "1.2.0", ws.TerraformVersion)
+	})
+
+	t.Run("look up per workspace speculative plan settings, falling back to the global setting", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:       "org",
+			SpeculativeEnabled: boolPtr(false),
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {SpeculativeEnabled: tfe.Bool(true)},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Contains(t, ws.Lookups, "speculative_enabled")
+		assert.Equal(t, true, ws.ForEach["staging"].SpeculativeEnabled)
+		assert.Equal(t, false, ws.ForEach["production"].SpeculativeEnabled)
+	})
+
+	t.Run("omit the speculative plan setting of workspaces without a setting", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestMultiWorkspaceList(), &WorkspaceResourceOptions{
+			Organization: "org",
+			WorkspaceSettings: map[string]WorkspaceSettings{
+				"staging": {SpeculativeEnabled: tfe.Bool(true)},
+			},
+		})
+		require.NoError(t, err)
+
+		b, err := json.Marshal(ws.ForEach["production"])
+		require.NoError(t, err)
+
+		assert.NotContains(t, string(b), "speculative_enabled")
+	})
+
+	t.Run("disable queueing the first run of new and existing workspaces by default", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+		workspaces[0].ID = strPtr("ws-abc123")
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, false, *ws.QueueAllRuns)
+		assert.Nil(t, ws.ForEach[workspaces[0].Workspace].QueueAllRuns)
+		assert.Nil(t, ws.ForEach[workspaces[1].Workspace].QueueAllRuns)
+	})
+
+	t.Run("queue the first run of new and existing workspaces if passed", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+		workspaces[0].ID = strPtr("ws-abc123")
+
+		ws, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+			QueueAllRuns: boolPtr(true),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, true, *ws.QueueAllRuns)
+		assert.Nil(t, ws.ForEach[workspaces[0].Workspace].QueueAllRuns)
+		assert.Nil(t, ws.ForEach[workspaces[1].Workspace].QueueAllRuns)
+	})
+
+	t.Run("set structured run output if passed", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:               "org",
+			StructuredRunOutputEnabled: boolPtr(false),
+		})
+		require.NoError(t, err)
+
+		b, err := json.Marshal(ws)
+		require.NoError(t, err)
+
+		assert.Contains(t, string(b), `"structured_run_output_enabled":false`)
+	})
+
+	t.Run("omit structured run output if not passed", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization: "org",
+		})
+		require.NoError(t, err)
+
+		b, err := json.Marshal(ws)
+		require.NoError(t, err)
+
+		assert.NotContains(t, string(b), "structured_run_output_enabled")
+	})
+
 	t.Run("add a description if passed", func(t *testing.T) {
 		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
 			Organization: "org",
@@ -357,6 +728,139 @@ func TestNewWorkspaceResource(t *testing.T) {
 
 		assert.Equal(t, ws.Description, "description")
 	})
+
+	t.Run("omit the default organization of the provider", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:         "org",
+			ProviderOrganization: "org",
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, ws.Organization)
+	})
+
+	t.Run("keep an organization other than the default organization of the provider", func(t *testing.T) {
+		ws, err := NewWorkspaceResource(ctx, client, newTestSingleWorkspaceList(), &WorkspaceResourceOptions{
+			Organization:         "other",
+			ProviderOrganization: "org",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "other", ws.Organization)
+	})
+}
+
+func TestNewWorkspaceResourceVCSTokenLookup(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	listed := map[string]int{}
+
+	for _, org := range []string{"org", "other"} {
+		org := org
+
+		mux.HandleFunc("/api/v2/organizations/"+org+"/oauth-clients", func(w http.ResponseWriter, r *http.Request) {
+			listed[org]++
+
+			testServerResHandler(t, 200, basicOauthClientResponse)(w, r)
+		})
+	}
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("list the OAuth clients once per organization", func(t *testing.T) {
+		workspaces := append(newTestMultiWorkspaceList(), &Workspace{Name: "foo-development", Workspace: "development", Organization: "other"})
+		workspaces = append(workspaces, &Workspace{Name: "foo-sandbox", Workspace: "sandbox", Organization: "other"})
+
+		_, err := NewWorkspaceResource(ctx, client, workspaces, &WorkspaceResourceOptions{
+			Organization: "org",
+			VCSType:      "github",
+			VCSRepo:      "org/repo",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]int{"org": 1, "other": 1}, listed)
+	})
+}
+
+func TestAppendWorkspaceRuns(t *testing.T) {
+	options := &tfeprovider.WorkspaceRunOptions{WaitForRun: true}
+
+	t.Run("skip when disabled", func(t *testing.T) {
+		module := NewModule()
+
+		AppendWorkspaceRuns(module, newTestMultiWorkspaceList(), nil, nil)
+
+		assert.NotContains(t, module.Resources, "tfe_workspace_run")
+	})
+
+	t.Run("add a run to a standalone workspace", func(t *testing.T) {
+		module := NewModule()
+		workspaces := []*Workspace{{Name: "foo", Workspace: "foo", Standalone: true}}
+
+		AppendWorkspaceRuns(module, workspaces, Variables{{Key: "bar", Workspace: workspaces[0]}}, options)
+
+		assert.Equal(t, tfeprovider.WorkspaceRun{
+			ForEach: map[string]tfeprovider.WorkspaceRun{
+				"foo": {WorkspaceID: "${tfe_workspace.workspace.id}"},
+			},
+			WorkspaceID: "${each.value.workspace_id}",
+			Apply:       options,
+			DependsOn:   []string{"tfe_variable.foo-bar"},
+		}, module.Resources["tfe_workspace_run"]["run"])
+	})
+
+	t.Run("add a run to each workspace after its variables", func(t *testing.T) {
+		module := NewModule()
+		workspaces := newTestMultiWorkspaceList()
+
+		AppendWorkspaceRuns(module, workspaces, Variables{
+			{Key: "foo", Workspace: workspaces[1]},
+			{Key: "foo", Workspace: workspaces[0]},
+			{Key: "bar", Workspace: workspaces[1]},
+			{Key: "foo", Workspace: workspaces[1], Category: "env"},
+		}, options)
+
+		assert.Equal(t, tfeprovider.WorkspaceRun{
+			ForEach: map[string]tfeprovider.WorkspaceRun{
+				"staging":    {WorkspaceID: "${tfe_workspace.workspace[\"staging\"].id}"},
+				"production": {WorkspaceID: "${tfe_workspace.workspace[\"production\"].id}"},
+			},
+			WorkspaceID: "${each.value.workspace_id}",
+			Apply:       options,
+			DependsOn:   []string{"tfe_variable.production-bar", "tfe_variable.production-foo", "tfe_variable.staging-foo"},
+		}, module.Resources["tfe_workspace_run"]["run"])
+	})
+}
+
+func TestValidateProviderWorkspaceRun(t *testing.T) {
+	assert.NoError(t, ValidateProviderWorkspaceRun("0.47.0"))
+	assert.EqualError(t, ValidateProviderWorkspaceRun("0.42.0"), "workspace_run requires tfe_provider_version 0.47.0 or later, got 0.42.0")
+}
+
+func TestValidateProviderAutoDestroyAt(t *testing.T) {
+	assert.NoError(t, ValidateProviderAutoDestroyAt("0.52.0"))
+	assert.EqualError(t, ValidateProviderAutoDestroyAt("0.30.2"), "auto_destroy_at requires tfe_provider_version 0.52.0 or later, got 0.30.2")
+}
+
+func TestValidateProviderTriggerPatterns(t *testing.T) {
+	assert.NoError(t, ValidateProviderTriggerPatterns("0.36.0"))
+	assert.EqualError(t, ValidateProviderTriggerPatterns("0.30.2"), "trigger_patterns requires tfe_provider_version 0.36.0 or later, got 0.30.2")
+}
+
+func TestValidateProviderTagsRegex(t *testing.T) {
+	assert.NoError(t, ValidateProviderTagsRegex("0.43.0"))
+	assert.EqualError(t, ValidateProviderTagsRegex("0.30.2"), "trigger_tags_regex requires tfe_provider_version 0.43.0 or later, got 0.30.2")
+}
+
+func TestValidateProviderOrganization(t *testing.T) {
+	assert.NoError(t, ValidateProviderOrganization("0.42.0"))
+	assert.NoError(t, ValidateProviderOrganization("~> 0.40"))
+	assert.EqualError(t, ValidateProviderOrganization("0.30.2"), "tfe_provider_organization requires tfe_provider_version 0.42.0 or later, got 0.30.2")
 }
 
 func TestNewWorkspaceResourceWithTags(t *testing.T) {
@@ -481,6 +985,41 @@ func TestAppendTeamAccess(t *testing.T) {
 	})
 }
 
+func TestAppendTeamAccessAcrossOrganizations(t *testing.T) {
+	module := NewModule()
+
+	other := &Workspace{Name: "foo-production", Workspace: "production", Organization: "other"}
+
+	AppendTeamAccess(module, TeamAccess{
+		TeamAccessItem{TeamName: "Readers", Access: "read", Workspace: newTestWorkspace()},
+		TeamAccessItem{TeamName: "Readers", Access: "read", Workspace: other},
+	}, "org")
+
+	assert.Equal(t, map[string]TeamDataResource{
+		"Readers":       {Name: "Readers", Organization: "org"},
+		"other/Readers": {Name: "Readers", Organization: "other"},
+	}, module.Data["tfe_team"]["teams"].(TeamDataResource).ForEach)
+
+	assert.Contains(t, module.Resources["tfe_team_access"]["teams"].(tfeprovider.TeamAccess).ForEach, "production-${data.tfe_team.teams[\"other/Readers\"].id}")
+}
+
+func TestSetWorkspaceOrganizations(t *testing.T) {
+	t.Run("set the organization of listed workspaces", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+
+		err := SetWorkspaceOrganizations(workspaces, map[string]string{"production": "other"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "", workspaces[0].Organization)
+		assert.Equal(t, "other", workspaces[1].Organization)
+	})
+
+	t.Run("error when a workspace name does not match known workspaces", func(t *testing.T) {
+		err := SetWorkspaceOrganizations(newTestMultiWorkspaceList(), map[string]string{"playground": "other"})
+		assert.Error(t, err)
+	})
+}
+
 func TestAddProviders(t *testing.T) {
 	module := NewModule()
 
@@ -1027,7 +1566,171 @@ func TestFindWorkspace(t *testing.T) {
 	})
 }
 
+func TestParseAutoDestroyAt(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("return an empty string if not set", func(t *testing.T) {
+		at, err := ParseAutoDestroyAt("", now)
+		assert.NoError(t, err)
+		assert.Equal(t, "", at)
+	})
+
+	t.Run("accept a future timestamp", func(t *testing.T) {
+		at, err := ParseAutoDestroyAt("2022-06-02T08:00:00-04:00", now)
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-06-02T12:00:00Z", at)
+	})
+
+	t.Run("resolve a duration relative to now", func(t *testing.T) {
+		at, err := ParseAutoDestroyAt("72h", now)
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-06-04T12:00:00Z", at)
+	})
+
+	t.Run("error on a past timestamp", func(t *testing.T) {
+		_, err := ParseAutoDestroyAt("2022-05-01T00:00:00Z", now)
+		assert.EqualError(t, err, "auto destroy time \"2022-05-01T00:00:00Z\" must be in the future")
+	})
+
+	t.Run("error on an invalid value", func(t *testing.T) {
+		_, err := ParseAutoDestroyAt("tomorrow", now)
+		assert.EqualError(t, err, "auto destroy time \"tomorrow\" must be an RFC3339 timestamp or a duration")
+	})
+}
+
+func TestSanitizeTag(t *testing.T) {
+	assert.Equal(t, Tag("repo:takescoop-terraform-cloud-workspace-action"), SanitizeTag("repo:TakeScoop/terraform-cloud-workspace-action"))
+	assert.Equal(t, Tag("foo-bar_baz"), SanitizeTag(" Foo  bar_baz. "))
+}
+
+func TestGitMetadataTags(t *testing.T) {
+	t.Run("tag the repository and commit", func(t *testing.T) {
+		t.Setenv("GITHUB_REPOSITORY", "TakeScoop/example")
+		t.Setenv("GITHUB_SHA", "abc123")
+
+		assert.Equal(t, Tags{"repo:takescoop-example", "sha:abc123"}, GitMetadataTags())
+	})
+
+	t.Run("skip values missing from the environment", func(t *testing.T) {
+		t.Setenv("GITHUB_REPOSITORY", "")
+		t.Setenv("GITHUB_SHA", "abc123")
+
+		assert.Equal(t, Tags{"sha:abc123"}, GitMetadataTags())
+	})
+}
+
+func TestMatchWorkspaces(t *testing.T) {
+	workspaces := []*Workspace{
+		{Name: "foo-prod-us", Workspace: "prod-us"},
+		{Name: "foo-prod-eu", Workspace: "prod-eu"},
+		{Name: "foo-staging", Workspace: "staging"},
+	}
+
+	t.Run("match an exact workspace name", func(t *testing.T) {
+		matches, err := MatchWorkspaces(workspaces, "staging")
+		assert.NoError(t, err)
+
+		assert.Equal(t, []*Workspace{workspaces[2]}, matches)
+	})
+
+	t.Run("match a glob pattern", func(t *testing.T) {
+		matches, err := MatchWorkspaces(workspaces, "prod-*")
+		assert.NoError(t, err)
+
+		assert.Equal(t, []*Workspace{workspaces[0], workspaces[1]}, matches)
+	})
+
+	t.Run("return no matches for an unknown workspace", func(t *testing.T) {
+		matches, err := MatchWorkspaces(workspaces, "qa-*")
+		assert.NoError(t, err)
+
+		assert.Empty(t, matches)
+	})
+
+	t.Run("error on a malformed pattern", func(t *testing.T) {
+		_, err := MatchWorkspaces(workspaces, "prod-[")
+		assert.Error(t, err)
+	})
+}
+
+func TestSortWorkspacePatterns(t *testing.T) {
+	assert.Equal(t, []string{"*", "prod-*", "prod-eu", "staging"}, SortWorkspacePatterns([]string{"staging", "prod-*", "prod-eu", "*"}))
+}
+
+func TestWorkspaceAddress(t *testing.T) {
+	t.Run("address a for_each workspace instance", func(t *testing.T) {
+		ws := newTestWorkspace()
+
+		assert.Equal(t, "tfe_workspace.workspace[\"default\"]", ws.Address())
+		assert.Equal(t, "${tfe_workspace.workspace[\"default\"].id}", ws.IDRef())
+	})
+
+	t.Run("address a standalone workspace", func(t *testing.T) {
+		ws := newTestWorkspace()
+		ws.Standalone = true
+
+		assert.Equal(t, "tfe_workspace.workspace", ws.Address())
+		assert.Equal(t, "${tfe_workspace.workspace.id}", ws.IDRef())
+	})
+}
+
+func TestSetStandaloneWorkspace(t *testing.T) {
+	t.Run("mark a single workspace as standalone", func(t *testing.T) {
+		workspaces := newTestSingleWorkspaceList()
+		SetStandaloneWorkspace(workspaces)
+
+		assert.True(t, workspaces[0].Standalone)
+	})
+
+	t.Run("leave multiple workspaces as for_each instances", func(t *testing.T) {
+		workspaces := newTestMultiWorkspaceList()
+		SetStandaloneWorkspace(workspaces)
+
+		for _, ws := range workspaces {
+			assert.False(t, ws.Standalone)
+		}
+	})
+}
+
+func TestStandaloneMovedBlocks(t *testing.T) {
+	t.Run("move an existing workspace to the standalone address", func(t *testing.T) {
+		workspaces := []*Workspace{{Name: "foo", Workspace: "default", ID: tfe.String("ws-abc123"), Standalone: true}}
+
+		assert.Equal(t, []tfconfig.Moved{
+			{From: "tfe_workspace.workspace[\"default\"]", To: "tfe_workspace.workspace"},
+		}, StandaloneMovedBlocks(workspaces, nil))
+	})
+
+	t.Run("move an existing workspace back to the for_each address", func(t *testing.T) {
+		workspaces := []*Workspace{{Name: "foo", Workspace: "default", ID: tfe.String("ws-abc123")}}
+
+		assert.Equal(t, []tfconfig.Moved{
+			{From: "tfe_workspace.workspace", To: "tfe_workspace.workspace[\"default\"]"},
+		}, StandaloneMovedBlocks(workspaces, nil))
+	})
+
+	t.Run("skip new, renamed and multiple workspaces", func(t *testing.T) {
+		assert.Empty(t, StandaloneMovedBlocks([]*Workspace{{Name: "foo", Workspace: "default", Standalone: true}}, nil))
+
+		renamed := &Workspace{Name: "foo-staging", Workspace: "staging", ID: tfe.String("ws-abc123")}
+		assert.Empty(t, StandaloneMovedBlocks([]*Workspace{renamed}, []WorkspaceRename{{From: &Workspace{Name: "foo-stage", Workspace: "stage"}, To: renamed}}))
+
+		workspaces := newTestMultiWorkspaceList()
+		for _, ws := range workspaces {
+			ws.ID = tfe.String("ws-abc123")
+		}
+
+		assert.Empty(t, StandaloneMovedBlocks(workspaces, nil))
+	})
+}
+
 func TestParseWorkspaces(t *testing.T) {
+	t.Run("return an error when no workspace resolves", func(t *testing.T) {
+		_, err := ParseWorkspaces([]string{}, "")
+
+		assert.ErrorIs(t, err, ErrNoWorkspaces)
+	})
+
 	t.Run("single workspace", func(t *testing.T) {
 		workspaces, err := ParseWorkspaces([]string{}, "foo")
 		if err != nil {
@@ -1121,3 +1824,113 @@ func TestMergeWorkspaceTags(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestFetchWorkspaceIDsByTags(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	defer server.Close()
+
+	mux.HandleFunc("/api/v2/organizations/org/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("search[tags]") {
+		case "consumer":
+			testServerResHandler(t, 200, `{"data": [{"id": "ws-def456", "type": "workspaces"}, {"id": "ws-abc123", "type": "workspaces"}]}`)(w, r)
+		case "reader":
+			testServerResHandler(t, 200, `{"data": [{"id": "ws-abc123", "type": "workspaces"}, {"id": "ws-ghi789", "type": "workspaces"}]}`)(w, r)
+		default:
+			testServerResHandler(t, 200, `{"data": []}`)(w, r)
+		}
+	})
+
+	client := newTestTFClient(t, server.URL)
+
+	t.Run("return the distinct IDs of workspaces carrying any of the tags", func(t *testing.T) {
+		ids, err := FetchWorkspaceIDsByTags(ctx, client, "org", []string{"consumer", " reader"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"ws-abc123", "ws-def456", "ws-ghi789"}, ids)
+	})
+
+	t.Run("return no IDs when no workspace is tagged", func(t *testing.T) {
+		ids, err := FetchWorkspaceIDsByTags(ctx, client, "org", []string{"unused"})
+		require.NoError(t, err)
+
+		assert.Empty(t, ids)
+	})
+}
+
+func TestDestroyedWorkspaces(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{
+				Address: `tfe_workspace.workspace["pr-12"]`,
+				Type:    "tfe_workspace",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}, Before: map[string]interface{}{"name": "foo-pr-12"}},
+			},
+			{
+				Address: `tfe_workspace.workspace["production"]`,
+				Type:    "tfe_workspace",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}, Before: map[string]interface{}{"name": "foo-production"}},
+			},
+			{
+				Address: `tfe_workspace.workspace["staging"]`,
+				Type:    "tfe_workspace",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}, Before: map[string]interface{}{"name": "foo-staging"}},
+			},
+			{
+				Address: "tfe_variable.staging-foo",
+				Type:    "tfe_variable",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"foo-pr-12", "foo-production"}, DestroyedWorkspaces(plan))
+}
+
+func TestCreatedWorkspaces(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{
+				Address: `tfe_workspace.workspace["staging"]`,
+				Type:    "tfe_workspace",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}, After: map[string]interface{}{"name": "foo-staging"}},
+			},
+			{
+				Address: `tfe_workspace.workspace["production"]`,
+				Type:    "tfe_workspace",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}, After: map[string]interface{}{"name": "foo-production"}},
+			},
+			{
+				Address: "tfe_variable.staging-foo",
+				Type:    "tfe_variable",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"foo-staging"}, CreatedWorkspaces(plan))
+}
+
+func TestBlockedWorkspaceDeletions(t *testing.T) {
+	t.Run("block workspaces that are not allowed", func(t *testing.T) {
+		blocked, err := BlockedWorkspaceDeletions([]string{"foo-pr-12", "foo-production"}, []string{"foo-pr-*"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"foo-production"}, blocked)
+	})
+
+	t.Run("block all workspaces without allowed names", func(t *testing.T) {
+		blocked, err := BlockedWorkspaceDeletions([]string{"foo-pr-12"}, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"foo-pr-12"}, blocked)
+	})
+
+	t.Run("error on an invalid pattern", func(t *testing.T) {
+		_, err := BlockedWorkspaceDeletions([]string{"foo-pr-12"}, []string{"foo-["})
+		assert.EqualError(t, err, `invalid workspace pattern "foo-[": syntax error in pattern`)
+	})
+}
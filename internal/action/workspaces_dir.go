@@ -0,0 +1,116 @@
+package action
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// workspaceNamePattern matches the names Terraform Cloud allows for workspaces
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// WorkspaceFile is a workspace defined in its own file of the workspaces directory, with its settings and variables
+type WorkspaceFile struct {
+	WorkspaceSettings `yaml:",inline"`
+	Variables         VariablesInput `yaml:"variables,omitempty"`
+}
+
+// ReadWorkspacesDir reads the workspaces defined in the passed directory, one per ".yml" or ".yaml" file named after the workspace.
+// Other files and subdirectories are ignored.
+func ReadWorkspacesDir(dir string) (map[string]WorkspaceFile, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspaces directory: %w", err)
+	}
+
+	files := map[string]WorkspaceFile{}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		wsName := strings.TrimSuffix(entry.Name(), ext)
+
+		if !workspaceNamePattern.MatchString(wsName) {
+			return nil, fmt.Errorf("invalid workspace file name %q, workspace names may only contain letters, numbers, dashes and underscores", entry.Name())
+		}
+
+		if _, ok := files[wsName]; ok {
+			return nil, fmt.Errorf("workspace %q is defined by more than one file", wsName)
+		}
+
+		b, err := ioutil.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace file %s: %w", entry.Name(), err)
+		}
+
+		var f WorkspaceFile
+		if err = yaml.UnmarshalStrict(b, &f); err != nil {
+			return nil, fmt.Errorf("failed to decode workspace file %s: %w", entry.Name(), err)
+		}
+
+		files[wsName] = f
+	}
+
+	return files, nil
+}
+
+// MergeWorkspaceFiles adds the workspaces, variables and settings defined in the workspaces directory to the passed inputs.
+// An error is returned if a workspace's settings are set both in a file and in the passed settings.
+func MergeWorkspaceFiles(files map[string]WorkspaceFile, wsInputs []string, wsVars WorkspaceVariablesInput, settings map[string]WorkspaceSettings) ([]string, WorkspaceVariablesInput, map[string]WorkspaceSettings, error) {
+	names := make([]string, 0, len(files))
+	for wsName := range files {
+		names = append(names, wsName)
+	}
+
+	sort.Strings(names)
+
+	if wsVars == nil {
+		wsVars = WorkspaceVariablesInput{}
+	}
+
+	if settings == nil {
+		settings = map[string]WorkspaceSettings{}
+	}
+
+	for _, wsName := range names {
+		f := files[wsName]
+
+		found := false
+
+		for _, ws := range wsInputs {
+			if ws == wsName {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			wsInputs = append(wsInputs, wsName)
+		}
+
+		if len(f.Variables) > 0 {
+			wsVars[wsName] = append(wsVars[wsName], f.Variables...)
+		}
+
+		if f.WorkspaceSettings == (WorkspaceSettings{}) {
+			continue
+		}
+
+		if _, ok := settings[wsName]; ok {
+			return nil, nil, nil, fmt.Errorf("settings of workspace %q are set in both workspace_settings and workspaces_dir", wsName)
+		}
+
+		settings[wsName] = f.WorkspaceSettings
+	}
+
+	return wsInputs, wsVars, settings, nil
+}
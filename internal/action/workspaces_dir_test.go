@@ -0,0 +1,85 @@
+package action
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestWorkspaceFiles writes the passed files to a temporary directory and returns its path
+func writeTestWorkspaceFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func TestReadWorkspacesDir(t *testing.T) {
+	t.Run("read a workspace per YAML file", func(t *testing.T) {
+		dir := writeTestWorkspaceFiles(t, map[string]string{
+			"staging.yml":     "auto_apply: true\nvariables:\n  - key: foo\n    value: bar\n",
+			"production.yaml": "terraform_version: 1.1.0\n",
+			"README.md":       "ignored",
+		})
+
+		files, err := ReadWorkspacesDir(dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]WorkspaceFile{
+			"staging": {
+				WorkspaceSettings: WorkspaceSettings{AutoApply: tfe.Bool(true)},
+				Variables:         VariablesInput{{Key: "foo", Value: "bar"}},
+			},
+			"production": {
+				WorkspaceSettings: WorkspaceSettings{TerraformVersion: tfe.String("1.1.0")},
+			},
+		}, files)
+	})
+
+	t.Run("return an error for an invalid workspace file name", func(t *testing.T) {
+		dir := writeTestWorkspaceFiles(t, map[string]string{"my staging.yml": ""})
+
+		_, err := ReadWorkspacesDir(dir)
+		assert.EqualError(t, err, "invalid workspace file name \"my staging.yml\", workspace names may only contain letters, numbers, dashes and underscores")
+	})
+
+	t.Run("return an error for unknown settings", func(t *testing.T) {
+		dir := writeTestWorkspaceFiles(t, map[string]string{"staging.yml": "unknown: true\n"})
+
+		_, err := ReadWorkspacesDir(dir)
+		assert.Error(t, err)
+	})
+}
+
+func TestMergeWorkspaceFiles(t *testing.T) {
+	files := map[string]WorkspaceFile{
+		"staging": {
+			WorkspaceSettings: WorkspaceSettings{AutoApply: tfe.Bool(true)},
+			Variables:         VariablesInput{{Key: "foo", Value: "bar"}},
+		},
+		"production": {},
+	}
+
+	t.Run("add the workspaces, variables and settings of each file", func(t *testing.T) {
+		wsInputs, wsVars, settings, err := MergeWorkspaceFiles(files, []string{"staging"}, nil, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"staging", "production"}, wsInputs)
+		assert.Equal(t, WorkspaceVariablesInput{"staging": {{Key: "foo", Value: "bar"}}}, wsVars)
+		assert.Equal(t, map[string]WorkspaceSettings{"staging": {AutoApply: tfe.Bool(true)}}, settings)
+	})
+
+	t.Run("return an error if settings are set in both a file and the settings input", func(t *testing.T) {
+		_, _, _, err := MergeWorkspaceFiles(files, nil, nil, map[string]WorkspaceSettings{
+			"staging": {AutoApply: tfe.Bool(false)},
+		})
+		assert.EqualError(t, err, "settings of workspace \"staging\" are set in both workspace_settings and workspaces_dir")
+	})
+}
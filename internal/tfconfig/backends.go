@@ -2,6 +2,8 @@ package tfconfig
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	yaml "sigs.k8s.io/yaml"
 )
@@ -23,5 +25,72 @@ func ParseBackend(backendInput string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	if err = validateRemoteBackendWorkspaces(backend); err != nil {
+		return nil, err
+	}
+
 	return backend, nil
 }
+
+// validateRemoteBackendWorkspaces returns an error if a remote backend selects its workspaces by both name and prefix
+func validateRemoteBackendWorkspaces(backend map[string]interface{}) error {
+	remote, ok := backend["remote"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	workspaces, ok := remote["workspaces"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	name, _ := workspaces["name"].(string)
+	prefix, _ := workspaces["prefix"].(string)
+
+	if name != "" && prefix != "" {
+		return fmt.Errorf("remote backend workspaces cannot set both name and prefix")
+	}
+
+	return nil
+}
+
+// SetRemoteBackendWorkspaceName expands the passed workspace name template, replacing "${name}" with the passed name, and sets it as the remote backend workspace name
+func SetRemoteBackendWorkspaceName(backend map[string]interface{}, template string, name string) error {
+	wsName := strings.TrimSpace(strings.ReplaceAll(template, "${name}", name))
+	if wsName == "" {
+		return fmt.Errorf("backend workspace name template %q expands to an empty string", template)
+	}
+
+	remote, ok := backend["remote"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("a backend workspace name can only be set for the remote backend")
+	}
+
+	remote["workspaces"] = map[string]interface{}{
+		"name": wsName,
+	}
+
+	return nil
+}
+
+// RemoteBackendWorkspace returns the organization and workspace name of a remote backend with a single named workspace, otherwise empty strings are returned
+func RemoteBackendWorkspace(backend map[string]interface{}) (string, string) {
+	remote, ok := backend["remote"].(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+
+	org, _ := remote["organization"].(string)
+
+	workspaces, ok := remote["workspaces"].(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+
+	name, _ := workspaces["name"].(string)
+	if org == "" || name == "" {
+		return "", ""
+	}
+
+	return org, name
+}
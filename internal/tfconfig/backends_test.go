@@ -59,6 +59,19 @@ foo:
 		assert.Equal(t, be["foo"].(map[string]interface{})["bar"], "baz")
 	})
 
+	t.Run("Error on a remote backend with both a workspace name and prefix", func(t *testing.T) {
+		config := `---
+remote:
+  organization: org
+  workspaces:
+    name: foo
+    prefix: foo-
+`
+
+		_, err := ParseBackend(config)
+		assert.EqualError(t, err, "remote backend workspaces cannot set both name and prefix")
+	})
+
 	t.Run("Parse empty backend", func(t *testing.T) {
 		be, err := ParseBackend("")
 
@@ -66,3 +79,102 @@ foo:
 		assert.Equal(t, be, (map[string]interface{})(nil))
 	})
 }
+
+func TestSetRemoteBackendWorkspaceName(t *testing.T) {
+	t.Run("set the templated workspace name on a remote backend", func(t *testing.T) {
+		be, err := ParseBackend(`---
+remote:
+  organization: org
+`)
+		assert.NoError(t, err)
+
+		err = SetRemoteBackendWorkspaceName(be, "${name}-workspace-state", "foo")
+		assert.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{"name": "foo-workspace-state"}, be["remote"].(map[string]interface{})["workspaces"])
+	})
+
+	t.Run("replace an existing workspaces block", func(t *testing.T) {
+		be, err := ParseBackend(`---
+remote:
+  organization: org
+  workspaces:
+    prefix: foo-
+`)
+		assert.NoError(t, err)
+
+		err = SetRemoteBackendWorkspaceName(be, "state", "foo")
+		assert.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{"name": "state"}, be["remote"].(map[string]interface{})["workspaces"])
+	})
+
+	t.Run("error when the template expands to an empty string", func(t *testing.T) {
+		be, err := ParseBackend(`---
+remote:
+  organization: org
+`)
+		assert.NoError(t, err)
+
+		err = SetRemoteBackendWorkspaceName(be, "${name}", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("error when the backend is not remote", func(t *testing.T) {
+		be, err := ParseBackend(`---
+local:
+  path: foo/terraform.tfstate
+`)
+		assert.NoError(t, err)
+
+		err = SetRemoteBackendWorkspaceName(be, "${name}", "foo")
+		assert.Error(t, err)
+	})
+
+	t.Run("error when no backend is set", func(t *testing.T) {
+		err := SetRemoteBackendWorkspaceName(nil, "${name}", "foo")
+		assert.Error(t, err)
+	})
+}
+
+func TestRemoteBackendWorkspace(t *testing.T) {
+	t.Run("return the organization and workspace of a remote backend", func(t *testing.T) {
+		be, err := ParseBackend(`---
+remote:
+  organization: org
+  workspaces:
+    name: state
+`)
+		assert.NoError(t, err)
+
+		org, name := RemoteBackendWorkspace(be)
+		assert.Equal(t, "org", org)
+		assert.Equal(t, "state", name)
+	})
+
+	t.Run("return empty strings for a workspace prefix", func(t *testing.T) {
+		be, err := ParseBackend(`---
+remote:
+  organization: org
+  workspaces:
+    prefix: foo-
+`)
+		assert.NoError(t, err)
+
+		org, name := RemoteBackendWorkspace(be)
+		assert.Empty(t, org)
+		assert.Empty(t, name)
+	})
+
+	t.Run("return empty strings for other backends", func(t *testing.T) {
+		be, err := ParseBackend(`---
+s3:
+  bucket: foo
+`)
+		assert.NoError(t, err)
+
+		org, name := RemoteBackendWorkspace(be)
+		assert.Empty(t, org)
+		assert.Empty(t, name)
+	})
+}
@@ -1,11 +1,19 @@
 package tfconfig
 
+import (
+	"encoding/json"
+	"fmt"
+
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
 type Module struct {
 	Terraform Terraform                         `json:"terraform"`
 	Variables map[string]Variable               `json:"variable,omitempty"`
 	Resources map[string]map[string]interface{} `json:"resource,omitempty"`
 	Data      map[string]map[string]interface{} `json:"data,omitempty"`
 	Providers map[string]ProviderConfig         `json:"provider,omitempty"`
+	Moved     []Moved                           `json:"moved,omitempty"`
 }
 
 // AppendData appends a data source of type "sourceType" with name "name" to the workspace's data configuration
@@ -25,3 +33,57 @@ func (m *Module) AppendResource(sourceType string, name string, source interface
 
 	m.Resources[sourceType][name] = source
 }
+
+// Validate checks that the module is well-formed Terraform JSON configuration, returning the first structural problem found
+func (m *Module) Validate() error {
+	if len(m.Terraform.Backend) > 1 {
+		return fmt.Errorf("only one backend can be configured, found %d", len(m.Terraform.Backend))
+	}
+
+	for name, config := range m.Terraform.Backend {
+		if config == nil {
+			return fmt.Errorf("backend %q has no configuration", name)
+		}
+	}
+
+	for name, p := range m.Providers {
+		if p == nil {
+			return fmt.Errorf("provider %q has no configuration", name)
+		}
+
+		if _, ok := m.Terraform.RequiredProviders[name]; !ok {
+			return fmt.Errorf("provider %q is configured but missing from required_providers", name)
+		}
+	}
+
+	for kind, blocks := range map[string]map[string]map[string]interface{}{"resource": m.Resources, "data": m.Data} {
+		for t, instances := range blocks {
+			if len(instances) == 0 {
+				return fmt.Errorf("%s type %q has no blocks", kind, t)
+			}
+
+			for name, v := range instances {
+				if v == nil {
+					return fmt.Errorf("%s %s.%s has no configuration", kind, t, name)
+				}
+			}
+		}
+	}
+
+	for _, mv := range m.Moved {
+		if mv.From == "" || mv.To == "" {
+			return fmt.Errorf("moved block from %q to %q must set both addresses", mv.From, mv.To)
+		}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	if _, diags := hcljson.Parse(b, "main.tf.json"); diags.HasErrors() {
+		return fmt.Errorf("generated configuration is not valid Terraform JSON: %s", diags.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package tfconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestModule() *Module {
+	return &Module{
+		Terraform: Terraform{
+			Backend: map[string]interface{}{
+				"s3": map[string]interface{}{"bucket": "foo"},
+			},
+			RequiredProviders: map[string]RequiredProvider{
+				"tfe": {Version: "0.26.0"},
+			},
+		},
+		Resources: map[string]map[string]interface{}{
+			"tfe_workspace": {"workspace": map[string]interface{}{"name": "foo"}},
+		},
+		Data: map[string]map[string]interface{}{},
+		Providers: map[string]ProviderConfig{
+			"tfe": map[string]interface{}{"hostname": "app.terraform.io"},
+		},
+	}
+}
+
+func TestModuleValidate(t *testing.T) {
+	t.Run("accept a well-formed module", func(t *testing.T) {
+		assert.NoError(t, newTestModule().Validate())
+	})
+
+	t.Run("error on a provider without configuration", func(t *testing.T) {
+		m := newTestModule()
+		m.Providers["tfe"] = nil
+
+		assert.EqualError(t, m.Validate(), "provider \"tfe\" has no configuration")
+	})
+
+	t.Run("error on a provider missing from required providers", func(t *testing.T) {
+		m := newTestModule()
+		m.Providers["aws"] = map[string]interface{}{}
+
+		assert.EqualError(t, m.Validate(), "provider \"aws\" is configured but missing from required_providers")
+	})
+
+	t.Run("error on a resource without configuration", func(t *testing.T) {
+		m := newTestModule()
+		m.Resources["tfe_variable"] = map[string]interface{}{"foo": nil}
+
+		assert.EqualError(t, m.Validate(), "resource tfe_variable.foo has no configuration")
+	})
+
+	t.Run("error on multiple backends", func(t *testing.T) {
+		m := newTestModule()
+		m.Terraform.Backend["local"] = map[string]interface{}{}
+
+		assert.EqualError(t, m.Validate(), "only one backend can be configured, found 2")
+	})
+
+	t.Run("error on an incomplete moved block", func(t *testing.T) {
+		m := newTestModule()
+		m.Moved = []Moved{{From: "tfe_variable.foo"}}
+
+		assert.EqualError(t, m.Validate(), "moved block from \"tfe_variable.foo\" to \"\" must set both addresses")
+	})
+}
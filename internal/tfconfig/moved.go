@@ -0,0 +1,6 @@
+package tfconfig
+
+type Moved struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -1,7 +1,27 @@
 package tfconfig
 
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteStateBackendConfigWorkspaces selects the Terraform Cloud workspaces of a remote backend, either a single workspace by name or all workspaces with a name prefix
 type RemoteStateBackendConfigWorkspaces struct {
-	Name string `json:"name"`
+	Name   string `json:"name,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Validate returns an error unless exactly one of the name and prefix is set
+func (w *RemoteStateBackendConfigWorkspaces) Validate() error {
+	if w.Name != "" && w.Prefix != "" {
+		return fmt.Errorf("workspaces cannot set both name and prefix")
+	}
+
+	if w.Name == "" && w.Prefix == "" {
+		return fmt.Errorf("workspaces must set either name or prefix")
+	}
+
+	return nil
 }
 
 type RemoteStateBackendConfig struct {
@@ -11,9 +31,43 @@ type RemoteStateBackendConfig struct {
 	Hostname     string                              `json:"hostname,omitempty"`
 	Organization string                              `json:"organization,omitempty"`
 	Workspaces   *RemoteStateBackendConfigWorkspaces `json:"workspaces,omitempty"`
+
+	// S3 backend settings for reading state from another account
+	RoleArn       string `json:"role_arn,omitempty" yaml:"role_arn"`
+	Profile       string `json:"profile,omitempty" yaml:"profile"`
+	DynamoDBTable string `json:"dynamodb_table,omitempty" yaml:"dynamodb_table"`
 }
 
 type RemoteState struct {
 	Config  RemoteStateBackendConfig `json:"config" yaml:"config"`
 	Backend string                   `json:"backend" yaml:"backend"`
+
+	// Workspace selects the workspace to read when the backend selects workspaces by prefix, without the prefix
+	Workspace string `json:"workspace,omitempty" yaml:"workspace"`
+}
+
+// ExpandName returns a copy of the remote state with "${name}" in its backend configuration replaced with the passed name
+func (rs RemoteState) ExpandName(name string) RemoteState {
+	expand := func(s string) string {
+		return strings.ReplaceAll(s, "${name}", name)
+	}
+
+	rs.Config.Key = expand(rs.Config.Key)
+	rs.Config.Bucket = expand(rs.Config.Bucket)
+	rs.Config.Region = expand(rs.Config.Region)
+	rs.Config.Hostname = expand(rs.Config.Hostname)
+	rs.Config.Organization = expand(rs.Config.Organization)
+	rs.Config.RoleArn = expand(rs.Config.RoleArn)
+	rs.Config.Profile = expand(rs.Config.Profile)
+	rs.Config.DynamoDBTable = expand(rs.Config.DynamoDBTable)
+	rs.Workspace = expand(rs.Workspace)
+
+	if rs.Config.Workspaces != nil {
+		rs.Config.Workspaces = &RemoteStateBackendConfigWorkspaces{
+			Name:   expand(rs.Config.Workspaces.Name),
+			Prefix: expand(rs.Config.Workspaces.Prefix),
+		}
+	}
+
+	return rs
 }
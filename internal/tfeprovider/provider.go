@@ -1,6 +1,8 @@
 package tfeprovider
 
 type Config struct {
-	Hostname string `json:"hostname"`
-	Token    string `json:"token,omitempty"`
+	Hostname      string `json:"hostname"`
+	Token         string `json:"token,omitempty"`
+	SSLSkipVerify bool   `json:"ssl_skip_verify,omitempty"`
+	Organization  string `json:"organization,omitempty"`
 }
@@ -1,30 +1,78 @@
 package tfeprovider
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type Workspace struct {
 	ForEach map[string]*Workspace `json:"for_each,omitempty"`
 
-	AgentPoolID            string      `json:"agent_pool_id,omitempty"`
-	AutoApply              *bool       `json:"auto_apply,omitempty"`
-	Description            string      `json:"description,omitempty"`
-	ExecutionMode          string      `json:"execution_mode,omitempty"`
-	FileTriggersEnabled    *bool       `json:"file_triggers_enabled,omitempty"`
-	GlobalRemoteState      *bool       `json:"global_remote_state,omitempty"`
-	Name                   string      `json:"name"`
-	Organization           string      `json:"organization,omitempty"`
-	QueueAllRuns           *bool       `json:"queue_all_runs,omitempty"`
-	RemoteStateConsumerIDs []string    `json:"remote_state_consumer_ids,omitempty"`
-	SpeculativeEnabled     *bool       `json:"speculative_enabled,omitempty"`
-	TagNames               interface{} `json:"tag_names,omitempty"`
-	TerraformVersion       string      `json:"terraform_version,omitempty"`
-	SSHKeyID               string      `json:"ssh_key_id,omitempty"`
-	VCSRepo                *VCSRepo    `json:"vcs_repo,omitempty"`
-	WorkingDirectory       string      `json:"working_directory,omitempty"`
+	// Lookups lists attributes that are looked up from the for_each value of each workspace, replacing the attribute's value
+	Lookups []string `json:"-"`
+
+	AgentPoolID                string      `json:"agent_pool_id,omitempty"`
+	AssessmentsEnabled         *bool       `json:"assessments_enabled,omitempty"`
+	AutoApply                  *bool       `json:"auto_apply,omitempty"`
+	AutoDestroyAt              string      `json:"auto_destroy_at,omitempty"`
+	Description                string      `json:"description,omitempty"`
+	ExecutionMode              string      `json:"execution_mode,omitempty"`
+	FileTriggersEnabled        *bool       `json:"file_triggers_enabled,omitempty"`
+	GlobalRemoteState          *bool       `json:"global_remote_state,omitempty"`
+	Name                       string      `json:"name"`
+	Organization               string      `json:"organization,omitempty"`
+	QueueAllRuns               *bool       `json:"queue_all_runs,omitempty"`
+	RemoteStateConsumerIDs     []string    `json:"remote_state_consumer_ids,omitempty"`
+	SpeculativeEnabled         interface{} `json:"speculative_enabled,omitempty"`
+	StructuredRunOutputEnabled *bool       `json:"structured_run_output_enabled,omitempty"`
+	TagNames                   interface{} `json:"tag_names,omitempty"`
+	TerraformVersion           string      `json:"terraform_version,omitempty"`
+	TriggerPatterns            []string    `json:"trigger_patterns,omitempty"`
+	TriggerPrefixes            []string    `json:"trigger_prefixes,omitempty"`
+	SSHKeyID                   string      `json:"ssh_key_id,omitempty"`
+	VCSRepo                    *VCSRepo    `json:"vcs_repo,omitempty"`
+	WorkingDirectory           string      `json:"working_directory,omitempty"`
+
+	DynamicVCSRepo *DynamicVCSRepo `json:"dynamic,omitempty"`
+}
+
+// MarshalJSON renders the attributes listed in Lookups as a lookup of the for_each value, which is null if the workspace has no value
+func (w Workspace) MarshalJSON() ([]byte, error) {
+	type workspace Workspace
+
+	b, err := json.Marshal(workspace(w))
+	if err != nil || len(w.Lookups) == 0 {
+		return b, err
+	}
+
+	attrs := map[string]json.RawMessage{}
+	if err = json.Unmarshal(b, &attrs); err != nil {
+		return nil, err
+	}
+
+	for _, attr := range w.Lookups {
+		if attrs[attr], err = json.Marshal(fmt.Sprintf("${lookup(each.value, %q, null)}", attr)); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(attrs)
 }
 
 type VCSRepo struct {
 	OauthTokenID      string `json:"oauth_token_id"`
 	Identifier        string `json:"identifier"`
 	IngressSubmodules bool   `json:"ingress_submodules"`
+	TagsRegex         string `json:"tags_regex,omitempty"`
+}
+
+type DynamicVCSRepo struct {
+	VCSRepo []DynamicVCSRepoEntry `json:"vcs_repo,omitempty"`
+}
+
+type DynamicVCSRepoEntry struct {
+	ForEach string   `json:"for_each"`
+	Content *VCSRepo `json:"content"`
 }
 
 type DataWorkspace struct {
@@ -0,0 +1,13 @@
+package tfeprovider
+
+type WorkspaceRun struct {
+	ForEach     map[string]WorkspaceRun `json:"for_each,omitempty"`
+	WorkspaceID string                  `json:"workspace_id"`
+	Apply       *WorkspaceRunOptions    `json:"apply,omitempty"`
+	DependsOn   []string                `json:"depends_on,omitempty"`
+}
+
+type WorkspaceRunOptions struct {
+	ManualConfirm bool `json:"manual_confirm"`
+	WaitForRun    bool `json:"wait_for_run"`
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"strings"
 
 	"github.com/sethvargo/go-githubactions"
@@ -10,42 +11,117 @@ import (
 
 func main() {
 	if err := action.Run(&action.Inputs{
-		Token:                     githubactions.GetInput("terraform_token"),
-		Host:                      githubactions.GetInput("terraform_host"),
-		Name:                      strings.TrimSpace(githubactions.GetInput("name")),
-		Description:               githubactions.GetInput("description"),
-		Tags:                      githubactions.GetInput("tags"),
-		WorkspaceTags:             githubactions.GetInput("workspace_tags"),
-		Organization:              githubactions.GetInput("terraform_organization"),
-		Apply:                     inputs.GetBool("apply"),
-		RunnerTerraformVersion:    githubactions.GetInput("runner_terraform_version"),
-		RemoteStates:              githubactions.GetInput("remote_states"),
-		Workspaces:                githubactions.GetInput("workspaces"),
-		Variables:                 githubactions.GetInput("variables"),
-		WorkspaceVariables:        githubactions.GetInput("workspace_variables"),
-		TeamAccess:                githubactions.GetInput("team_access"),
-		BackendConfig:             githubactions.GetInput("backend_config"),
-		AgentPoolID:               githubactions.GetInput("agent_pool_id"),
-		AutoApply:                 inputs.GetBoolPtr("auto_apply"),
-		ExecutionMode:             githubactions.GetInput("execution_mode"),
-		FileTriggersEnabled:       inputs.GetBoolPtr("file_triggers_enabled"),
-		GlobalRemoteState:         inputs.GetBoolPtr("global_remote_state"),
-		QueueAllRuns:              inputs.GetBoolPtr("queue_all_runs"),
-		RemoteStateConsumerIDs:    githubactions.GetInput("remote_state_consumer_ids"),
-		SpeculativeEnabled:        inputs.GetBoolPtr("speculative_enabled"),
-		TerraformVersion:          githubactions.GetInput("terraform_version"),
-		RunTriggers:               githubactions.GetInput("run_triggers"),
-		WorkspaceRunTriggers:      githubactions.GetInput("workspace_run_triggers"),
-		NotificationConfiguration: githubactions.GetInput("notification_configuration"),
-		SSHKeyID:                  githubactions.GetInput("ssh_key_id"),
-		VCSIngressSubmodules:      inputs.GetBool("vcs_ingress_submodules"),
-		VCSRepo:                   githubactions.GetInput("vcs_repo"),
-		VCSTokenID:                githubactions.GetInput("vcs_token_id"),
-		VCSType:                   githubactions.GetInput("vcs_type"),
-		WorkingDirectory:          githubactions.GetInput("working_directory"),
-		TFEProviderVersion:        githubactions.GetInput("tfe_provider_version"),
-		Import:                    inputs.GetBool("import"),
-		AllowWorkspaceDeletion:    inputs.GetBool("allow_workspace_deletion"),
+		Token:                      githubactions.GetInput("terraform_token"),
+		Host:                       githubactions.GetInput("terraform_host"),
+		Name:                       strings.TrimSpace(githubactions.GetInput("name")),
+		Description:                githubactions.GetInput("description"),
+		Tags:                       githubactions.GetInput("tags"),
+		WorkspaceTags:              githubactions.GetInput("workspace_tags"),
+		TagPolicyFile:              githubactions.GetInput("tag_policy_file"),
+		Organization:               githubactions.GetInput("terraform_organization"),
+		Apply:                      inputs.GetBool("apply"),
+		RunnerTerraformVersion:     githubactions.GetInput("runner_terraform_version"),
+		RemoteStates:               githubactions.GetInput("remote_states"),
+		RemoteStatesFile:           githubactions.GetInput("remote_states_file"),
+		CheckRemoteStates:          inputs.GetBool("check_remote_states"),
+		Workspaces:                 githubactions.GetInput("workspaces"),
+		AllowEmpty:                 inputs.GetBool("allow_empty"),
+		Variables:                  githubactions.GetInput("variables"),
+		WorkspaceVariables:         githubactions.GetInput("workspace_variables"),
+		VariableSets:               githubactions.GetInput("variable_sets"),
+		TeamAccess:                 githubactions.GetInput("team_access"),
+		BackendConfig:              githubactions.GetInput("backend_config"),
+		AgentPoolID:                githubactions.GetInput("agent_pool_id"),
+		AgentPoolName:              githubactions.GetInput("agent_pool_name"),
+		SkipAgentCheck:             inputs.GetBool("skip_agent_check"),
+		AutoApply:                  inputs.GetBoolPtr("auto_apply"),
+		ExecutionMode:              githubactions.GetInput("execution_mode"),
+		FileTriggersEnabled:        inputs.GetBoolPtr("file_triggers_enabled"),
+		GlobalRemoteState:          inputs.GetBoolPtr("global_remote_state"),
+		QueueAllRuns:               inputs.GetBoolPtr("queue_all_runs"),
+		RemoteStateConsumerIDs:     githubactions.GetInput("remote_state_consumer_ids"),
+		RemoteStateConsumerTags:    githubactions.GetInput("remote_state_consumer_tags"),
+		SpeculativeEnabled:         inputs.GetBoolPtr("speculative_enabled"),
+		WorkspaceSpeculative:       githubactions.GetInput("workspace_speculative_enabled"),
+		StructuredRunOutputEnabled: inputs.GetBoolPtr("structured_run_output_enabled"),
+		AssessmentsEnabled:         inputs.GetBoolPtr("assessments_enabled"),
+		AutoDestroyAt:              githubactions.GetInput("auto_destroy_at"),
+		Environment:                githubactions.GetInput("environment"),
+		SkipPermissionCheck:        inputs.GetBool("skip_permission_check"),
+		GenerateGraph:              inputs.GetBool("generate_graph"),
+		SSLSkipVerify:              inputs.GetBool("ssl_skip_verify"),
+		CABundlePath:               githubactions.GetInput("ca_bundle_path"),
+		HTTPHeaders:                githubactions.GetInput("http_headers"),
+		GitMetadataTags:            inputs.GetBool("git_metadata_tags"),
+		ImportConcurrency:          githubactions.GetInput("import_concurrency"),
+		InitRetries:                githubactions.GetInput("init_retries"),
+		MaxNewWorkspaces:           githubactions.GetInput("max_new_workspaces"),
+		SensitiveKeyPatterns:       githubactions.GetInput("sensitive_key_patterns"),
+		WorkspaceTerraformVersions: githubactions.GetInput("workspace_terraform_versions"),
+		PlanStepSummary:            inputs.GetBool("plan_step_summary"),
+		PlanPRComment:              inputs.GetBool("plan_pr_comment"),
+		UseEnvCredentials:          inputs.GetBool("use_env_credentials"),
+		Env:                        githubactions.GetInput("env"),
+		AllowCredentialsEnv:        inputs.GetBool("allow_credentials_env"),
+		PluginCacheDir:             githubactions.GetInput("plugin_cache_dir"),
+		TriggerPatterns:            githubactions.GetInput("trigger_patterns"),
+		TriggerPrefixes:            githubactions.GetInput("trigger_prefixes"),
+		TriggerTagsRegex:           githubactions.GetInput("trigger_tags_regex"),
+		ValidateGeneratedConfig:    inputs.GetBool("validate_generated_config"),
+		ContinueOnPartialFailure:   inputs.GetBool("continue_on_partial_failure"),
+		RunMessage:                 githubactions.GetInput("run_message"),
+		ModuleSource:               githubactions.GetInput("module_source"),
+		ModuleVersion:              githubactions.GetInput("module_version"),
+		EmptyConfigurationVersion:  inputs.GetBool("empty_configuration_version"),
+		WorkspaceRun:               inputs.GetBool("workspace_run"),
+		OnExisting:                 githubactions.GetInput("on_existing"),
+		WorkspaceRunWait:           inputs.GetBool("workspace_run_wait"),
+		PruneTeamAccess:            inputs.GetBool("prune_team_access"),
+		ReportHealth:               inputs.GetBool("report_health"),
+		CheckLiveDifferences:       inputs.GetBool("check_live_differences"),
+		WorkspaceSettings:          githubactions.GetInput("workspace_settings"),
+		IgnoreDescriptionDrift:     inputs.GetBool("ignore_description_drift"),
+		ParallelPlan:               inputs.GetBool("parallel_plan"),
+		ImportMappings:             githubactions.GetInput("import_mappings"),
+		WorkspacesDir:              githubactions.GetInput("workspaces_dir"),
+		TerraformVersion:           githubactions.GetInput("terraform_version"),
+		RunTriggers:                githubactions.GetInput("run_triggers"),
+		WorkspaceRunTriggers:       githubactions.GetInput("workspace_run_triggers"),
+		NotificationConfiguration:  githubactions.GetInput("notification_configuration"),
+		SSHKeyID:                   githubactions.GetInput("ssh_key_id"),
+		VCSIngressSubmodules:       inputs.GetBool("vcs_ingress_submodules"),
+		VCSRepo:                    githubactions.GetInput("vcs_repo"),
+		VCSTokenID:                 githubactions.GetInput("vcs_token_id"),
+		VCSType:                    githubactions.GetInput("vcs_type"),
+		WorkingDirectory:           githubactions.GetInput("working_directory"),
+		TFEProviderVersion:         githubactions.GetInput("tfe_provider_version"),
+		TFEProviderSource:          githubactions.GetInput("tfe_provider_source"),
+		TFEProviderOrganization:    inputs.GetBool("tfe_provider_organization"),
+		Import:                     inputs.GetBool("import"),
+		Destroy:                    githubactions.GetInput("destroy"),
+		AllowWorkspaceDeletion:     inputs.GetBool("allow_workspace_deletion"),
+		DeletableWorkspaces:        githubactions.GetInput("allow_workspace_deletion_names"),
+		StandaloneWorkspace:        inputs.GetBool("standalone_workspace"),
+		AutoApplyResourceTypes:     githubactions.GetInput("auto_apply_resource_types"),
+		SkipApplyOnDestroy:         inputs.GetBool("skip_apply_on_destroy"),
+		BackendWorkspaceName:       strings.TrimSpace(githubactions.GetInput("backend_workspace_name")),
+		TargetWorkspaces:           githubactions.GetInput("target_workspaces"),
+		ApprovedPlan:               githubactions.GetInput("approved_plan"),
+		BaselinePlanPath:           githubactions.GetInput("baseline_plan_path"),
+		KeepWorkDirOnError:         inputs.GetBool("keep_workdir_on_error"),
+		LockWorkspace:              githubactions.GetInput("lock_workspace"),
+		LockTimeout:                githubactions.GetInput("lock_timeout"),
+		RenderOnly:                 inputs.GetBool("render_only"),
+		RenderPath:                 githubactions.GetInput("render_path"),
+		PreviewOnly:                inputs.GetBool("preview_only"),
+		ApplyRequiresPlanMatch:     inputs.GetBool("apply_requires_plan_match"),
+		InheritOrgDefaults:         inputs.GetBool("inherit_organization_defaults"),
+		AllowTagChanges:            inputs.GetBoolPtr("allow_tag_changes"),
+		WorkspaceOrganizations:     githubactions.GetInput("workspace_organizations"),
+		WorkspaceRenames:           githubactions.GetInput("workspace_renames"),
+		SARIFOutput:                githubactions.GetInput("sarif_output"),
+
+		Stdin: os.Stdin,
 	}); err != nil {
 		githubactions.Fatalf("Error: %s", err)
 	}